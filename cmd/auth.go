@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/keanucz/AdobeConnectDL/connectdl"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Inspect Adobe Connect session tokens",
+}
+
+var authCheckCmd = &cobra.Command{
+	Use:   "check <host|url>",
+	Short: "Check whether a session token is valid and a recording is accessible",
+	Long: `Check whether a session token is valid and a recording is accessible.
+
+Calls the Connect common-info API with the configured session and reports the
+logged-in user and session expiry (if the server advertises one). When a
+recording URL is given and the session is logged in, also checks whether that
+recording can be opened; guests can't look recordings up.
+
+Examples:
+  adobeconnectdl auth check --session TOKEN acme.adobeconnect.com
+  adobeconnectdl auth check "https://acme.adobeconnect.com/p1a2b3c4d5e6/?session=TOKEN"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+		defer cancel()
+
+		dl := connectdl.New(
+			connectdl.WithHTTPClient(&http.Client{Timeout: 30 * time.Second}),
+			connectdl.WithLogger(Logger),
+		)
+		session := connectdl.ResolveSession(args[0], sessionFlag)
+		status, err := dl.CheckSession(ctx, recordingHost(args[0]), session)
+		if err != nil {
+			return fmt.Errorf("session check failed: %w", err)
+		}
+		// Guests can't query SCOs, so, as in the preflight, access is only
+		// checked for a logged-in session
+		_, recordingID, err := connectdl.ParseTarget(args[0])
+		if err == nil && recordingID != "" && status.LoggedIn {
+			access, err := dl.CheckRecording(ctx, args[0], session)
+			if err != nil {
+				return fmt.Errorf("recording check failed: %w", err)
+			}
+			status.Recording = &access
+		}
+
+		printSessionStatus(cmd.OutOrStdout(), status)
+
+		if !status.LoggedIn && session != "" {
+			return errors.New("session token is not valid (expired or logged out)")
+		}
+		if status.Recording != nil && !status.Recording.Accessible {
+			return fmt.Errorf("recording %s is not accessible (%s)", status.Recording.ID, status.Recording.Reason)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authCheckCmd)
+
+	authCheckCmd.Flags().StringVar(
+		&sessionFlag,
+		"session",
+		"",
+		"BREEZESESSION token to check",
+	)
+}
+
+// printSessionStatus writes a human-readable summary of a session check.
+func printSessionStatus(w io.Writer, status connectdl.SessionStatus) {
+	fmt.Fprintf(w, "Host:       %s\n", status.Host)
+	if status.ServerVersion != "" {
+		fmt.Fprintf(w, "Server:     Adobe Connect %s\n", status.ServerVersion)
+	}
+	if status.LoggedIn {
+		fmt.Fprintf(w, "Session:    \033[32mvalid\033[0m\n")
+		fmt.Fprintf(w, "User:       %s", status.UserName)
+		if status.Login != "" {
+			fmt.Fprintf(w, " (%s)", status.Login)
+		}
+		fmt.Fprintln(w)
+	} else {
+		fmt.Fprintf(w, "Session:    \033[31mnot logged in\033[0m\n")
+	}
+	if !status.Expires.IsZero() {
+		fmt.Fprintf(w, "Expires:    %s\n", status.Expires.Local().Format(time.RFC1123))
+	} else {
+		fmt.Fprintf(w, "Expires:    unknown\n")
+	}
+	if rec := status.Recording; rec != nil {
+		if rec.Accessible {
+			fmt.Fprintf(w, "Recording:  \033[32maccessible\033[0m %s (sco-id %s)\n", rec.Name, rec.ScoID)
+		} else {
+			fmt.Fprintf(w, "Recording:  \033[31mnot accessible\033[0m %s (%s)\n", rec.ID, rec.Reason)
+		}
+	}
+}
+
+// preflightTimeout bounds each session and recording check of the preflight.
+const preflightTimeout = 30 * time.Second
+
+// preflightBatch checks every distinct host/session pair in the batch before any
+// download is queued. It returns an error when a provided session token is not
+// valid, and the set of URLs whose recordings the server reports as inaccessible.
+// Hosts that don't expose the XML API or can't be reached are skipped with a warning.
+func preflightBatch(ctx context.Context, dl *connectdl.Client, urls []string) (map[string]string, error) {
+	type hostKey struct{ host, session string }
+	sessions := make(map[hostKey]*connectdl.SessionStatus)
+	denied := make(map[string]string)
+
+	for _, rawURL := range urls {
+		session := connectdl.ResolveSession(rawURL, sessionFlag)
+		key := hostKey{host: recordingHost(rawURL), session: session}
+		status, checked := sessions[key]
+		if !checked {
+			var err error
+			if status, err = preflightSession(ctx, dl, key.host, session); err != nil {
+				return nil, err
+			}
+			sessions[key] = status
+		}
+
+		// Guests can't query SCOs, so access is only conclusive for a logged-in session
+		if status == nil || !status.LoggedIn {
+			continue
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, preflightTimeout)
+		access, err := dl.CheckRecording(checkCtx, rawURL, session)
+		cancel()
+		if err != nil {
+			Logger.Warn("preflight skipped: recording could not be checked",
+				"url", connectdl.RedactURL(rawURL), "error", err)
+			continue
+		}
+		if !access.Accessible {
+			denied[rawURL] = access.Reason
+			Logger.Error("recording not accessible", "url", connectdl.RedactURL(rawURL), "reason", access.Reason)
+		}
+	}
+
+	return denied, nil
+}
+
+// preflightSession checks a session on host. It returns nil when the host
+// can't be checked, so its recordings are downloaded without a preflight.
+func preflightSession(
+	ctx context.Context,
+	dl *connectdl.Client,
+	host, session string,
+) (*connectdl.SessionStatus, error) {
+	checkCtx, cancel := context.WithTimeout(ctx, preflightTimeout)
+	defer cancel()
+	status, err := dl.CheckSession(checkCtx, host, session)
+	if errors.Is(err, connectdl.ErrAPIUnavailable) || connectdl.CategoryOf(err) == connectdl.CategoryNetwork {
+		Logger.Warn("preflight skipped: connect API unavailable", "host", host, "error", err)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("preflight for %s: %w", host, err)
+	}
+	if session != "" && !status.LoggedIn {
+		return nil, fmt.Errorf("%w: session token for %s is expired or invalid", connectdl.ErrAuthRequired, status.Host)
+	}
+	if status.LoggedIn {
+		Logger.Info("session valid", "host", status.Host, "user", status.UserName)
+	}
+	return &status, nil
+}
+
+// recordingHost returns the origin of a recording URL or bare host, as the
+// downloader sees it, or the redacted URL when it can't be parsed.
+func recordingHost(rawURL string) string {
+	origin, _, err := connectdl.ParseTarget(rawURL)
+	if err != nil {
+		return connectdl.RedactURL(rawURL)
+	}
+	return origin
+}
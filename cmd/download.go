@@ -5,8 +5,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -14,26 +17,49 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/keanucz/AdobeConnectDL/internal/downloader"
+	"github.com/keanucz/AdobeConnectDL/connectdl"
 	"github.com/keanucz/AdobeConnectDL/internal/mp4box"
 	"github.com/keanucz/AdobeConnectDL/internal/version"
 )
 
 var (
-	outputDirFlag string
-	sessionFlag   string
-	urlFileFlag   string
-	overwriteFlag bool
+	outputDirFlag  string
+	sessionFlag    string
+	urlFileFlag    string
+	overwriteFlag  bool
+	noPreflight    bool
+	dryRunFlag     bool
+	patternsFlag   string
+	zipWaitFlag    time.Duration
+	selectiveFlag  bool
+	streamFlag     bool
+	maxFilesFlag   int
+	maxSizeFlag    string
+	retentionFlag  string
+	chatFormatFlag string
+	snapshotsFlag  bool
+	aggregateFlag  bool
+	chaptersFlag   string
+	chatCueFlag    time.Duration
 )
 
-// makeProgressCallback creates a progress callback that logs at 10% intervals.
+// makeEventHandler creates an event handler that logs video progress at 10% intervals
+// and waits for the server to prepare the raw ZIP.
 // The recordingID identifies which recording is being downloaded.
-func makeProgressCallback(recordingID string, logger interface {
+func makeEventHandler(recordingID string, logger interface {
 	Info(msg any, keyvals ...any)
-}) downloader.ProgressCallback {
+}) connectdl.EventHandler {
 	var lastPercent int64 = -1
 	first := true
-	return func(downloaded, total int64) {
+	return func(e connectdl.Event) {
+		if e.Kind == connectdl.EventWaiting {
+			logger.Info(e.Message, "recording", recordingID)
+			return
+		}
+		if e.Kind != connectdl.EventProgress || e.Asset != "mp4" {
+			return
+		}
+		downloaded, total := e.Downloaded, e.Total
 		if total <= 0 {
 			return
 		}
@@ -75,6 +101,106 @@ func init() {
 		false,
 		"Overwrite existing directories without prompting",
 	)
+	downloadCmd.Flags().BoolVar(
+		&noPreflight,
+		"no-preflight",
+		false,
+		"Skip the session and recording access check before the batch starts",
+	)
+	downloadCmd.Flags().BoolVar(
+		&dryRunFlag,
+		"dry-run",
+		false,
+		"Report the assets that would be downloaded without writing any files",
+	)
+	downloadCmd.Flags().BoolVar(&jsonFlag, "json", false, "Print --dry-run results as JSON")
+	downloadCmd.Flags().DurationVar(
+		&zipWaitFlag,
+		"zip-wait",
+		connectdl.DefaultZipWait,
+		"How long to wait for the server to prepare the raw ZIP (0 to skip waiting)",
+	)
+	downloadCmd.Flags().BoolVar(
+		&selectiveFlag,
+		"selective-zip",
+		false,
+		"Fetch only captions, chat and document data from the raw ZIP instead of downloading it",
+	)
+	downloadCmd.Flags().BoolVar(
+		&streamFlag,
+		"stream-extract",
+		false,
+		"Extract the raw ZIP while it downloads instead of after",
+	)
+	downloadCmd.Flags().IntVar(
+		&maxFilesFlag,
+		"max-extract-files",
+		connectdl.DefaultExtractLimits().MaxFiles,
+		"Refuse to extract raw ZIPs with more entries than this",
+	)
+	downloadCmd.Flags().StringVar(
+		&maxSizeFlag,
+		"max-extract-size",
+		"64G",
+		"Refuse to extract raw ZIPs that expand to more than this (e.g. 500M, 64G)",
+	)
+	downloadCmd.Flags().StringVar(
+		&retentionFlag,
+		"retention",
+		string(connectdl.RetainAll),
+		"Raw data to keep after processing: keep-all, keep-zip, keep-extracted, keep-xml-only or delete",
+	)
+	downloadCmd.Flags().StringVar(
+		&chatFormatFlag,
+		"chat-format",
+		string(connectdl.ChatText),
+		"Chat outputs to write, comma-separated: txt, json, csv, md, html or all",
+	)
+	downloadCmd.Flags().BoolVar(
+		&snapshotsFlag,
+		"whiteboard-snapshots",
+		false,
+		"Also render each whiteboard page as it was at every page change",
+	)
+	downloadCmd.Flags().BoolVar(
+		&aggregateFlag,
+		"attendance-aggregate",
+		false,
+		"Write only aggregate counts to the attendance report, without names or times",
+	)
+	downloadCmd.Flags().DurationVar(
+		&chatCueFlag,
+		"chat-cue",
+		connectdl.DefaultChatCueDuration,
+		"How long each chat message is shown in the chat subtitle track (0 = no chat.vtt)",
+	)
+	downloadCmd.Flags().StringVar(
+		&chaptersFlag,
+		"chapters",
+		"document,layout",
+		"Events that start a chapter, comma-separated: document, slide, layout, all or none",
+	)
+	downloadCmd.Flags().StringVar(
+		&patternsFlag,
+		"patterns",
+		"",
+		"JSON file of custom page extraction patterns for this institution's player",
+	)
+}
+
+// parseBytes parses a size such as "512", "500M" or "64G" using binary units.
+func parseBytes(size string) (int64, error) {
+	s := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(size)), "B")
+	shift := 0
+	if i := strings.IndexAny(s, "KMGT"); i >= 0 && i == len(s)-1 {
+		shift = 10 * (strings.IndexByte("KMGT", s[i]) + 1)
+		s = s[:i]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64>>shift {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return n << shift, nil
 }
 
 // formatBytes converts bytes to human readable format.
@@ -105,7 +231,8 @@ Examples:
   adobeconnectdl download https://example.com/recording1
   adobeconnectdl download https://example.com/recording1 https://example.com/recording2
   adobeconnectdl download -f urls.txt
-  adobeconnectdl download -y https://example.com/recording1  # overwrite existing`,
+  adobeconnectdl download -y https://example.com/recording1  # overwrite existing
+  adobeconnectdl download --dry-run -f urls.txt               # report assets only`,
 	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Collect all URLs from args and file
@@ -131,6 +258,27 @@ Examples:
 		// Remove duplicates while preserving order
 		urls = deduplicateURLs(urls)
 
+		limits, err := extractLimits()
+		if err != nil {
+			return err
+		}
+		retention, err := connectdl.ParseRetention(retentionFlag)
+		if err != nil {
+			return fmt.Errorf("--retention: %w", err)
+		}
+		chatFormats, err := connectdl.ParseChatFormats(chatFormatFlag)
+		if err != nil {
+			return fmt.Errorf("--chat-format: %w", err)
+		}
+		chapterRules, err := connectdl.ParseChapterRules(chaptersFlag)
+		if err != nil {
+			return fmt.Errorf("--chapters: %w", err)
+		}
+
+		if dryRunFlag {
+			return runProbes(cmd, urls)
+		}
+
 		// Display version banner
 		fmt.Println()
 		fmt.Println("╭──────────────────────────────────────╮")
@@ -141,9 +289,9 @@ Examples:
 		Logger.Info("starting batch download", "count", len(urls))
 
 		// Try to locate MP4Box for subtitle embedding
-		var mp4boxRunner *mp4box.Runner
+		var embedder connectdl.SubtitleEmbedder
 		if runner, err := mp4box.New(""); err == nil {
-			mp4boxRunner = runner
+			embedder = mp4boxEmbedder{runner}
 			Logger.Info("MP4Box located", "path", runner.Path())
 		} else {
 			Logger.Warn("MP4Box not available, subtitles will not be embedded")
@@ -152,6 +300,7 @@ Examples:
 		// Track results
 		var successful, failed int
 		var failedURLs []string
+		var failures []error
 		var totalBytes int64
 		batchStartTime := time.Now()
 
@@ -185,16 +334,48 @@ Examples:
 
 		// Create shared download pool for all recordings
 		// This allows MP4, ZIP, and document downloads to share workers across recordings
-		poolConfig := downloader.PoolConfig{
+		poolConfig := connectdl.PoolConfig{
 			NumWorkers: 12,   // Match maxConcurrentRecordings for optimal throughput
 			QueueSize:  1000, // Large queue to handle bursts
 			Logger:     Logger,
 		}
-		pool := downloader.NewDownloadPool(client, poolConfig)
+		pool := connectdl.NewPool(client, poolConfig)
 		pool.Start()
 		defer pool.Stop()
 
-		dl := downloader.NewWithPool(client, pool)
+		extractors, err := loadExtractors()
+		if err != nil {
+			return err
+		}
+
+		dl := connectdl.New(
+			connectdl.WithHTTPClient(client),
+			connectdl.WithLogger(Logger),
+			connectdl.WithPool(pool),
+			connectdl.WithEmbedder(embedder), // Subtitle embedding handled inside Download()
+			connectdl.WithExtractors(extractors),
+		)
+
+		// Fail fast on expired sessions before queueing the whole batch
+		if !noPreflight {
+			denied, err := preflightBatch(cmd.Context(), dl, urls)
+			if err != nil {
+				return err
+			}
+			if len(denied) > 0 {
+				accessible := make([]string, 0, len(urls))
+				for _, u := range urls {
+					if reason, ok := denied[u]; ok {
+						failed++
+						failedURLs = append(failedURLs, u)
+						failures = append(failures, fmt.Errorf("%w: %s", connectdl.ErrAuthRequired, reason))
+						continue
+					}
+					accessible = append(accessible, u)
+				}
+				urls = accessible
+			}
+		}
 
 		// Process URLs - concurrent if overwrite flag is set, sequential otherwise (for prompts)
 		if len(urls) > 1 && overwriteFlag {
@@ -222,13 +403,21 @@ Examples:
 					// Create a short ID for progress logging
 					recordingID := fmt.Sprintf("%d/%d", idx+1, len(urls))
 
-					opts := downloader.Options{
-						OutputDir:  outputDir,
-						Session:    sessionFlag,
-						Log:        Logger,
-						Overwrite:  true,
-						MP4Box:     mp4boxRunner, // Subtitle embedding handled inside Download()
-						OnProgress: makeProgressCallback(recordingID, Logger),
+					opts := connectdl.DownloadOptions{
+						OutputDir:           outputDir,
+						Session:             sessionFlag,
+						Overwrite:           true,
+						OnEvent:             makeEventHandler(recordingID, Logger),
+						ZipWait:             zipWait(),
+						SelectiveZip:        selectiveFlag,
+						StreamExtract:       streamFlag,
+						ExtractLimits:       limits,
+						Retention:           retention,
+						ChatFormats:         chatFormats,
+						WhiteboardSnapshots: snapshotsFlag,
+						AttendanceAggregate: aggregateFlag,
+						ChatCueDuration:     chatCue(chatCueFlag),
+						ChapterRules:        chapterRules,
 					}
 
 					ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Minute)
@@ -240,6 +429,7 @@ Examples:
 						atomic.AddInt32(&failCount, 1)
 						failedMu.Lock()
 						failedURLs = append(failedURLs, url)
+						failures = append(failures, err)
 						failedMu.Unlock()
 						return
 					}
@@ -257,7 +447,7 @@ Examples:
 
 			wg.Wait()
 			successful = int(successCount)
-			failed = int(failCount)
+			failed += int(failCount)
 		} else {
 			// Sequential processing (single URL or no overwrite flag)
 			for i, rawURL := range urls {
@@ -266,20 +456,28 @@ Examples:
 				// Create a short ID for progress logging
 				recordingID := fmt.Sprintf("%d/%d", i+1, len(urls))
 
-				opts := downloader.Options{
-					OutputDir:  outputDir,
-					Session:    sessionFlag,
-					Log:        Logger,
-					Overwrite:  overwriteFlag,
-					MP4Box:     mp4boxRunner, // Subtitle embedding handled inside Download()
-					OnProgress: makeProgressCallback(recordingID, Logger),
+				opts := connectdl.DownloadOptions{
+					OutputDir:           outputDir,
+					Session:             sessionFlag,
+					Overwrite:           overwriteFlag,
+					OnEvent:             makeEventHandler(recordingID, Logger),
+					ZipWait:             zipWait(),
+					SelectiveZip:        selectiveFlag,
+					StreamExtract:       streamFlag,
+					ExtractLimits:       limits,
+					Retention:           retention,
+					ChatFormats:         chatFormats,
+					WhiteboardSnapshots: snapshotsFlag,
+					AttendanceAggregate: aggregateFlag,
+					ChatCueDuration:     chatCue(chatCueFlag),
+					ChapterRules:        chapterRules,
 				}
 
 				ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Minute)
 				result, err := dl.Download(ctx, rawURL, opts)
 
 				// Handle directory exists error with prompt
-				if errors.Is(err, downloader.ErrDirectoryExists) && !overwriteFlag {
+				if errors.Is(err, connectdl.ErrDirectoryExists) && !overwriteFlag {
 					fmt.Fprintf(cmd.OutOrStdout(), "\033[1;33m⚠  Directory already exists.\033[0m Overwrite? [y/N]: ")
 					reader := bufio.NewReader(os.Stdin)
 					response, _ := reader.ReadString('\n')
@@ -300,6 +498,7 @@ Examples:
 					Logger.Error("failed to download recording", "url", rawURL, "error", err)
 					failed++
 					failedURLs = append(failedURLs, rawURL)
+					failures = append(failures, err)
 					continue
 				}
 
@@ -347,13 +546,80 @@ Examples:
 			for _, u := range failedURLs {
 				fmt.Fprintf(cmd.OutOrStdout(), "  \033[31m✗\033[0m %s\n", u)
 			}
-			return fmt.Errorf("%d download(s) failed", failed)
+			return fmt.Errorf("%d download(s) failed: %w", failed, errors.Join(failures...))
 		}
 
 		return nil
 	},
 }
 
+// zipWait converts --zip-wait to the library option, where zero means the default.
+func zipWait() time.Duration {
+	if zipWaitFlag <= 0 {
+		return -1
+	}
+	return zipWaitFlag
+}
+
+// chatCue converts --chat-cue to the library option. Zero or a negative
+// duration turns chat.vtt off; the flag itself carries the default.
+func chatCue(flag time.Duration) time.Duration {
+	if flag <= 0 {
+		return -1
+	}
+	return flag
+}
+
+// mp4boxEmbedder lets the downloader add the captions, chat subtitles and
+// chapters to a recording in a single MP4Box run.
+type mp4boxEmbedder struct{ *mp4box.Runner }
+
+// EmbedTracks implements connectdl.TrackEmbedder.
+func (e mp4boxEmbedder) EmbedTracks(
+	ctx context.Context,
+	mp4Path string,
+	subtitles []connectdl.SubtitleTrack,
+	chaptersPath string,
+	stdout, stderr io.Writer,
+) error {
+	tracks := make([]mp4box.Subtitle, len(subtitles))
+	for i, s := range subtitles {
+		tracks[i] = mp4box.Subtitle(s)
+	}
+	return e.Embed(ctx, mp4Path, tracks, chaptersPath, stdout, stderr)
+}
+
+// extractLimits converts --max-extract-files and --max-extract-size to library limits.
+func extractLimits() (connectdl.ExtractLimits, error) {
+	size, err := parseBytes(maxSizeFlag)
+	if err != nil {
+		return connectdl.ExtractLimits{}, fmt.Errorf("--max-extract-size: %w", err)
+	}
+	return connectdl.ExtractLimits{MaxFiles: maxFilesFlag, MaxTotalSize: size}, nil
+}
+
+// loadExtractors builds the page extractor registry, appending any custom
+// patterns from --patterns after the built-in extractors.
+func loadExtractors() (*connectdl.ExtractorRegistry, error) {
+	registry := connectdl.DefaultExtractors()
+	if patternsFlag == "" {
+		return registry, nil
+	}
+	rules, err := connectdl.LoadPatternRules(patternsFlag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load extraction patterns: %w", err)
+	}
+	for _, rule := range rules {
+		extractor, err := connectdl.NewPatternExtractor(rule)
+		if err != nil {
+			return nil, err
+		}
+		registry.Register(extractor)
+	}
+	Logger.Debug("custom extraction patterns loaded", "count", len(rules), "path", patternsFlag)
+	return registry, nil
+}
+
 // readURLsFromFile reads URLs from a text file, one per line.
 func readURLsFromFile(path string) ([]string, error) {
 	file, err := os.Open(path)
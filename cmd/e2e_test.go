@@ -0,0 +1,317 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/keanucz/AdobeConnectDL/connectdl"
+	"github.com/keanucz/AdobeConnectDL/connectdl/fakeconnect"
+)
+
+// runCLI executes the root command with args after resetting every flag to
+// its default, returning stdout.
+func runCLI(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var reset func(c *cobra.Command)
+	reset = func(c *cobra.Command) {
+		c.Flags().VisitAll(func(f *pflag.Flag) {
+			if sv, ok := f.Value.(pflag.SliceValue); ok {
+				_ = sv.Replace(nil)
+			} else {
+				_ = f.Value.Set(f.DefValue)
+			}
+			f.Changed = false
+		})
+		for _, sub := range c.Commands() {
+			reset(sub)
+		}
+	}
+	reset(rootCmd)
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	rootCmd.SetArgs(args)
+	err := rootCmd.ExecuteContext(context.Background())
+	return out.String(), err
+}
+
+func newFakeConnect(t *testing.T, recs ...fakeconnect.Recording) (*fakeconnect.Server, string) {
+	t.Helper()
+	srv := fakeconnect.New(recs...)
+	srv.AddSession("good", fakeconnect.User{Name: "Jane Doe", Login: "jane@example.com"})
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return srv, ts.URL
+}
+
+func TestDownloadEndToEnd(t *testing.T) {
+	rec := fakeconnect.SampleRecording("p1e2e")
+	rec.Private = true
+	_, origin := newFakeConnect(t, rec)
+	outDir := t.TempDir()
+
+	out, err := runCLI(t, "download", "-y", "-o", outDir, "--session", "good", origin+"/p1e2e/")
+	if err != nil {
+		t.Fatalf("download failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "1 successful") {
+		t.Errorf("expected success summary, got:\n%s", out)
+	}
+
+	root := filepath.Join(outDir, rec.Title)
+	for _, name := range []string{"recording.mp4", "captions.vtt", "transcript.txt", "chat_log.txt",
+		filepath.Join("documents", "Week 1 Slides.pdf")} {
+		if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "metadata.json"))
+	if err != nil {
+		t.Fatalf("read metadata: %v", err)
+	}
+	var meta struct {
+		Recording struct {
+			MeetingName string `json:"meeting_name"`
+		} `json:"recording"`
+		Lecturer string `json:"lecturer"`
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatalf("parse metadata: %v", err)
+	}
+	if meta.Recording.MeetingName != "SE101 Lectures" || meta.Lecturer != "Jane Doe" {
+		t.Errorf("unexpected metadata: %s", data)
+	}
+}
+
+func TestDownloadExpiredSessionFailsPreflight(t *testing.T) {
+	rec := fakeconnect.SampleRecording("p1exp")
+	rec.Private = true
+	srv, origin := newFakeConnect(t, rec)
+	srv.ExpireSession("good")
+
+	_, err := runCLI(t, "download", "-y", "-o", t.TempDir(), "--session", "good", origin+"/p1exp/")
+	if !errors.Is(err, connectdl.ErrAuthRequired) {
+		t.Fatalf("expected ErrAuthRequired from preflight, got %v", err)
+	}
+}
+
+func TestPreflightChecksEachSessionOnce(t *testing.T) {
+	srv, origin := newFakeConnect(t, fakeconnect.SampleRecording("p1pre1"), fakeconnect.SampleRecording("p1pre2"))
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+	logger, session := Logger, sessionFlag
+	Logger, sessionFlag = log.New(io.Discard), "good"
+	t.Cleanup(func() { Logger, sessionFlag = logger, session })
+
+	urls := []string{origin + "/p1pre1/", origin + "/p1pre2/", origin + "/p1gone/", unreachable.URL + "/p1down/"}
+	denied, err := preflightBatch(context.Background(), connectdl.New(), urls)
+	if err != nil {
+		t.Fatalf("expected the unreachable host to be skipped, got %v", err)
+	}
+	if len(denied) != 1 || denied[origin+"/p1gone/"] == "" {
+		t.Errorf("denied = %v, want only the missing recording", denied)
+	}
+	// One common-info for the host and one sco-by-url per recording
+	if hits := srv.Hits("", fakeconnect.AssetAPI); hits != 4 {
+		t.Errorf("API hits = %d, want 4", hits)
+	}
+}
+
+func TestAuthCheckLooksUpRecordingsOnlyWhenLoggedIn(t *testing.T) {
+	srv, origin := newFakeConnect(t, fakeconnect.SampleRecording("p1auth"))
+
+	out, err := runCLI(t, "auth", "check", origin+"/p1auth/")
+	if err != nil {
+		t.Fatalf("auth check as guest failed: %v\n%s", err, out)
+	}
+	// Guests can't query SCOs, so only common-info is called
+	if hits := srv.Hits("", fakeconnect.AssetAPI); hits != 1 || strings.Contains(out, "Recording:") {
+		t.Errorf("guest check made %d API calls:\n%s", hits, out)
+	}
+
+	out, err = runCLI(t, "auth", "check", "--session", "good", origin+"/p1auth/")
+	if err != nil {
+		t.Fatalf("auth check failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "accessible") {
+		t.Errorf("expected the recording to be checked:\n%s", out)
+	}
+}
+
+func TestRecordingHost(t *testing.T) {
+	for _, in := range []string{
+		"acme.adobeconnect.com",
+		"acme.adobeconnect.com/p1a2b3/",
+		"https://acme.adobeconnect.com/p1a2b3/?session=x",
+	} {
+		if got := recordingHost(in); got != "https://acme.adobeconnect.com" {
+			t.Errorf("recordingHost(%q) = %q", in, got)
+		}
+	}
+}
+
+func TestProbeEndToEnd(t *testing.T) {
+	srv, origin := newFakeConnect(t, fakeconnect.SampleRecording("p1probe"))
+	srv.InjectFault("p1probe", fakeconnect.AssetZip, fakeconnect.Fault{Kind: fakeconnect.FaultLoginPage})
+
+	out, err := runCLI(t, "probe", "--json", "-o", t.TempDir(), origin+"/p1probe/?session=secret")
+	if err != nil {
+		t.Fatalf("probe failed: %v\n%s", err, out)
+	}
+	if strings.Contains(out, "secret") {
+		t.Errorf("session leaked into probe output:\n%s", out)
+	}
+	var results []connectdl.ProbeResult
+	if err := json.Unmarshal([]byte(out), &results); err != nil {
+		t.Fatalf("parse probe output: %v\n%s", err, out)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected one result, got %d", len(results))
+	}
+	for _, a := range results[0].Assets {
+		switch a.Name {
+		case "zip":
+			if a.Available || a.Category != connectdl.CategoryLoginWall {
+				t.Errorf("expected login page to make the zip unavailable: %+v", a)
+			}
+		case "mp4", "vtt":
+			if !a.Available {
+				t.Errorf("expected %s to be available: %+v", a.Name, a)
+			}
+		}
+	}
+}
+
+func TestParseFaultSpec(t *testing.T) {
+	asset, fault, err := parseFaultSpec("zip=rate-limit:2")
+	if err != nil || asset != fakeconnect.AssetZip || fault.Kind != fakeconnect.FaultRateLimit || fault.Times != 2 {
+		t.Errorf("unexpected fault: %v %+v %v", asset, fault, err)
+	}
+	for _, bad := range []string{"zip", "video=slow", "zip=explode", "zip=slow:0"} {
+		if _, _, err := parseFaultSpec(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestDownloadLoginWallExitCode(t *testing.T) {
+	srv, origin := newFakeConnect(t, fakeconnect.SampleRecording("p1wall"))
+	srv.InjectFault("p1wall", fakeconnect.AssetPage, fakeconnect.Fault{Kind: fakeconnect.FaultLoginPage})
+
+	_, err := runCLI(t, "download", "-y", "--no-preflight", "-o", t.TempDir(), origin+"/p1wall/?session=secret")
+	if !errors.Is(err, connectdl.ErrLoginWall) {
+		t.Fatalf("expected login wall error, got %v", err)
+	}
+	if code := exitCode(err); code != exitLoginWall {
+		t.Errorf("exit code = %d, want %d", code, exitLoginWall)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("session leaked into error: %v", err)
+	}
+}
+
+func TestDownloadForbiddenAssetsExitCode(t *testing.T) {
+	srv, origin := newFakeConnect(t, fakeconnect.SampleRecording("p1forb"))
+	srv.InjectFault("p1forb", fakeconnect.AssetMP4, fakeconnect.Fault{Kind: fakeconnect.FaultForbidden})
+	srv.InjectFault("p1forb", fakeconnect.AssetZip, fakeconnect.Fault{Kind: fakeconnect.FaultForbidden})
+
+	_, err := runCLI(t, "download", "-y", "--no-preflight", "-o", t.TempDir(), origin+"/p1forb/")
+	if err == nil {
+		t.Fatal("expected the download to fail when both assets are forbidden")
+	}
+	if code := exitCode(err); code != exitSignedURLExpired {
+		t.Errorf("exit code = %d, want %d from the MP4 failure: %v", code, exitSignedURLExpired, err)
+	}
+	if !errors.Is(err, connectdl.ErrAuthRequired) {
+		t.Errorf("expected the ZIP failure to be kept: %v", err)
+	}
+}
+
+func TestInspectEndToEnd(t *testing.T) {
+	rec := fakeconnect.SampleRecording("p1ins")
+	_, origin := newFakeConnect(t, rec)
+	outDir := t.TempDir()
+
+	out, err := runCLI(t, "download", "-y", "-o", outDir, "--retention", "keep-all", origin+"/p1ins/")
+	if err != nil {
+		t.Fatalf("download failed: %v\n%s", err, out)
+	}
+	root := filepath.Join(outDir, rec.Title)
+	out, err = runCLI(t, "inspect", root)
+	if err != nil {
+		t.Fatalf("inspect failed: %v\n%s", err, out)
+	}
+	want := []string{"cameraVoip_1_3.flv (cameraVoip, Jane Doe)", "Start:    00:00:12", "audio aac 44100 Hz mono"}
+	for _, s := range want {
+		if !strings.Contains(out, s) {
+			t.Errorf("inspect output missing %q:\n%s", s, out)
+		}
+	}
+
+	// Without raw/ the inventory comes from metadata.json
+	if err := os.RemoveAll(filepath.Join(root, "raw")); err != nil {
+		t.Fatal(err)
+	}
+	out, err = runCLI(t, "inspect", "--json", root)
+	if err != nil {
+		t.Fatalf("inspect failed: %v\n%s", err, out)
+	}
+	var streams []connectdl.MediaStream
+	if err := json.Unmarshal([]byte(out), &streams); err != nil {
+		t.Fatalf("parse inspect output: %v\n%s", err, out)
+	}
+	if len(streams) != 1 || streams[0].AudioCodec != "aac" || streams[0].Duration != 1978 {
+		t.Errorf("unexpected streams: %s", out)
+	}
+
+	if _, err := runCLI(t, "inspect", t.TempDir()); err == nil {
+		t.Error("expected error for a directory without streams")
+	}
+}
+
+func TestReprocessAfterRetention(t *testing.T) {
+	rec := fakeconnect.SampleRecording("p1rep")
+	_, origin := newFakeConnect(t, rec)
+	outDir := t.TempDir()
+
+	out, err := runCLI(t, "download", "-y", "-o", outDir, "--retention", "delete", origin+"/p1rep/")
+	if err != nil {
+		t.Fatalf("download failed: %v\n%s", err, out)
+	}
+	root := filepath.Join(outDir, rec.Title)
+	for _, name := range []string{"raw.zip", "raw"} {
+		if _, err := os.Stat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should be deleted, got %v", name, err)
+		}
+	}
+
+	out, err = runCLI(t, "reprocess", "--retention", "keep-xml-only", root)
+	if err != nil {
+		t.Fatalf("reprocess failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "Reprocessed") || !strings.Contains(out, "keep-xml-only") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(root, "raw", "indexstream.xml")); err != nil {
+		t.Errorf("expected re-fetched XML streams: %v", err)
+	}
+
+	if _, err := runCLI(t, "download", "--retention", "keep-some", origin+"/p1rep/"); err == nil {
+		t.Error("expected error for unknown retention policy")
+	}
+}
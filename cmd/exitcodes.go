@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"github.com/keanucz/AdobeConnectDL/connectdl"
+)
+
+// Process exit codes. Each failure category gets its own code so scripts can
+// react (e.g. refresh the session on 3) without parsing the output.
+const (
+	exitOK               = 0
+	exitFailure          = 1
+	exitAuthExpired      = 3
+	exitLoginWall        = 4
+	exitSignedURLExpired = 5
+	exitNotGenerated     = 6
+	exitNetwork          = 7
+	exitDiskFull         = 8
+	exitToolMissing      = 9
+	exitRateLimited      = 10
+	exitInvalidContent   = 11
+	exitFilesystem       = 12
+)
+
+// exitCode maps an error to the process exit code for its category. When a
+// batch fails for several reasons, the first failure decides the code.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	switch connectdl.CategoryOf(err) {
+	case connectdl.CategoryAuthExpired:
+		return exitAuthExpired
+	case connectdl.CategoryLoginWall:
+		return exitLoginWall
+	case connectdl.CategorySignedURLExpired:
+		return exitSignedURLExpired
+	case connectdl.CategoryNotGenerated:
+		return exitNotGenerated
+	case connectdl.CategoryNetwork:
+		return exitNetwork
+	case connectdl.CategoryDiskFull:
+		return exitDiskFull
+	case connectdl.CategoryToolMissing:
+		return exitToolMissing
+	case connectdl.CategoryRateLimited:
+		return exitRateLimited
+	case connectdl.CategoryInvalidContent:
+		return exitInvalidContent
+	case connectdl.CategoryFilesystem:
+		return exitFilesystem
+	default:
+		return exitFailure
+	}
+}
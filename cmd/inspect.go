@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/keanucz/AdobeConnectDL/connectdl"
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <recording-dirs...>",
+	Short: "List the media streams of a downloaded recording",
+	Long: `List the media streams of a downloaded recording.
+
+Reads the FLV streams in raw/ and prints each one's type (cameraVoip,
+screenshare, ...), owner, start offset, duration, codecs, resolution and
+bitrate. Each argument is a recording directory written by download or its
+raw/ directory. When the retention policy has removed the FLV files, the
+inventory recorded in metadata.json is shown instead. Nothing is downloaded.
+
+Examples:
+  adobeconnectdl inspect "SE101 Lecture 1"
+  adobeconnectdl inspect --json "SE101 Lecture 1/raw"`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inventories := make(map[string][]connectdl.MediaStream, len(args))
+		var failures []error
+		for _, dir := range args {
+			streams, err := connectdl.Inspect(dir)
+			if err != nil {
+				Logger.Error("failed to inspect recording", "dir", dir, "error", err)
+				failures = append(failures, fmt.Errorf("%s: %w", dir, err))
+				continue
+			}
+			inventories[dir] = streams
+			if !jsonFlag {
+				printStreams(cmd.OutOrStdout(), dir, streams)
+			}
+		}
+		if jsonFlag {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			var err error
+			if len(args) == 1 {
+				err = enc.Encode(inventories[args[0]])
+			} else {
+				err = enc.Encode(inventories)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(failures) > 0 {
+			return fmt.Errorf("%d inspection(s) failed: %w", len(failures), errors.Join(failures...))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+
+	inspectCmd.Flags().BoolVar(
+		&jsonFlag,
+		"json",
+		false,
+		"Print the streams as JSON, keyed by directory when there are several",
+	)
+}
+
+// printStreams writes a human-readable stream inventory.
+func printStreams(w io.Writer, dir string, streams []connectdl.MediaStream) {
+	fmt.Fprintf(w, "\n\033[1m%s\033[0m\n", dir)
+	for _, s := range streams {
+		kind := s.Type
+		if s.Owner != "" {
+			kind += ", " + s.Owner
+		}
+		fmt.Fprintf(w, "  %s (%s)\n", s.Name, kind)
+		if s.Error != "" {
+			fmt.Fprintf(w, "    \033[31m✗\033[0m %s\n", s.Error)
+			continue
+		}
+		duration := time.Duration(s.Duration) * time.Millisecond
+		fmt.Fprintf(w, "    Start:    %s  Duration: %s  Size: %s", s.Time, duration, formatBytes(s.Size))
+		if s.Bitrate > 0 {
+			fmt.Fprintf(w, "  Bitrate: %d kbit/s", s.Bitrate/1000)
+		}
+		fmt.Fprintln(w)
+		var media []string
+		if s.VideoCodec != "" {
+			video := "video " + s.VideoCodec
+			if s.Width > 0 {
+				video += fmt.Sprintf(" %dx%d", s.Width, s.Height)
+			}
+			media = append(media, video)
+		}
+		if s.AudioCodec != "" {
+			audio := "audio " + s.AudioCodec
+			if s.SampleRate > 0 {
+				audio += fmt.Sprintf(" %d Hz", s.SampleRate)
+			}
+			switch s.Channels {
+			case 1:
+				audio += " mono"
+			case 2:
+				audio += " stereo"
+			}
+			media = append(media, audio)
+		}
+		if len(media) == 0 {
+			media = append(media, "no media")
+		}
+		fmt.Fprintf(w, "    Media:    %s\n", strings.Join(media, ", "))
+	}
+}
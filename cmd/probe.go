@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/keanucz/AdobeConnectDL/connectdl"
+)
+
+var jsonFlag bool
+
+var probeCmd = &cobra.Command{
+	Use:   "probe <recording-urls...>",
+	Short: "Report what a recording offers without downloading it",
+	Long: `Report what a recording offers without downloading it.
+
+Resolves the recording page, then checks the raw ZIP, MP4 and captions with
+HEAD requests and prints their sizes, the output directory that would be used,
+the authentication status and any problems found. No files are written.
+
+Examples:
+  adobeconnectdl probe https://example.com/recording1
+  adobeconnectdl probe --json https://example.com/recording1`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runProbes(cmd, deduplicateURLs(args))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(probeCmd)
+
+	probeCmd.Flags().StringVarP(
+		&outputDirFlag,
+		"output",
+		"o",
+		"",
+		"Output directory (defaults to current working directory)",
+	)
+	probeCmd.Flags().StringVar(
+		&sessionFlag,
+		"session",
+		"",
+		"BREEZESESSION token to access private recordings",
+	)
+	probeCmd.Flags().BoolVar(&jsonFlag, "json", false, "Print results as JSON")
+	probeCmd.Flags().StringVar(
+		&patternsFlag,
+		"patterns",
+		"",
+		"JSON file of custom page extraction patterns for this institution's player",
+	)
+}
+
+// runProbes probes each URL and prints the results as text or JSON.
+func runProbes(cmd *cobra.Command, urls []string) error {
+	outputDir := outputDirFlag
+	if outputDir == "" {
+		var err error
+		outputDir, err = os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+	}
+
+	extractors, err := loadExtractors()
+	if err != nil {
+		return err
+	}
+
+	dl := connectdl.New(
+		connectdl.WithHTTPClient(&http.Client{Timeout: 2 * time.Minute}),
+		connectdl.WithLogger(Logger),
+		connectdl.WithExtractors(extractors),
+	)
+	results := make([]connectdl.ProbeResult, 0, len(urls))
+	var failures int
+
+	for _, rawURL := range urls {
+		ctx, cancel := context.WithTimeout(cmd.Context(), 2*time.Minute)
+		res, err := dl.Probe(ctx, rawURL, connectdl.DownloadOptions{
+			OutputDir: outputDir,
+			Session:   sessionFlag,
+		})
+		cancel()
+		if err != nil {
+			Logger.Error("failed to probe recording", "url", connectdl.RedactURL(rawURL), "error", err)
+			failures++
+			continue
+		}
+		results = append(results, res)
+	}
+
+	if jsonFlag {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, res := range results {
+			printProbeResult(cmd.OutOrStdout(), res)
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d probe(s) failed", failures)
+	}
+	return nil
+}
+
+// printProbeResult writes a human-readable probe report.
+func printProbeResult(w io.Writer, res connectdl.ProbeResult) {
+	fmt.Fprintf(w, "\n\033[1m%s\033[0m\n", res.Title)
+	fmt.Fprintf(w, "  URL:        %s\n", res.URL)
+	fmt.Fprintf(w, "  Output dir: %s", res.OutputDir)
+	if res.DirExists {
+		fmt.Fprint(w, " \033[33m(exists)\033[0m")
+	}
+	fmt.Fprintln(w)
+
+	auth := "no session"
+	if res.Auth.SessionProvided {
+		auth = "session provided"
+	}
+	if res.Session != nil && res.Session.LoggedIn {
+		auth += fmt.Sprintf(", logged in as %s", res.Session.UserName)
+	}
+	if res.Auth.PageAccessible {
+		auth += ", page accessible"
+	} else {
+		auth += ", \033[31mpage not accessible\033[0m"
+	}
+	fmt.Fprintf(w, "  Auth:       %s\n", auth)
+
+	fmt.Fprintf(w, "  Assets:\n")
+	for _, a := range res.Assets {
+		if a.Available {
+			size := "size unknown"
+			if a.Size >= 0 {
+				size = formatBytes(a.Size)
+			}
+			fmt.Fprintf(w, "    \033[32m✓\033[0m %-4s %s\n", a.Name, size)
+		} else {
+			fmt.Fprintf(w, "    \033[31m✗\033[0m %-4s %s\n", a.Name, a.Problem)
+		}
+	}
+
+	if len(res.Problems) > 0 {
+		fmt.Fprintf(w, "  Problems:\n")
+		for _, p := range res.Problems {
+			fmt.Fprintf(w, "    - %s\n", p)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/keanucz/AdobeConnectDL/connectdl"
+)
+
+var (
+	reprocessRetentionFlag  string
+	reprocessChatFormatFlag string
+	reprocessSnapshotsFlag  bool
+	reprocessAggregateFlag  bool
+	reprocessChaptersFlag   string
+	reprocessChatCueFlag    time.Duration
+)
+
+var reprocessCmd = &cobra.Command{
+	Use:   "reprocess <recording-dirs...>",
+	Short: "Rebuild captions, transcript, chat and other derived files from raw data",
+	Long: `Rebuild captions, transcript, chat, timeline, attendance, Q&A, polls, notes,
+whiteboards, slide timeline, chapters and documents from raw data.
+
+Each argument is a recording directory written by download. The derived files
+are rebuilt from raw/ or raw.zip. Raw data deleted by the retention policy is
+fetched again, with range requests when the server supports them, and the
+policy recorded in metadata.json is applied again afterwards unless
+--retention overrides it. An existing recording.mp4 is left as downloaded, so
+its captions, chat and chapter tracks are not added twice; a missing one is
+rebuilt from the raw streams and gets them embedded.
+
+Examples:
+  adobeconnectdl reprocess "SE101 Lecture 1"
+  adobeconnectdl reprocess --retention keep-xml-only lectures/*
+  adobeconnectdl reprocess --chat-format all lectures/*`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var retention connectdl.Retention
+		if reprocessRetentionFlag != "" {
+			var err error
+			if retention, err = connectdl.ParseRetention(reprocessRetentionFlag); err != nil {
+				return fmt.Errorf("--retention: %w", err)
+			}
+		}
+
+		chatFormats, err := connectdl.ParseChatFormats(reprocessChatFormatFlag)
+		if err != nil {
+			return fmt.Errorf("--chat-format: %w", err)
+		}
+		chapterRules, err := connectdl.ParseChapterRules(reprocessChaptersFlag)
+		if err != nil {
+			return fmt.Errorf("--chapters: %w", err)
+		}
+
+		dl := connectdl.New(
+			connectdl.WithHTTPClient(&http.Client{Timeout: 30 * time.Minute}),
+			connectdl.WithLogger(Logger),
+		)
+		var failures []error
+		for _, dir := range args {
+			res, err := dl.Reprocess(cmd.Context(), dir, connectdl.DownloadOptions{
+				Session:             sessionFlag,
+				Retention:           retention,
+				ChatFormats:         chatFormats,
+				WhiteboardSnapshots: reprocessSnapshotsFlag,
+				AttendanceAggregate: reprocessAggregateFlag,
+				ChatCueDuration:     chatCue(reprocessChatCueFlag),
+				ChapterRules:        chapterRules,
+			})
+			if err != nil {
+				Logger.Error("failed to reprocess recording", "dir", dir, "error", err)
+				failures = append(failures, fmt.Errorf("%s: %w", dir, err))
+				continue
+			}
+			for _, w := range res.Warnings {
+				Logger.Warn(w)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "\033[32m✓\033[0m Reprocessed \"%s\" (%s)\n", res.Title, res.Retention)
+		}
+
+		if len(failures) > 0 {
+			return fmt.Errorf("%d reprocess(es) failed: %w", len(failures), errors.Join(failures...))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reprocessCmd)
+
+	reprocessCmd.Flags().StringVar(
+		&sessionFlag,
+		"session",
+		"",
+		"BREEZESESSION token, needed only if deleted raw data must be fetched again",
+	)
+	reprocessCmd.Flags().StringVar(
+		&reprocessRetentionFlag,
+		"retention",
+		"",
+		"Retention policy to apply afterwards (defaults to the one recorded in metadata.json)",
+	)
+	reprocessCmd.Flags().StringVar(
+		&reprocessChatFormatFlag,
+		"chat-format",
+		string(connectdl.ChatText),
+		"Chat outputs to write, comma-separated: txt, json, csv, md, html or all",
+	)
+	reprocessCmd.Flags().BoolVar(
+		&reprocessSnapshotsFlag,
+		"whiteboard-snapshots",
+		false,
+		"Also render each whiteboard page as it was at every page change",
+	)
+	reprocessCmd.Flags().BoolVar(
+		&reprocessAggregateFlag,
+		"attendance-aggregate",
+		false,
+		"Write only aggregate counts to the attendance report, without names or times",
+	)
+	reprocessCmd.Flags().DurationVar(
+		&reprocessChatCueFlag,
+		"chat-cue",
+		connectdl.DefaultChatCueDuration,
+		"How long each chat message is shown in the chat subtitle track (0 = no chat.vtt)",
+	)
+	reprocessCmd.Flags().StringVar(
+		&reprocessChaptersFlag,
+		"chapters",
+		"document,layout",
+		"Events that start a chapter, comma-separated: document, slide, layout, all or none",
+	)
+}
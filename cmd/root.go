@@ -7,6 +7,7 @@ import (
 	"github.com/charmbracelet/log"
 	"github.com/spf13/cobra"
 
+	"github.com/keanucz/AdobeConnectDL/connectdl"
 	"github.com/keanucz/AdobeConnectDL/internal/version"
 )
 
@@ -38,7 +39,10 @@ var rootCmd = &cobra.Command{
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		if hint := connectdl.Remediation(connectdl.CategoryOf(err)); hint != "" {
+			fmt.Fprintln(os.Stderr, "hint:", hint)
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/keanucz/AdobeConnectDL/connectdl/fakeconnect"
+)
+
+var (
+	fakeAddrFlag       string
+	fakeRecordingsFlag []string
+	fakeSessionsFlag   []string
+	fakePrivateFlag    bool
+	fakeFaultsFlag     []string
+)
+
+var serveFakeCmd = &cobra.Command{
+	Use:   "serve-fake",
+	Short: "Run a fake Adobe Connect server for offline testing",
+	Long: `Run a fake Adobe Connect server for offline testing.
+
+Serves sample recordings with a CAS player page, raw ZIP (chat, attendees and
+shared documents), signed MP4 redirect, captions and the XML API, so the
+download, probe and auth commands can be exercised without a real server.
+
+Faults are given as asset=kind[:times]. Assets: page, zip, mp4, signed-mp4,
+captions, document, api. Kinds: slow, truncate, rate-limit, login-page,
+not-found, forbidden, server, preparing. Without :times the fault applies to
+every request.
+
+Examples:
+  adobeconnectdl serve-fake
+  adobeconnectdl serve-fake --private --session good --recording p1abc
+  adobeconnectdl serve-fake --fault zip=rate-limit:2 --fault signed-mp4=truncate`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		srv, err := newFakeServer()
+		if err != nil {
+			return err
+		}
+
+		ln, err := net.Listen("tcp", fakeAddrFlag)
+		if err != nil {
+			return fmt.Errorf("listen: %w", err)
+		}
+		origin := "http://" + ln.Addr().String()
+		for _, id := range fakeRecordingsFlag {
+			fmt.Fprintf(cmd.OutOrStdout(), "recording: %s/%s/\n", origin, id)
+		}
+		for _, token := range fakeSessionsFlag {
+			fmt.Fprintf(cmd.OutOrStdout(), "session:   %s\n", token)
+		}
+
+		server := &http.Server{Handler: srv, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			<-cmd.Context().Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+
+		Logger.Info("fake connect server listening", "addr", origin)
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveFakeCmd)
+
+	serveFakeCmd.Flags().StringVar(&fakeAddrFlag, "addr", "127.0.0.1:8080", "Address to listen on")
+	serveFakeCmd.Flags().StringSliceVar(
+		&fakeRecordingsFlag,
+		"recording",
+		[]string{"p1sample"},
+		"Recording IDs to serve (repeatable)",
+	)
+	serveFakeCmd.Flags().StringSliceVar(&fakeSessionsFlag, "session", nil, "Valid BREEZESESSION tokens (repeatable)")
+	serveFakeCmd.Flags().BoolVar(&fakePrivateFlag, "private", false, "Require a valid session for recordings")
+	serveFakeCmd.Flags().StringArrayVar(
+		&fakeFaultsFlag,
+		"fault",
+		nil,
+		"Inject a fault into every recording as asset=kind[:times] (repeatable)",
+	)
+}
+
+// newFakeServer builds the fake server from the serve-fake flags.
+func newFakeServer() (*fakeconnect.Server, error) {
+	srv := fakeconnect.New()
+	for _, id := range fakeRecordingsFlag {
+		rec := fakeconnect.SampleRecording(id)
+		rec.Private = fakePrivateFlag
+		srv.AddRecording(rec)
+	}
+	for _, token := range fakeSessionsFlag {
+		srv.AddSession(token, fakeconnect.User{Name: "Jane Doe", Login: "jane@example.com"})
+	}
+	for _, spec := range fakeFaultsFlag {
+		asset, fault, err := parseFaultSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		if asset == fakeconnect.AssetAPI {
+			srv.InjectFault("", asset, fault)
+			continue
+		}
+		for _, id := range fakeRecordingsFlag {
+			srv.InjectFault(id, asset, fault)
+		}
+	}
+	return srv, nil
+}
+
+// parseFaultSpec parses asset=kind[:times].
+func parseFaultSpec(spec string) (fakeconnect.Asset, fakeconnect.Fault, error) {
+	assetName, rest, ok := strings.Cut(spec, "=")
+	if !ok {
+		return "", fakeconnect.Fault{}, fmt.Errorf("invalid fault %q: want asset=kind[:times]", spec)
+	}
+	asset, err := fakeconnect.ParseAsset(assetName)
+	if err != nil {
+		return "", fakeconnect.Fault{}, err
+	}
+	kindName, timesStr, hasTimes := strings.Cut(rest, ":")
+	kind, err := fakeconnect.ParseFaultKind(kindName)
+	if err != nil {
+		return "", fakeconnect.Fault{}, err
+	}
+	fault := fakeconnect.Fault{Kind: kind}
+	if hasTimes {
+		fault.Times, err = strconv.Atoi(timesStr)
+		if err != nil || fault.Times < 1 {
+			return "", fakeconnect.Fault{}, fmt.Errorf("invalid fault %q: times must be a positive number", spec)
+		}
+	}
+	return asset, fault, nil
+}
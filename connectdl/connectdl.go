@@ -0,0 +1,483 @@
+// Package connectdl is the public Go API for downloading Adobe Connect recordings.
+//
+// It exposes the same pipeline the adobeconnectdl CLI uses: resolve a recording,
+// download the raw ZIP and MP4, extract captions, chat and documents, and write
+// metadata.json. The API follows semantic versioning: APIVersion only changes
+// major version when an exported identifier is removed or changes meaning.
+//
+//	client := connectdl.New(connectdl.WithLogger(logger))
+//	res, err := client.Download(ctx, url, connectdl.DownloadOptions{OutputDir: "lectures"})
+package connectdl
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/keanucz/AdobeConnectDL/internal/downloader"
+)
+
+// APIVersion is the version of this package's API.
+const APIVersion = "1.1.0"
+
+// DefaultZipWait is how long Download waits for the server to prepare the raw ZIP.
+const DefaultZipWait = downloader.DefaultZipWait
+
+// DefaultChatCueDuration is how long each chat message is shown in chat.vtt.
+const DefaultChatCueDuration = downloader.DefaultChatCueDuration
+
+// Re-exported types. They are aliases, so values can be passed between this
+// package and any code built on it without conversion.
+type (
+	HTTPClient        = downloader.HTTPClient
+	Logger            = downloader.Logger
+	SubtitleEmbedder  = downloader.SubtitleEmbedder
+	ProgressCallback  = downloader.ProgressCallback
+	Result            = downloader.Result
+	RecordingDetails  = downloader.RecordingDetails
+	DocumentInfo      = downloader.DocumentInfo
+	ProbeResult       = downloader.ProbeResult
+	ProbeAuth         = downloader.ProbeAuth
+	AssetProbe        = downloader.AssetProbe
+	SessionStatus     = downloader.SessionStatus
+	RecordingAccess   = downloader.RecordingAccess
+	Event             = downloader.Event
+	EventKind         = downloader.EventKind
+	EventHandler      = downloader.EventHandler
+	Pool              = downloader.DownloadPool
+	PoolConfig        = downloader.PoolConfig
+	ExtractorRegistry = downloader.ExtractorRegistry
+	PageExtractor     = downloader.PageExtractor
+	Candidate         = downloader.Candidate
+	CandidateKind     = downloader.CandidateKind
+	PatternRule       = downloader.PatternRule
+	DownloadError     = downloader.DownloadError
+	ErrorCategory     = downloader.ErrorCategory
+	AssetStatus       = downloader.AssetStatus
+	AssetState        = downloader.AssetState
+	ExtractLimits     = downloader.ExtractLimits
+	Retention         = downloader.Retention
+	StreamMessage     = downloader.StreamMessage
+	StreamNode        = downloader.StreamNode
+	StreamDecoder     = downloader.StreamDecoder
+	SessionTimeline   = downloader.SessionTimeline
+	SessionEvent      = downloader.SessionEvent
+	ChatFormat        = downloader.ChatFormat
+	ChatMessage       = downloader.ChatMessage
+	ChatAudience      = downloader.ChatAudience
+	QAQuestion        = downloader.QAQuestion
+	QAAnswer          = downloader.QAAnswer
+	Poll              = downloader.Poll
+	PollOption        = downloader.PollOption
+	PollResponse      = downloader.PollResponse
+	PollAnswerType    = downloader.PollAnswerType
+	Note              = downloader.Note
+	NoteRevision      = downloader.NoteRevision
+	Link              = downloader.Link
+)
+
+// Whiteboard drawings returned by Whiteboards.
+type (
+	Whiteboard         = downloader.Whiteboard
+	WhiteboardShape    = downloader.WhiteboardShape
+	WhiteboardSnapshot = downloader.WhiteboardSnapshot
+)
+
+// SlideChange is a document page shown in a share pod, as returned by Slides.
+type SlideChange = downloader.SlideChange
+
+// MediaStream is one FLV stream of a raw recording, as returned by Streams
+// and Inspect.
+type MediaStream = downloader.MediaStream
+
+// Chapter types for DownloadOptions.ChapterRules and Chapters.
+type (
+	Chapter     = downloader.Chapter
+	ChapterRule = downloader.ChapterRule
+)
+
+// Embedder types for WithEmbedder. An embedder that is also a TrackEmbedder
+// adds the captions, chat subtitles and chapters to the MP4 in one pass.
+type (
+	TrackEmbedder = downloader.TrackEmbedder
+	SubtitleTrack = downloader.SubtitleTrack
+)
+
+// Attendance report types returned by Attendance.
+type (
+	AttendanceReport  = downloader.AttendanceReport
+	Attendee          = downloader.Attendee
+	AttendanceSession = downloader.AttendanceSession
+	AttendanceSummary = downloader.AttendanceSummary
+)
+
+// Participant roles reported in Attendee.Role.
+const (
+	RoleHost        = downloader.RoleHost
+	RolePresenter   = downloader.RolePresenter
+	RoleParticipant = downloader.RoleParticipant
+)
+
+// Event kinds.
+const (
+	EventStarted     = downloader.EventStarted
+	EventProgress    = downloader.EventProgress
+	EventWaiting     = downloader.EventWaiting
+	EventAssetDone   = downloader.EventAssetDone
+	EventAssetFailed = downloader.EventAssetFailed
+	EventWarning     = downloader.EventWarning
+	EventFinished    = downloader.EventFinished
+)
+
+// Raw data retention policies for DownloadOptions.Retention.
+const (
+	RetainAll       = downloader.RetainAll
+	RetainZip       = downloader.RetainZip
+	RetainExtracted = downloader.RetainExtracted
+	RetainXMLOnly   = downloader.RetainXMLOnly
+	RetainNone      = downloader.RetainNone
+)
+
+// Chat output formats for DownloadOptions.ChatFormats.
+const (
+	ChatText     = downloader.ChatText
+	ChatJSON     = downloader.ChatJSON
+	ChatCSV      = downloader.ChatCSV
+	ChatMarkdown = downloader.ChatMarkdown
+	ChatHTML     = downloader.ChatHTML
+)
+
+// Chapter rules for DownloadOptions.ChapterRules.
+const (
+	ChapterDocument = downloader.ChapterDocument
+	ChapterSlide    = downloader.ChapterSlide
+	ChapterLayout   = downloader.ChapterLayout
+)
+
+// Chat message audiences reported in ChatMessage.Audience.
+const (
+	AudiencePublic  = downloader.AudiencePublic
+	AudiencePrivate = downloader.AudiencePrivate
+	AudienceHosts   = downloader.AudienceHosts
+)
+
+// Poll answer types reported in Poll.AnswerType.
+const (
+	PollSingle      = downloader.PollSingle
+	PollMultiple    = downloader.PollMultiple
+	PollShortAnswer = downloader.PollShortAnswer
+)
+
+// Whiteboard shape kinds reported in WhiteboardShape.Kind.
+const (
+	ShapeLine        = downloader.ShapeLine
+	ShapeArrow       = downloader.ShapeArrow
+	ShapeRect        = downloader.ShapeRect
+	ShapeEllipse     = downloader.ShapeEllipse
+	ShapePath        = downloader.ShapePath
+	ShapeHighlighter = downloader.ShapeHighlighter
+	ShapeText        = downloader.ShapeText
+)
+
+// Asset states reported in Result.Assets.
+const (
+	StateOK      = downloader.StateOK
+	StateSkipped = downloader.StateSkipped
+	StateMissing = downloader.StateMissing
+	StateFailed  = downloader.StateFailed
+)
+
+// Asset names reported in Result.Assets.
+const (
+	AssetPage       = downloader.AssetPage
+	AssetZip        = downloader.AssetZip
+	AssetExtraction = downloader.AssetExtraction
+	AssetMP4        = downloader.AssetMP4
+	AssetRemux      = downloader.AssetRemux
+	AssetCaptions   = downloader.AssetCaptions
+	AssetTranscript = downloader.AssetTranscript
+	AssetChat       = downloader.AssetChat
+	AssetChatVTT    = downloader.AssetChatVTT
+	AssetSession    = downloader.AssetSession
+	AssetQA         = downloader.AssetQA
+	AssetPolls      = downloader.AssetPolls
+	AssetNotes      = downloader.AssetNotes
+	AssetLinks      = downloader.AssetLinks
+	AssetWhiteboard = downloader.AssetWhiteboard
+	AssetAttendance = downloader.AssetAttendance
+	AssetSlides     = downloader.AssetSlides
+	AssetChapters   = downloader.AssetChapters
+	AssetSubtitles  = downloader.AssetSubtitles
+	AssetMarkers    = downloader.AssetMarkers
+	AssetChatTrack  = downloader.AssetChatTrack
+	AssetDocument   = downloader.AssetDocument
+)
+
+// Event types in SessionTimeline.Events.
+const (
+	SessionJoin      = downloader.SessionJoin
+	SessionLeave     = downloader.SessionLeave
+	SessionChat      = downloader.SessionChat
+	SessionFileShare = downloader.SessionFileShare
+	SessionSlide     = downloader.SessionSlide
+	SessionPod       = downloader.SessionPod
+	SessionMetadata  = downloader.SessionMetadata
+	SessionMessage   = downloader.SessionMessage
+)
+
+// Candidate kinds for custom page extractors.
+const (
+	CandidateVideo    = downloader.CandidateVideo
+	CandidateCaptions = downloader.CandidateCaptions
+)
+
+// Errors returned by Client methods; test with errors.Is.
+var (
+	ErrNotFound        = downloader.ErrNotFound
+	ErrInvalidZip      = downloader.ErrInvalidZip
+	ErrAuthRequired    = downloader.ErrAuthRequired
+	ErrDirectoryExists = downloader.ErrDirectoryExists
+	ErrAPIUnavailable  = downloader.ErrAPIUnavailable
+	ErrLoginWall       = downloader.ErrLoginWall
+	ErrCorruptZip      = downloader.ErrCorruptZip
+	ErrExtractLimit    = downloader.ErrExtractLimit
+)
+
+// Error categories reported by CategoryOf and DownloadError.
+const (
+	CategoryUnknown          = downloader.CategoryUnknown
+	CategoryAuthExpired      = downloader.CategoryAuthExpired
+	CategoryLoginWall        = downloader.CategoryLoginWall
+	CategorySignedURLExpired = downloader.CategorySignedURLExpired
+	CategoryNotGenerated     = downloader.CategoryNotGenerated
+	CategoryRateLimited      = downloader.CategoryRateLimited
+	CategoryInvalidContent   = downloader.CategoryInvalidContent
+	CategoryNetwork          = downloader.CategoryNetwork
+	CategoryDiskFull         = downloader.CategoryDiskFull
+	CategoryFilesystem       = downloader.CategoryFilesystem
+	CategoryToolMissing      = downloader.CategoryToolMissing
+)
+
+// Client downloads and inspects recordings. It is safe for concurrent use.
+type Client struct {
+	client     HTTPClient
+	logger     Logger
+	pool       *Pool
+	embedder   SubtitleEmbedder
+	extractors *ExtractorRegistry
+	dl         *downloader.Downloader
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client. Defaults to http.DefaultClient.
+func WithHTTPClient(client HTTPClient) Option {
+	return func(c *Client) { c.client = client }
+}
+
+// WithLogger sets the structured logger (compatible with charmbracelet/log).
+func WithLogger(logger Logger) Option {
+	return func(c *Client) { c.logger = logger }
+}
+
+// WithPool shares a started download pool between recordings. The caller
+// owns the pool and must stop it when done.
+func WithPool(pool *Pool) Option {
+	return func(c *Client) { c.pool = pool }
+}
+
+// WithEmbedder embeds the cleaned captions, chat subtitles and chapters into
+// the MP4 after download.
+func WithEmbedder(embedder SubtitleEmbedder) Option {
+	return func(c *Client) { c.embedder = embedder }
+}
+
+// WithExtractors replaces the page extractors used to find video and caption URLs.
+func WithExtractors(extractors *ExtractorRegistry) Option {
+	return func(c *Client) { c.extractors = extractors }
+}
+
+// New creates a Client.
+func New(opts ...Option) *Client {
+	c := &Client{client: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.extractors == nil {
+		c.extractors = DefaultExtractors()
+	}
+	if c.pool != nil {
+		c.dl = downloader.NewWithPool(c.client, c.pool)
+	} else {
+		c.dl = downloader.New(c.client)
+	}
+	return c
+}
+
+// DownloadOptions control a single Download or Probe call.
+type DownloadOptions struct {
+	OutputDir  string           // Parent directory; the recording gets its own subdirectory
+	Session    string           // BREEZESESSION token; the URL's ?session= is used when empty
+	Overwrite  bool             // Replace an existing non-empty recording directory
+	OnProgress ProgressCallback // Called with MP4 byte progress
+	OnEvent    EventHandler     // Called for typed events; may run on several goroutines
+	ZipWait    time.Duration    // Wait for the server to prepare the raw ZIP (0 = DefaultZipWait, <0 = no wait)
+	// SelectiveZip fetches only the raw ZIP entries needed for captions, chat and
+	// documents with range requests instead of downloading raw.zip.
+	SelectiveZip bool
+	// StreamExtract extracts the raw ZIP while it downloads instead of after.
+	StreamExtract bool
+	ExtractLimits ExtractLimits // Zip bomb protection (zero value = DefaultExtractLimits)
+	// Retention decides which raw data is kept once captions, chat and documents
+	// are produced ("" = RetainAll). For Reprocess, "" keeps the recorded policy.
+	Retention Retention
+	// ChatFormats selects the chat outputs: chat_log.txt, chat.json, chat.csv,
+	// chat.md and chat.html (empty = ChatText only).
+	ChatFormats []ChatFormat
+	// WhiteboardSnapshots also renders each whiteboard page as it was at every
+	// page change, next to its final state.
+	WhiteboardSnapshots bool
+	// AttendanceAggregate writes only aggregate counts to attendance.json and
+	// attendance.csv, with no participant names or times.
+	AttendanceAggregate bool
+	// ChatCueDuration is how long each public chat message is shown in
+	// chat.vtt, which is embedded as a "Chat" subtitle track next to the
+	// captions (0 = DefaultChatCueDuration, <0 = no chat.vtt).
+	ChatCueDuration time.Duration
+	// ChapterRules select the events that start a chapter in chapters.txt and
+	// chapters.vtt (nil = document and layout changes, empty = no chapters).
+	ChapterRules []ChapterRule
+}
+
+// Download fetches a recording and its derived artifacts into opts.OutputDir.
+func (c *Client) Download(ctx context.Context, rawURL string, opts DownloadOptions) (Result, error) {
+	return c.dl.Download(ctx, rawURL, c.options(opts))
+}
+
+// Reprocess rebuilds the captions, transcript, chat, session timeline, other
+// raw-derived outputs and document list of a recording downloaded earlier
+// into dir, re-fetching raw data only if it was deleted by the retention
+// policy.
+func (c *Client) Reprocess(ctx context.Context, dir string, opts DownloadOptions) (Result, error) {
+	return c.dl.Reprocess(ctx, dir, c.options(opts))
+}
+
+// Probe reports what a recording offers without writing any files.
+func (c *Client) Probe(ctx context.Context, rawURL string, opts DownloadOptions) (ProbeResult, error) {
+	return c.dl.Probe(ctx, rawURL, c.options(opts))
+}
+
+// CheckSession reports whether session is valid on the target host and, when
+// target is a recording URL, whether that recording is accessible.
+func (c *Client) CheckSession(ctx context.Context, target, session string) (SessionStatus, error) {
+	return c.dl.CheckSession(ctx, target, session, c.logger)
+}
+
+// CheckRecording reports whether the recording URL target can be opened with
+// session, without checking the session itself.
+func (c *Client) CheckRecording(ctx context.Context, target, session string) (RecordingAccess, error) {
+	return c.dl.CheckRecording(ctx, target, session, c.logger)
+}
+
+// options converts per-call options to the internal downloader options.
+func (c *Client) options(opts DownloadOptions) downloader.Options {
+	return downloader.Options{
+		OutputDir:           opts.OutputDir,
+		Session:             opts.Session,
+		Log:                 c.logger,
+		OnProgress:          opts.OnProgress,
+		Overwrite:           opts.Overwrite,
+		MP4Box:              c.embedder,
+		Extractors:          c.extractors,
+		OnEvent:             opts.OnEvent,
+		ZipWait:             opts.ZipWait,
+		SelectiveZip:        opts.SelectiveZip,
+		StreamExtract:       opts.StreamExtract,
+		ExtractLimits:       opts.ExtractLimits,
+		Retention:           opts.Retention,
+		ChatFormats:         opts.ChatFormats,
+		WhiteboardSnapshots: opts.WhiteboardSnapshots,
+		AttendanceAggregate: opts.AttendanceAggregate,
+		ChatCueDuration:     opts.ChatCueDuration,
+		ChapterRules:        opts.ChapterRules,
+	}
+}
+
+// NewPool creates a download pool. Call Start before use and Stop when done.
+func NewPool(client HTTPClient, config PoolConfig) *Pool {
+	return downloader.NewDownloadPool(client, config)
+}
+
+// DefaultPoolConfig returns the pool defaults used by the CLI.
+func DefaultPoolConfig() PoolConfig {
+	return downloader.DefaultPoolConfig()
+}
+
+// ParseRetention validates a retention policy name such as "keep-zip".
+func ParseRetention(name string) (Retention, error) {
+	return downloader.ParseRetention(name)
+}
+
+// ParseChatFormats parses a comma-separated list of chat formats such as
+// "txt,json"; "all" selects every format.
+func ParseChatFormats(list string) ([]ChatFormat, error) {
+	return downloader.ParseChatFormats(list)
+}
+
+// ParseChapterRules parses a comma-separated list of chapter rules such as
+// "document,layout"; "all" selects every rule and "none" turns chapters off.
+func ParseChapterRules(list string) ([]ChapterRule, error) {
+	return downloader.ParseChapterRules(list)
+}
+
+// DefaultExtractLimits returns the extraction limits used when none are set.
+func DefaultExtractLimits() ExtractLimits {
+	return downloader.DefaultExtractLimits()
+}
+
+// DefaultExtractors returns a registry with the built-in page extractors.
+func DefaultExtractors() *ExtractorRegistry {
+	return downloader.DefaultExtractors()
+}
+
+// NewExtractorRegistry creates a registry with the given extractors.
+func NewExtractorRegistry(extractors ...PageExtractor) *ExtractorRegistry {
+	return downloader.NewExtractorRegistry(extractors...)
+}
+
+// NewPatternExtractor compiles a PatternRule into a PageExtractor.
+func NewPatternExtractor(rule PatternRule) (PageExtractor, error) {
+	return downloader.NewPatternExtractor(rule)
+}
+
+// LoadPatternRules reads a JSON array of PatternRule from path.
+func LoadPatternRules(path string) ([]PatternRule, error) {
+	return downloader.LoadPatternRules(path)
+}
+
+// ResolveSession returns session, or the ?session= query parameter of rawURL when empty.
+func ResolveSession(rawURL, session string) string {
+	return downloader.ResolveSession(rawURL, session)
+}
+
+// ParseTarget returns the origin (scheme://host) of a bare host, host URL or
+// recording URL, and the recording ID when the path names one.
+func ParseTarget(target string) (origin, recordingID string, err error) {
+	return downloader.ParseTarget(target)
+}
+
+// CategoryOf classifies an error returned by a Client method; nil yields "".
+func CategoryOf(err error) ErrorCategory {
+	return downloader.CategoryOf(err)
+}
+
+// Remediation returns advice on how to resolve a category of failure.
+func Remediation(c ErrorCategory) string {
+	return downloader.Remediation(c)
+}
+
+// RedactURL removes credential-bearing query values from a URL for logging.
+func RedactURL(rawURL string) string {
+	return downloader.RedactURL(rawURL)
+}
@@ -0,0 +1,124 @@
+package connectdl_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/keanucz/AdobeConnectDL/connectdl"
+)
+
+func TestClientDownloadEmitsEvents(t *testing.T) {
+	mp4Content := make([]byte, 2048)
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	w, _ := zw.Create("a.txt")
+	w.Write([]byte("hello"))
+	zw.Close()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rec/":
+			fmt.Fprintf(w, `<title>Library Test</title><script>var casRecordingURL = '%s/rec/output/rec.mp4';</script>`,
+				server.URL)
+		case "/rec/output/rec.mp4":
+			w.Write(mp4Content)
+		case "/rec/output/rec.zip":
+			w.Write(zipBuf.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	kinds := make(map[connectdl.EventKind]int)
+	var leaked []string
+	client := connectdl.New(connectdl.WithHTTPClient(server.Client()))
+	res, err := client.Download(context.Background(), server.URL+"/rec/?session=secret", connectdl.DownloadOptions{
+		OutputDir: t.TempDir(),
+		OnEvent: func(e connectdl.Event) {
+			mu.Lock()
+			kinds[e.Kind]++
+			if strings.Contains(e.URL, "secret") {
+				leaked = append(leaked, e.URL)
+			}
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatalf("Download error: %v", err)
+	}
+	if res.Title != "Library Test" || res.MP4Path == "" || res.ZipPath == "" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if kinds[connectdl.EventStarted] != 1 || kinds[connectdl.EventFinished] != 1 {
+		t.Errorf("expected one started and finished event, got %v", kinds)
+	}
+	if kinds[connectdl.EventAssetDone] != 2 {
+		t.Errorf("expected asset_done for zip and mp4, got %v", kinds)
+	}
+	if kinds[connectdl.EventProgress] == 0 {
+		t.Errorf("expected progress events, got %v", kinds)
+	}
+	if len(leaked) > 0 {
+		t.Errorf("session leaked into event URLs: %v", leaked)
+	}
+
+	_, err = client.Download(context.Background(), server.URL+"/rec/", connectdl.DownloadOptions{
+		OutputDir: filepath.Dir(res.RootDir),
+	})
+	if !errors.Is(err, connectdl.ErrDirectoryExists) {
+		t.Errorf("expected ErrDirectoryExists, got %v", err)
+	}
+}
+
+func TestParsers(t *testing.T) {
+	rawDir := filepath.Join("..", "internal", "downloader", "testdata", "lecture1")
+
+	mapping := connectdl.UserMapping(rawDir)
+	if len(mapping) == 0 {
+		t.Fatal("expected user mapping from indexstream.xml")
+	}
+	if name := connectdl.LecturerName(rawDir); name == "" {
+		t.Error("expected lecturer name")
+	}
+
+	out := t.TempDir()
+	share := `<root><Message><newValue><name><![CDATA[Slides.pdf]]></name><size><![CDATA[42]]></size>
+<playbackFileName><![CDATA[/system/download?download-url=/_a1/p1/output/&name=Slides.pdf]]></playbackFileName>
+</newValue></Message></root>`
+	if err := os.WriteFile(filepath.Join(out, "ftfileshare1.xml"), []byte(share), 0o644); err != nil {
+		t.Fatalf("write ftfileshare: %v", err)
+	}
+	docs := connectdl.DocumentLinks(out, "example.adobeconnect.com")
+	wantURL := "https://example.adobeconnect.com/_a1/p1/output/Slides.pdf?download=true"
+	if len(docs) != 1 || docs[0].DownloadURL != wantURL {
+		t.Errorf("unexpected documents: %+v", docs)
+	}
+
+	if err := connectdl.WriteChatLog(rawDir, filepath.Join(out, "chat.txt")); err != nil {
+		t.Errorf("WriteChatLog error: %v", err)
+	}
+	cleaned := filepath.Join(out, "captions.vtt")
+	if err := connectdl.CleanVTT(filepath.Join(rawDir, "raw_captions.vtt"), cleaned, "", mapping); err != nil {
+		t.Fatalf("CleanVTT error: %v", err)
+	}
+	transcript := filepath.Join(out, "transcript.txt")
+	if err := connectdl.WriteTranscript(cleaned, transcript); err != nil {
+		t.Fatalf("WriteTranscript error: %v", err)
+	}
+	if info, err := os.Stat(transcript); err != nil || info.Size() == 0 {
+		t.Errorf("expected non-empty transcript, err=%v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package fakeconnect
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+)
+
+// sessionMaxAge is the lifetime advertised on the BREEZESESSION cookie.
+const sessionMaxAge = 30 * 60
+
+// serveAPI implements the subset of the Connect XML API used by the downloader.
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.hits["/"+string(AssetAPI)]++
+	fault := s.takeFaultLocked("", AssetAPI)
+	user, loggedIn := s.sessionUserLocked(r)
+	s.mu.Unlock()
+	if fault != nil && fault.respond(w) {
+		return
+	}
+
+	q := r.URL.Query()
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	out := &strings.Builder{}
+	out.WriteString(`<?xml version="1.0" encoding="utf-8"?><results>`)
+
+	switch q.Get("action") {
+	case "common-info":
+		out.WriteString(`<status code="ok"/><common locale="en"><version>12.5.0</version>`)
+		fmt.Fprintf(out, `<host>%s</host>`, html.EscapeString(requestOrigin(r)))
+		if loggedIn {
+			c, _ := r.Cookie("BREEZESESSION")
+			http.SetCookie(w, &http.Cookie{Name: "BREEZESESSION", Value: c.Value, MaxAge: sessionMaxAge})
+			fmt.Fprintf(out, `<user user-id="%s" type="user"><name>%s</name><login>%s</login></user>`,
+				user.ID, html.EscapeString(user.Name), html.EscapeString(user.Login))
+		}
+		out.WriteString(`</common>`)
+	case "sco-by-url":
+		rec, status := s.accessibleRecording(loggedIn, user, strings.Trim(q.Get("url-path"), "/"), true)
+		if rec == nil {
+			out.WriteString(status)
+			break
+		}
+		out.WriteString(`<status code="ok"/>`)
+		writeSco(out, rec)
+	case "sco-info":
+		out.WriteString(s.scoInfo(loggedIn, user, q.Get("sco-id")))
+	case "sco-nav":
+		rec, status := s.accessibleRecording(loggedIn, user, q.Get("sco-id"), false)
+		if rec == nil {
+			out.WriteString(status)
+			break
+		}
+		out.WriteString(`<status code="ok"/><sco-nav>`)
+		fmt.Fprintf(out, `<sco sco-id="%s" depth="0"><name>%s</name></sco>`, rec.ScoID, html.EscapeString(rec.Title))
+		if rec.Meeting != "" {
+			fmt.Fprintf(out, `<sco sco-id="%s" depth="-1"><name>%s</name></sco>`,
+				folderID(rec), html.EscapeString(rec.Meeting))
+			out.WriteString(`<sco sco-id="10" depth="-2"><name>Shared Meetings</name></sco>`)
+		}
+		out.WriteString(`</sco-nav>`)
+	case "principal-info":
+		rec, status := s.accessibleRecording(loggedIn, user, strings.TrimPrefix(q.Get("principal-id"), "p"), false)
+		if rec == nil || rec.Creator == "" {
+			out.WriteString(status)
+			break
+		}
+		fmt.Fprintf(out, `<status code="ok"/><principal principal-id="p%s"><name>%s</name></principal>`,
+			rec.ScoID, html.EscapeString(rec.Creator))
+	default:
+		out.WriteString(`<status code="invalid" subcode="no-such-action"/>`)
+	}
+
+	out.WriteString(`</results>`)
+	fmt.Fprint(w, out.String())
+}
+
+// accessibleRecording finds a recording by URL path (byPath) or SCO ID and
+// applies the auth rules. On failure it returns the status element to send.
+func (s *Server) accessibleRecording(loggedIn bool, user User, key string, byPath bool) (*Recording, string) {
+	if !loggedIn {
+		return nil, `<status code="no-access" subcode="no-login"/>`
+	}
+	s.mu.Lock()
+	rec := s.scos[key]
+	if byPath {
+		rec = s.recordings[key]
+	}
+	s.mu.Unlock()
+	if rec == nil || !rec.allows(user) {
+		return nil, `<status code="no-access" subcode="denied"/>`
+	}
+	return rec, ""
+}
+
+// scoInfo answers sco-info for a recording or its meeting folder.
+func (s *Server) scoInfo(loggedIn bool, user User, scoID string) string {
+	s.mu.Lock()
+	var folderOf *Recording
+	for _, rec := range s.recordings {
+		if rec.Meeting != "" && folderID(rec) == scoID {
+			folderOf = rec
+		}
+	}
+	s.mu.Unlock()
+
+	if folderOf != nil && loggedIn {
+		return fmt.Sprintf(`<status code="ok"/><sco sco-id="%s" folder-id="10" type="meeting"><name>%s</name></sco>`,
+			scoID, html.EscapeString(folderOf.Meeting))
+	}
+	rec, status := s.accessibleRecording(loggedIn, user, scoID, false)
+	if rec == nil {
+		return status
+	}
+	var b strings.Builder
+	b.WriteString(`<status code="ok"/>`)
+	writeSco(&b, rec)
+	return b.String()
+}
+
+// writeSco writes the <sco> element for a recording.
+func writeSco(b *strings.Builder, rec *Recording) {
+	fmt.Fprintf(b, `<sco sco-id="%s" folder-id="%s" type="content" icon="archive">`, rec.ScoID, folderID(rec))
+	fmt.Fprintf(b, `<name>%s</name><url-path>/%s/</url-path>`, html.EscapeString(rec.Title), rec.ID)
+	if rec.Description != "" {
+		fmt.Fprintf(b, `<description>%s</description>`, html.EscapeString(rec.Description))
+	}
+	if rec.DateBegin != "" {
+		fmt.Fprintf(b, `<date-begin>%s</date-begin>`, rec.DateBegin)
+	}
+	if rec.DateEnd != "" {
+		fmt.Fprintf(b, `<date-end>%s</date-end>`, rec.DateEnd)
+	}
+	if rec.Creator != "" {
+		fmt.Fprintf(b, `<owner-principal-id>p%s</owner-principal-id>`, rec.ScoID)
+	}
+	b.WriteString(`</sco>`)
+}
+
+// folderID returns the SCO ID of the meeting that owns a recording.
+func folderID(rec *Recording) string {
+	return "9" + rec.ScoID
+}
+
+// allows reports whether user may open the recording.
+func (rec *Recording) allows(user User) bool {
+	if len(rec.AllowedLogins) == 0 {
+		return true
+	}
+	for _, login := range rec.AllowedLogins {
+		if strings.EqualFold(login, user.Login) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,361 @@
+// Package fakeconnect is an in-memory Adobe Connect server for tests and
+// offline debugging.
+//
+// It serves the parts of Connect the downloader talks to: the recording page
+// with the CAS player JavaScript, the raw recording ZIP (with Range support),
+// a signed MP4 URL reached through a redirect, WebVTT captions, shared
+// documents behind /system/download, and the XML API (common-info, sco-by-url,
+// sco-info, sco-nav, principal-info). Recordings can be private, sessions can expire,
+// and faults can be injected per asset.
+//
+//	srv := fakeconnect.New(fakeconnect.SampleRecording("p1abc"))
+//	ts := httptest.NewServer(srv)
+//	defer ts.Close()
+package fakeconnect
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Recording is a recording served by the fake server.
+type Recording struct {
+	ID            string            // URL path segment, e.g. "p1abc2def3"
+	ScoID         string            // Defaults to a number derived from the registration order
+	Title         string            // Page <title> and SCO name
+	Description   string            // SCO description
+	DateBegin     string            // Connect timestamp, e.g. "2025-03-05T10:00:00.000+00:00"
+	DateEnd       string            // Connect timestamp
+	Meeting       string            // Name of the meeting that owns the recording
+	Creator       string            // Name of the recording owner
+	Private       bool              // Page and assets require a valid session
+	AllowedLogins []string          // Logins allowed to open the recording; empty allows any session
+	MP4           []byte            // MP4 rendition behind the signed CAS URL; nil means none
+	Captions      string            // WebVTT served at output/<ID>.vtt; empty means none
+	CaptionsInZip bool              // Also place the captions in the raw ZIP
+	Files         map[string]string // Raw ZIP entries (indexstream.xml, ...); nil means no ZIP
+	NoRanges      bool              // Ignore Range requests for the raw ZIP
+	Documents     map[string][]byte // Shared documents served at /_a1/<ID>/output/<name>
+}
+
+// User is the principal a session token belongs to.
+type User struct {
+	ID    string
+	Name  string
+	Login string
+}
+
+// Server is an http.Handler that emulates an Adobe Connect server.
+// It is safe for concurrent use.
+type Server struct {
+	mu         sync.Mutex
+	recordings map[string]*Recording
+	scos       map[string]*Recording
+	sessions   map[string]User
+	expired    map[string]bool
+	faults     map[faultKey]*faultState
+	signed     map[string]string // Signed MP4 token -> recording ID
+	usedTokens map[string]bool
+	nextToken  int
+	hits       map[string]int
+}
+
+// New creates a server with the given recordings.
+func New(recordings ...Recording) *Server {
+	s := &Server{
+		recordings: make(map[string]*Recording),
+		scos:       make(map[string]*Recording),
+		sessions:   make(map[string]User),
+		expired:    make(map[string]bool),
+		faults:     make(map[faultKey]*faultState),
+		signed:     make(map[string]string),
+		usedTokens: make(map[string]bool),
+		hits:       make(map[string]int),
+	}
+	for _, rec := range recordings {
+		s.AddRecording(rec)
+	}
+	return s
+}
+
+// AddRecording registers a recording, replacing any with the same ID.
+func (s *Server) AddRecording(rec Recording) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rec.ScoID == "" {
+		rec.ScoID = strconv.Itoa(1001 + len(s.recordings))
+	}
+	if rec.Title == "" {
+		rec.Title = rec.ID
+	}
+	s.recordings[rec.ID] = &rec
+	s.scos[rec.ScoID] = &rec
+}
+
+// AddSession makes token a valid BREEZESESSION for user.
+func (s *Server) AddSession(token string, user User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if user.ID == "" {
+		user.ID = strconv.Itoa(42 + len(s.sessions))
+	}
+	s.sessions[token] = user
+	delete(s.expired, token)
+}
+
+// ExpireSession invalidates token; the API then reports it as logged out.
+func (s *Server) ExpireSession(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expired[token] = true
+}
+
+// Hits returns how many requests were made for an asset of a recording,
+// including requests answered by an injected fault.
+func (s *Server) Hits(recordingID string, asset Asset) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[recordingID+"/"+string(asset)]
+}
+
+// ServeHTTP routes a request to the page, asset or API handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(p, "/")
+
+	switch {
+	case p == "api/xml":
+		s.serveAPI(w, r)
+	case p == "system/download":
+		s.serveSystemDownload(w, r)
+	case p == "system/login":
+		serveLoginPage(w)
+	case len(parts) == 2 && parts[0] == "cas":
+		s.serveSignedMP4(w, r, parts[1])
+	case len(parts) == 4 && parts[0] == "_a1" && parts[2] == "output":
+		s.serveDocument(w, r, parts[1], parts[3])
+	case len(parts) == 3 && parts[1] == "output":
+		s.serveOutput(w, r, parts[0], parts[2])
+	case len(parts) == 1 && p != "":
+		s.servePage(w, r, parts[0])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// lookup returns the recording and the writer to answer with, recording the
+// hit. It writes the response itself and returns a nil recording when the
+// recording is missing, the session is not allowed, or a status fault was
+// injected. Body faults are applied through the returned writer.
+func (s *Server) lookup(
+	w http.ResponseWriter,
+	r *http.Request,
+	id string,
+	asset Asset,
+) (*Recording, http.ResponseWriter) {
+	s.mu.Lock()
+	rec := s.recordings[id]
+	s.hits[id+"/"+string(asset)]++
+	// Signed MP4 URLs carry their own authorisation
+	allowed := rec != nil && (!rec.Private || asset == AssetSignedMP4 || s.sessionAllowedLocked(r, rec))
+	fault := s.takeFaultLocked(id, asset)
+	s.mu.Unlock()
+
+	switch {
+	case rec == nil:
+		http.NotFound(w, r)
+		return nil, w
+	case fault != nil && fault.respond(w):
+		return nil, w
+	case !allowed:
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, w
+	}
+	if fault != nil {
+		return rec, fault.wrap(w, r)
+	}
+	return rec, w
+}
+
+// sessionAllowedLocked reports whether the request's session may open rec.
+func (s *Server) sessionAllowedLocked(r *http.Request, rec *Recording) bool {
+	user, ok := s.sessionUserLocked(r)
+	return ok && rec.allows(user)
+}
+
+// sessionUserLocked returns the user of the request's session cookie, if valid.
+func (s *Server) sessionUserLocked(r *http.Request) (User, bool) {
+	c, err := r.Cookie("BREEZESESSION")
+	if err != nil || s.expired[c.Value] {
+		return User{}, false
+	}
+	u, ok := s.sessions[c.Value]
+	return u, ok
+}
+
+// servePage writes the HTML5 player page with the CAS JavaScript variables.
+func (s *Server) servePage(w http.ResponseWriter, r *http.Request, id string) {
+	rec, w := s.lookup(w, r, id, AssetPage)
+	if rec == nil {
+		return
+	}
+	origin := requestOrigin(r)
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!DOCTYPE html>\n<html><head><title>%s</title></head>\n<body>\n", html.EscapeString(rec.Title))
+	b.WriteString("<div id=\"player\"></div>\n<script>\n")
+	if rec.MP4 != nil {
+		fmt.Fprintf(&b, "var casRecordingURL = '%s/%s/output/%s.mp4?download=mp4';\n", origin, rec.ID, rec.ID)
+	}
+	if rec.Captions != "" {
+		fmt.Fprintf(&b, "var transcriptFilename = '%s.vtt';\n", rec.ID)
+	}
+	b.WriteString("</script>\n</body></html>\n")
+	writeBody(w, r, "text/html; charset=utf-8", []byte(b.String()))
+}
+
+// serveOutput serves the ZIP, the MP4 redirect and the captions under /<id>/output/.
+func (s *Server) serveOutput(w http.ResponseWriter, r *http.Request, id, name string) {
+	switch path.Ext(name) {
+	case ".zip":
+		rec, w := s.lookup(w, r, id, AssetZip)
+		if rec == nil {
+			return
+		}
+		if rec.Files == nil || r.URL.Query().Get("download") != "zip" {
+			http.NotFound(w, r)
+			return
+		}
+		if rec.NoRanges {
+			writeBody(w, r, "application/zip", buildZip(rec))
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(buildZip(rec)))
+	case ".mp4":
+		rec, w := s.lookup(w, r, id, AssetMP4)
+		if rec == nil {
+			return
+		}
+		if rec.MP4 == nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.mu.Lock()
+		s.nextToken++
+		token := fmt.Sprintf("sig%d", s.nextToken)
+		s.signed[token] = id
+		s.mu.Unlock()
+		http.Redirect(w, r, "/cas/"+id+".mp4?token="+token, http.StatusFound)
+	case ".vtt":
+		rec, w := s.lookup(w, r, id, AssetCaptions)
+		if rec == nil {
+			return
+		}
+		if rec.Captions == "" {
+			http.NotFound(w, r)
+			return
+		}
+		writeBody(w, r, "text/vtt", []byte(rec.Captions))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveSignedMP4 serves the MP4 for a signed token. Tokens are single use, so
+// a second GET returns 403 like an expired CAS signature.
+func (s *Server) serveSignedMP4(w http.ResponseWriter, r *http.Request, name string) {
+	token := r.URL.Query().Get("token")
+	s.mu.Lock()
+	id, ok := s.signed[token]
+	used := s.usedTokens[token]
+	if ok && r.Method == http.MethodGet {
+		s.usedTokens[token] = true
+	}
+	s.mu.Unlock()
+
+	if !ok || name != id+".mp4" {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if used {
+		http.Error(w, "Signature expired", http.StatusForbidden)
+		return
+	}
+	rec, w := s.lookup(w, r, id, AssetSignedMP4)
+	if rec == nil {
+		return
+	}
+	writeBody(w, r, "video/mp4", rec.MP4)
+}
+
+// serveSystemDownload emulates /system/download?download-url=/_a1/<id>/output/&name=<file>.
+func (s *Server) serveSystemDownload(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	parts := strings.Split(strings.Trim(q.Get("download-url"), "/"), "/")
+	if len(parts) != 3 || parts[0] != "_a1" || parts[2] != "output" {
+		http.NotFound(w, r)
+		return
+	}
+	s.serveDocument(w, r, parts[1], q.Get("name"))
+}
+
+// serveDocument serves a shared document.
+func (s *Server) serveDocument(w http.ResponseWriter, r *http.Request, id, name string) {
+	rec, w := s.lookup(w, r, id, AssetDocument)
+	if rec == nil {
+		return
+	}
+	data, ok := rec.Documents[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeBody(w, r, "application/octet-stream", data)
+}
+
+// buildZip builds the raw recording ZIP in memory.
+func buildZip(rec *Recording) []byte {
+	names := make([]string, 0, len(rec.Files))
+	for name := range rec.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		fw, _ := zw.Create(name)
+		fw.Write([]byte(rec.Files[name]))
+	}
+	if rec.CaptionsInZip && rec.Captions != "" {
+		fw, _ := zw.Create(rec.ID + ".vtt")
+		fw.Write([]byte(rec.Captions))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+// requestOrigin returns the scheme and host the client used.
+func requestOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return (&url.URL{Scheme: scheme, Host: r.Host}).String()
+}
+
+// DocumentPath returns the /system/download path Connect records in
+// ftfileshare XML for a document shared in recording id.
+func DocumentPath(id, name string) string {
+	return fmt.Sprintf("/system/download?download-url=/_a1/%s/output/&name=%s", id, url.QueryEscape(name))
+}
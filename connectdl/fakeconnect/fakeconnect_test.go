@@ -0,0 +1,555 @@
+package fakeconnect_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/keanucz/AdobeConnectDL/connectdl"
+	"github.com/keanucz/AdobeConnectDL/connectdl/fakeconnect"
+)
+
+func newClient(ts *httptest.Server) *connectdl.Client {
+	return connectdl.New(connectdl.WithHTTPClient(ts.Client()))
+}
+
+func TestSampleRecordingFullDownload(t *testing.T) {
+	srv := fakeconnect.New(fakeconnect.SampleRecording("p1abc"))
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	res, err := newClient(ts).Download(context.Background(), ts.URL+"/p1abc/", connectdl.DownloadOptions{
+		OutputDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("Download error: %v", err)
+	}
+	if len(res.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", res.Warnings)
+	}
+	if res.Lecturer != "Jane Doe" || res.Participants != 2 {
+		t.Errorf("lecturer = %q, participants = %d", res.Lecturer, res.Participants)
+	}
+
+	for _, name := range []string{
+		"recording.mp4", "raw.zip", "captions.vtt", "transcript.txt", "chat_log.txt", "session.json",
+		"qa.json", "polls.md", "notes.md", "links.html", "documents.txt", "slides.csv",
+		"chapters.vtt", "chat.vtt",
+		filepath.Join("documents", "Week 1 Slides.pdf"), filepath.Join("whiteboards", "ftwhiteboard6-page1.svg"),
+		"metadata.json",
+	} {
+		if _, err := os.Stat(filepath.Join(res.RootDir, name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+	captions, _ := os.ReadFile(filepath.Join(res.RootDir, "captions.vtt"))
+	if !strings.Contains(string(captions), "James Lewis") {
+		t.Errorf("expected speaker names in captions, got:\n%s", captions)
+	}
+	polls, _ := os.ReadFile(filepath.Join(res.RootDir, "polls.md"))
+	if !strings.Contains(string(polls), "- James Lewis: Yes") {
+		t.Errorf("expected named poll responses, got:\n%s", polls)
+	}
+	attendance, _ := os.ReadFile(filepath.Join(res.RootDir, "attendance.csv"))
+	if !strings.Contains(string(attendance), "James Lewis,participant,00:00:10,") {
+		t.Errorf("unexpected attendance.csv:\n%s", attendance)
+	}
+	slides, _ := os.ReadFile(filepath.Join(res.RootDir, "slides.csv"))
+	if !strings.Contains(string(slides), "300000,00:05:00,,,ftcontent7,Week 1 Slides.pdf,2,documents/Week 1 Slides.pdf") {
+		t.Errorf("unexpected slides.csv:\n%s", slides)
+	}
+	chapters, _ := os.ReadFile(filepath.Join(res.RootDir, "chapters.txt"))
+	if !strings.Contains(string(chapters), "00:00:00.000 Start\n00:01:00.000 Week 1 Slides\n") {
+		t.Errorf("unexpected chapters.txt:\n%s", chapters)
+	}
+	voice := connectdl.MediaStream{
+		Name: "cameraVoip_1_3.flv", Type: "cameraVoip", Owner: "Jane Doe", Offset: 12000, Time: "00:00:12",
+		Duration: 1978, AudioCodec: "aac", SampleRate: 44100, Channels: 1, Bitrate: 2814, Size: 2033,
+	}
+	if !reflect.DeepEqual(res.Streams, []connectdl.MediaStream{voice}) {
+		t.Errorf("streams = %+v, want %+v", res.Streams, voice)
+	}
+	notes, _ := os.ReadFile(filepath.Join(res.RootDir, "notes.md"))
+	for _, s := range []string{"**Agenda**\n\n- Intro\n- Examples", "[Course page](https://example.edu/se101)"} {
+		if !strings.Contains(string(notes), s) {
+			t.Errorf("notes.md missing %q:\n%s", s, notes)
+		}
+	}
+	qa, _ := os.ReadFile(filepath.Join(res.RootDir, "qa.md"))
+	for _, s := range []string{"Asked by James Lewis", "**Jane Doe** at 00:00:45", "chapters 1 & 2"} {
+		if !strings.Contains(string(qa), s) {
+			t.Errorf("qa.md missing %q:\n%s", s, qa)
+		}
+	}
+}
+
+func TestSelectiveZipDownload(t *testing.T) {
+	srv := fakeconnect.New(fakeconnect.SampleRecording("p1sel"))
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	res, err := newClient(ts).Download(context.Background(), ts.URL+"/p1sel/", connectdl.DownloadOptions{
+		OutputDir:    t.TempDir(),
+		SelectiveZip: true,
+	})
+	if err != nil {
+		t.Fatalf("Download error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(res.RootDir, "raw.zip")); err == nil {
+		t.Error("raw.zip should not be written")
+	}
+	for _, name := range []string{connectdl.AssetChat, connectdl.AssetDocument, connectdl.AssetCaptions} {
+		if a, _ := res.Asset(name); a.State != connectdl.StateOK {
+			t.Errorf("%s: got %+v", name, a)
+		}
+	}
+	if res.Lecturer != "Jane Doe" {
+		t.Errorf("lecturer = %q", res.Lecturer)
+	}
+}
+
+func TestStreamExtractDownload(t *testing.T) {
+	srv := fakeconnect.New(fakeconnect.SampleRecording("p1str"))
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	res, err := newClient(ts).Download(context.Background(), ts.URL+"/p1str/", connectdl.DownloadOptions{
+		OutputDir:     t.TempDir(),
+		StreamExtract: true,
+	})
+	if err != nil {
+		t.Fatalf("Download error: %v", err)
+	}
+	for _, name := range []string{connectdl.AssetZip, connectdl.AssetExtraction, connectdl.AssetChat} {
+		if a, _ := res.Asset(name); a.State != connectdl.StateOK {
+			t.Errorf("%s: got %+v", name, a)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(res.RootDir, "raw", "indexstream.xml")); err != nil {
+		t.Errorf("expected extracted indexstream.xml: %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(res.RootDir)); len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestRetentionAndReprocess(t *testing.T) {
+	srv := fakeconnect.New(fakeconnect.SampleRecording("p1ret"))
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	client := newClient(ts)
+
+	for _, tt := range []struct {
+		policy  connectdl.Retention
+		refetch bool
+	}{
+		{connectdl.RetainXMLOnly, false},
+		{connectdl.RetainZip, false},
+		{connectdl.RetainNone, true},
+	} {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			res, err := client.Download(context.Background(), ts.URL+"/p1ret/", connectdl.DownloadOptions{
+				OutputDir: t.TempDir(),
+				Retention: tt.policy,
+			})
+			if err != nil {
+				t.Fatalf("Download error: %v", err)
+			}
+			if res.Retention != tt.policy {
+				t.Errorf("retention = %q", res.Retention)
+			}
+			chatLog := filepath.Join(res.RootDir, "chat_log.txt")
+			if err := os.Remove(chatLog); err != nil {
+				t.Fatalf("expected chat log: %v", err)
+			}
+
+			hits := srv.Hits("p1ret", fakeconnect.AssetZip)
+			again, err := client.Reprocess(context.Background(), res.RootDir, connectdl.DownloadOptions{})
+			if err != nil {
+				t.Fatalf("Reprocess error: %v", err)
+			}
+			if refetched := srv.Hits("p1ret", fakeconnect.AssetZip) > hits; refetched != tt.refetch {
+				t.Errorf("raw data re-fetched = %v, want %v", refetched, tt.refetch)
+			}
+			if _, err := os.Stat(chatLog); err != nil {
+				t.Errorf("chat log not rebuilt: %v", err)
+			}
+			if again.Retention != tt.policy || again.Lecturer != "Jane Doe" {
+				t.Errorf("unexpected reprocess result: %+v", again)
+			}
+
+			data, err := os.ReadFile(filepath.Join(res.RootDir, "metadata.json"))
+			if err != nil {
+				t.Fatalf("read metadata: %v", err)
+			}
+			var meta struct {
+				Retention     connectdl.Retention `json:"retention"`
+				ReprocessedAt string              `json:"reprocessed_at"`
+			}
+			if err := json.Unmarshal(data, &meta); err != nil {
+				t.Fatalf("parse metadata: %v", err)
+			}
+			if meta.Retention != tt.policy || meta.ReprocessedAt == "" {
+				t.Errorf("unexpected metadata: %s", data)
+			}
+		})
+	}
+}
+
+// countingEmbedder counts the tracks it was asked to embed and the passes it
+// took.
+type countingEmbedder struct{ tracks, passes int }
+
+func (e *countingEmbedder) EmbedSubtitles(context.Context, string, string, string, io.Writer, io.Writer) error {
+	e.tracks++
+	e.passes++
+	return nil
+}
+
+func (e *countingEmbedder) EmbedTracks(
+	_ context.Context,
+	_ string,
+	subtitles []connectdl.SubtitleTrack,
+	chaptersPath string,
+	_, _ io.Writer,
+) error {
+	e.tracks += len(subtitles)
+	if chaptersPath != "" {
+		e.tracks++
+	}
+	e.passes++
+	return nil
+}
+
+func TestReprocessEmbedsOnlyIntoRebuiltMP4(t *testing.T) {
+	srv := fakeconnect.New(fakeconnect.SampleRecording("p1emb"))
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	embedder := &countingEmbedder{}
+	client := connectdl.New(connectdl.WithHTTPClient(ts.Client()), connectdl.WithEmbedder(embedder))
+
+	res, err := client.Download(context.Background(), ts.URL+"/p1emb/", connectdl.DownloadOptions{
+		OutputDir: t.TempDir(),
+		Retention: connectdl.RetainAll,
+	})
+	if err != nil {
+		t.Fatalf("Download error: %v", err)
+	}
+	embedded := embedder.tracks
+	if embedded == 0 {
+		t.Fatal("expected the download to embed tracks")
+	}
+	if embedder.passes != 1 {
+		t.Errorf("download embedded in %d passes, want 1", embedder.passes)
+	}
+
+	again, err := client.Reprocess(context.Background(), res.RootDir, connectdl.DownloadOptions{})
+	if err != nil {
+		t.Fatalf("Reprocess error: %v", err)
+	}
+	if embedder.tracks != embedded {
+		t.Errorf("reprocess embedded %d more tracks into the existing MP4", embedder.tracks-embedded)
+	}
+	for _, name := range []string{connectdl.AssetSubtitles, connectdl.AssetChatTrack, connectdl.AssetMarkers} {
+		if a, _ := again.Asset(name); a.State != connectdl.StateSkipped || !strings.Contains(a.Error, "duplicate") {
+			t.Errorf("%s: got %+v, want skipped to avoid duplicate tracks", name, a)
+		}
+	}
+
+	if err := os.Remove(filepath.Join(res.RootDir, "recording.mp4")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Reprocess(context.Background(), res.RootDir, connectdl.DownloadOptions{}); err != nil {
+		t.Fatalf("Reprocess error: %v", err)
+	}
+	if embedder.tracks != 2*embedded || embedder.passes != 2 {
+		t.Errorf("rebuilt MP4 got %d tracks in %d passes, want %d in 1",
+			embedder.tracks-embedded, embedder.passes-1, embedded)
+	}
+}
+
+func TestReprocessRebuildsMP4FromPrunedStreams(t *testing.T) {
+	srv := fakeconnect.New(fakeconnect.SampleRecording("p1prn"))
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	client := newClient(ts)
+
+	for _, policy := range []connectdl.Retention{connectdl.RetainXMLOnly, connectdl.RetainNone} {
+		t.Run(string(policy), func(t *testing.T) {
+			res, err := client.Download(context.Background(), ts.URL+"/p1prn/", connectdl.DownloadOptions{
+				OutputDir: t.TempDir(),
+				Retention: policy,
+			})
+			if err != nil {
+				t.Fatalf("Download error: %v", err)
+			}
+			mp4Path := filepath.Join(res.RootDir, "recording.mp4")
+			if err := os.Remove(mp4Path); err != nil {
+				t.Fatal(err)
+			}
+
+			again, err := client.Reprocess(context.Background(), res.RootDir, connectdl.DownloadOptions{})
+			if err != nil {
+				t.Fatalf("Reprocess error: %v", err)
+			}
+			if a, _ := again.Asset(connectdl.AssetRemux); a.State != connectdl.StateOK {
+				t.Errorf("remux = %+v, want recording.mp4 rebuilt from the re-fetched streams", a)
+			}
+			if _, err := os.Stat(mp4Path); err != nil {
+				t.Errorf("recording.mp4 not rebuilt: %v", err)
+			}
+			if flvs, _ := filepath.Glob(filepath.Join(res.RootDir, "raw", "*.flv")); len(flvs) != 0 {
+				t.Errorf("retention policy not applied again, FLV streams left: %v", flvs)
+			}
+		})
+	}
+}
+
+func TestReprocessDoesNotRefetchUnremuxableStreams(t *testing.T) {
+	rec := fakeconnect.SampleRecording("p1nm")
+	rec.MP4 = nil
+	// Nellymoser voice, which the remuxer can't copy into an MP4
+	voice := []byte("FLV\x01\x04\x00\x00\x00\x09\x00\x00\x00\x00")
+	for i := range 10 {
+		ts := i * 64
+		voice = append(voice, 8, 0, 0, 5, byte(ts>>16), byte(ts>>8), byte(ts), 0, 0, 0, 0, 0x62, 1, 2, 3, 4, 0, 0, 0, 16)
+	}
+	rec.Files["cameraVoip_1_3.flv"] = string(voice)
+	srv := fakeconnect.New(rec)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	client := newClient(ts)
+
+	res, err := client.Download(context.Background(), ts.URL+"/p1nm/", connectdl.DownloadOptions{
+		OutputDir: t.TempDir(),
+		Retention: connectdl.RetainXMLOnly,
+	})
+	if err != nil {
+		t.Fatalf("Download error: %v", err)
+	}
+	if a, _ := res.Asset(connectdl.AssetRemux); a.State == connectdl.StateOK {
+		t.Fatalf("remux = %+v, want no MP4 from Nellymoser voice", a)
+	}
+
+	hits := srv.Hits("p1nm", fakeconnect.AssetZip)
+	if _, err := client.Reprocess(context.Background(), res.RootDir, connectdl.DownloadOptions{}); err != nil {
+		t.Fatalf("Reprocess error: %v", err)
+	}
+	if got := srv.Hits("p1nm", fakeconnect.AssetZip); got != hits {
+		t.Errorf("Reprocess fetched the raw zip %d times, want no fetch", got-hits)
+	}
+}
+
+func TestAssetStatuses(t *testing.T) {
+	srv := fakeconnect.New(fakeconnect.SampleRecording("p1st"))
+	srv.InjectFault("p1st", fakeconnect.AssetDocument, fakeconnect.Fault{Kind: fakeconnect.FaultForbidden})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	res, err := newClient(ts).Download(context.Background(), ts.URL+"/p1st/", connectdl.DownloadOptions{
+		OutputDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("Download error: %v", err)
+	}
+
+	want := map[string]connectdl.AssetState{
+		connectdl.AssetPage:       connectdl.StateOK,
+		connectdl.AssetZip:        connectdl.StateOK,
+		connectdl.AssetExtraction: connectdl.StateOK,
+		connectdl.AssetMP4:        connectdl.StateOK,
+		connectdl.AssetCaptions:   connectdl.StateOK,
+		connectdl.AssetTranscript: connectdl.StateOK,
+		connectdl.AssetChat:       connectdl.StateOK,
+		connectdl.AssetChatVTT:    connectdl.StateOK, // Recorded without an embedder too
+		connectdl.AssetSession:    connectdl.StateOK,
+		connectdl.AssetQA:         connectdl.StateOK,
+		connectdl.AssetPolls:      connectdl.StateOK,
+		connectdl.AssetSubtitles:  connectdl.StateSkipped, // No embedder configured
+		connectdl.AssetDocument:   connectdl.StateFailed,
+	}
+	for name, state := range want {
+		a, ok := res.Asset(name)
+		if !ok || a.State != state {
+			t.Errorf("%s: got %+v, want state %s", name, a, state)
+		}
+	}
+	if mp4, _ := res.Asset(connectdl.AssetMP4); mp4.Path != "recording.mp4" || mp4.Size == 0 {
+		t.Errorf("unexpected mp4 status: %+v", mp4)
+	}
+	if doc, _ := res.Asset(connectdl.AssetDocument); doc.Document != "Week 1 Slides.pdf" ||
+		doc.Category != connectdl.CategoryAuthExpired {
+		t.Errorf("unexpected document status: %+v", doc)
+	}
+	if !slices.Contains(res.Warnings, "1 of 1 documents could not be downloaded") {
+		t.Errorf("expected document warning, got %v", res.Warnings)
+	}
+
+	data, err := os.ReadFile(filepath.Join(res.RootDir, "metadata.json"))
+	if err != nil {
+		t.Fatalf("read metadata: %v", err)
+	}
+	var meta struct {
+		Assets []connectdl.AssetStatus `json:"assets"`
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatalf("parse metadata: %v", err)
+	}
+	if len(meta.Assets) != len(res.Assets) || meta.Assets[0].Name != connectdl.AssetPage {
+		t.Errorf("metadata assets = %+v", meta.Assets)
+	}
+}
+
+func TestPrivateRecordingRequiresSession(t *testing.T) {
+	rec := fakeconnect.SampleRecording("p1priv")
+	rec.Private = true
+	srv := fakeconnect.New(rec)
+	srv.AddSession("good", fakeconnect.User{Name: "Jane Doe", Login: "jane@example.com"})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	client := newClient(ts)
+
+	_, err := client.Download(context.Background(), ts.URL+"/p1priv/", connectdl.DownloadOptions{
+		OutputDir: t.TempDir(),
+	})
+	if !errors.Is(err, connectdl.ErrAuthRequired) {
+		t.Fatalf("expected ErrAuthRequired without session, got %v", err)
+	}
+
+	res, err := client.Download(context.Background(), ts.URL+"/p1priv/?session=good", connectdl.DownloadOptions{
+		OutputDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("Download with session error: %v", err)
+	}
+	if res.Details == nil || res.Details.MeetingName != "SE101 Lectures" || res.Details.Creator != "Jane Doe" {
+		t.Errorf("unexpected details: %+v", res.Details)
+	}
+
+	srv.ExpireSession("good")
+	status, err := client.CheckSession(context.Background(), ts.URL+"/p1priv/", "good")
+	if err != nil {
+		t.Fatalf("CheckSession error: %v", err)
+	}
+	if status.LoggedIn {
+		t.Error("expected expired session to be logged out")
+	}
+}
+
+func TestAllowedLogins(t *testing.T) {
+	rec := fakeconnect.SampleRecording("p1staff")
+	rec.AllowedLogins = []string{"staff@example.com"}
+	srv := fakeconnect.New(rec)
+	srv.AddSession("student", fakeconnect.User{Name: "Sam", Login: "sam@example.com"})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	status, err := newClient(ts).CheckSession(context.Background(), ts.URL+"/p1staff/", "student")
+	if err != nil {
+		t.Fatalf("CheckSession error: %v", err)
+	}
+	if !status.LoggedIn || status.Recording == nil || status.Recording.Accessible {
+		t.Fatalf("expected logged-in session without access, got %+v", status)
+	}
+}
+
+func TestSignedURLIsSingleUse(t *testing.T) {
+	srv := fakeconnect.New(fakeconnect.SampleRecording("p1sig"))
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	client := ts.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(ts.URL + "/p1sig/output/p1sig.mp4?download=mp4")
+	if err != nil {
+		t.Fatalf("GET error: %v", err)
+	}
+	resp.Body.Close()
+	signed := resp.Header.Get("Location")
+	if resp.StatusCode != http.StatusFound || signed == "" {
+		t.Fatalf("expected redirect to signed URL, got %d", resp.StatusCode)
+	}
+
+	for i, want := range []int{http.StatusOK, http.StatusForbidden} {
+		resp, err := client.Get(ts.URL + signed)
+		if err != nil {
+			t.Fatalf("GET signed error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("request %d: status %d, want %d", i+1, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestFaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		asset   fakeconnect.Asset
+		fault   fakeconnect.Fault
+		warning string
+	}{
+		{"truncated mp4", fakeconnect.AssetSignedMP4, fakeconnect.Fault{Kind: fakeconnect.FaultTruncate},
+			"MP4 rendition not available"},
+		{"rate limited zip", fakeconnect.AssetZip, fakeconnect.Fault{Kind: fakeconnect.FaultRateLimit}, ""},
+		{"login page for zip", fakeconnect.AssetZip, fakeconnect.Fault{Kind: fakeconnect.FaultLoginPage},
+			"Raw recording ZIP failed: zip: redirected to the login page"},
+		{"zip not generated", fakeconnect.AssetZip, fakeconnect.Fault{Kind: fakeconnect.FaultNotFound},
+			"Raw recording ZIP not available"},
+		{"slow mp4", fakeconnect.AssetSignedMP4, fakeconnect.Fault{Kind: fakeconnect.FaultSlow, Delay: 1}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fakeconnect.New(fakeconnect.SampleRecording("p1f"))
+			srv.InjectFault("p1f", tt.asset, tt.fault)
+			ts := httptest.NewServer(srv)
+			defer ts.Close()
+
+			res, err := newClient(ts).Download(context.Background(), ts.URL+"/p1f/", connectdl.DownloadOptions{
+				OutputDir: t.TempDir(),
+			})
+			if err != nil {
+				t.Fatalf("Download error: %v", err)
+			}
+			hasWarning := slices.ContainsFunc(res.Warnings, func(w string) bool {
+				return strings.HasPrefix(w, tt.warning)
+			})
+			if tt.warning != "" && !hasWarning {
+				t.Errorf("expected warning %q, got %v", tt.warning, res.Warnings)
+			}
+			if tt.fault.Kind == fakeconnect.FaultSlow && res.MP4Path == "" {
+				t.Errorf("slow MP4 should still download, warnings: %v", res.Warnings)
+			}
+			if srv.Hits("p1f", tt.asset) == 0 {
+				t.Errorf("expected %s to be requested", tt.asset)
+			}
+		})
+	}
+}
+
+func TestFaultTimes(t *testing.T) {
+	srv := fakeconnect.New(fakeconnect.SampleRecording("p1t"))
+	srv.InjectFault("p1t", fakeconnect.AssetPage, fakeconnect.Fault{Kind: fakeconnect.FaultServer, Times: 1})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	for i, want := range []int{http.StatusInternalServerError, http.StatusOK} {
+		resp, err := ts.Client().Get(ts.URL + "/p1t/")
+		if err != nil {
+			t.Fatalf("GET error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("request %d: status %d, want %d", i+1, resp.StatusCode, want)
+		}
+	}
+}
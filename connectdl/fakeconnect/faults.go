@@ -0,0 +1,237 @@
+package fakeconnect
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Asset identifies a resource of a recording that faults can target.
+type Asset string
+
+const (
+	AssetPage      Asset = "page"       // Recording page
+	AssetZip       Asset = "zip"        // Raw recording ZIP
+	AssetMP4       Asset = "mp4"        // MP4 URL from the page, which redirects to the signed URL
+	AssetSignedMP4 Asset = "signed-mp4" // Signed CAS URL serving the MP4 bytes
+	AssetCaptions  Asset = "captions"   // WebVTT captions
+	AssetDocument  Asset = "document"   // Shared documents
+	AssetAPI       Asset = "api"        // XML API; use "" as the recording ID
+)
+
+// ParseAsset validates an asset name.
+func ParseAsset(name string) (Asset, error) {
+	switch a := Asset(name); a {
+	case AssetPage, AssetZip, AssetMP4, AssetSignedMP4, AssetCaptions, AssetDocument, AssetAPI:
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown asset %q", name)
+}
+
+// FaultKind selects how a faulty response misbehaves.
+type FaultKind string
+
+const (
+	FaultSlow      FaultKind = "slow"       // Body is sent in small chunks with Delay between them
+	FaultTruncate  FaultKind = "truncate"   // Connection closes after half the declared body
+	FaultRateLimit FaultKind = "rate-limit" // 429 Too Many Requests with Retry-After
+	FaultLoginPage FaultKind = "login-page" // 200 HTML login page instead of the asset
+	FaultNotFound  FaultKind = "not-found"  // 404, e.g. ZIP not generated yet
+	FaultForbidden FaultKind = "forbidden"  // 403, e.g. expired signature
+	FaultServer    FaultKind = "server"     // 500 Internal Server Error
+	FaultPreparing FaultKind = "preparing"  // 200 HTML "preparing your download" page, as for an ungenerated ZIP
+)
+
+// Fault describes an injected failure.
+type Fault struct {
+	Kind  FaultKind
+	Delay time.Duration // FaultSlow: pause between chunks (default 50ms); FaultRateLimit: Retry-After
+	Times int           // Number of requests affected; 0 affects every request
+}
+
+// ParseFaultKind validates a fault kind name.
+func ParseFaultKind(name string) (FaultKind, error) {
+	switch k := FaultKind(name); k {
+	case FaultSlow, FaultTruncate, FaultRateLimit, FaultLoginPage, FaultNotFound, FaultForbidden, FaultServer,
+		FaultPreparing:
+		return k, nil
+	}
+	return "", fmt.Errorf("unknown fault kind %q", name)
+}
+
+type faultKey struct {
+	recording string
+	asset     Asset
+}
+
+type faultState struct {
+	Fault
+	remaining int // Requests left when Times > 0
+}
+
+// InjectFault makes requests for an asset of a recording fail. Faults on the
+// same asset replace each other.
+func (s *Server) InjectFault(recordingID string, asset Asset, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[faultKey{recordingID, asset}] = &faultState{Fault: fault, remaining: fault.Times}
+}
+
+// ClearFaults removes all injected faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.faults)
+}
+
+// takeFaultLocked returns the fault for the next request, consuming one use.
+func (s *Server) takeFaultLocked(recordingID string, asset Asset) *Fault {
+	key := faultKey{recordingID, asset}
+	state, ok := s.faults[key]
+	if !ok {
+		return nil
+	}
+	if state.Times > 0 {
+		state.remaining--
+		if state.remaining <= 0 {
+			delete(s.faults, key)
+		}
+	}
+	f := state.Fault
+	return &f
+}
+
+// respond writes the response for status faults and reports whether it did.
+// Body faults (slow, truncate) are applied by wrap instead.
+func (f *Fault) respond(w http.ResponseWriter) bool {
+	switch f.Kind {
+	case FaultRateLimit:
+		retry := max(int(f.Delay/time.Second), 1)
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+	case FaultLoginPage:
+		serveLoginPage(w)
+	case FaultPreparing:
+		servePreparingPage(w)
+	case FaultNotFound:
+		http.Error(w, "Not Found", http.StatusNotFound)
+	case FaultForbidden:
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	case FaultServer:
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	default:
+		return false
+	}
+	return true
+}
+
+// wrap returns a writer that applies a body fault.
+func (f *Fault) wrap(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	switch f.Kind {
+	case FaultSlow:
+		delay := f.Delay
+		if delay <= 0 {
+			delay = 50 * time.Millisecond
+		}
+		return &slowWriter{ResponseWriter: w, r: r, delay: delay}
+	case FaultTruncate:
+		return &truncatingWriter{ResponseWriter: w}
+	default:
+		return w
+	}
+}
+
+// slowWriter sends the body in small chunks with a pause between them.
+type slowWriter struct {
+	http.ResponseWriter
+	r     *http.Request
+	delay time.Duration
+}
+
+const slowChunkSize = 16 << 10
+
+func (sw *slowWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), slowChunkSize)
+		m, err := sw.ResponseWriter.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+			f.Flush()
+		}
+		p = p[n:]
+		select {
+		case <-sw.r.Context().Done():
+			return written, sw.r.Context().Err()
+		case <-time.After(sw.delay):
+		}
+	}
+	return written, nil
+}
+
+// truncatingWriter drops everything after half of the declared Content-Length,
+// so the server closes the connection early.
+type truncatingWriter struct {
+	http.ResponseWriter
+	limit   int
+	written int
+	started bool
+}
+
+func (tw *truncatingWriter) Write(p []byte) (int, error) {
+	if !tw.started {
+		tw.started = true
+		n, _ := strconv.Atoi(tw.Header().Get("Content-Length"))
+		tw.limit = n / 2
+	}
+	keep := min(len(p), max(tw.limit-tw.written, 0))
+	if keep > 0 {
+		if _, err := tw.ResponseWriter.Write(p[:keep]); err != nil {
+			return 0, err
+		}
+		tw.written += keep
+	}
+	return len(p), nil
+}
+
+// writeBody writes a complete response with Content-Length, honouring HEAD.
+func writeBody(w http.ResponseWriter, r *http.Request, contentType string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(data)
+}
+
+// serveLoginPage writes the HTML login wall Connect shows for expired sessions.
+func serveLoginPage(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, `<!DOCTYPE html>
+<html><head><title>Adobe Connect Central Login</title></head>
+<body>
+<form name="login" action="/system/login" method="post">
+<input type="text" name="login"><input type="password" name="password">
+<input type="submit" value="Log In">
+</form>
+</body></html>
+`)
+}
+
+// servePreparingPage writes the page Connect shows while it builds a download package.
+func servePreparingPage(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, `<!DOCTYPE html>
+<html><head><title>Adobe Connect</title><meta http-equiv="refresh" content="10"></head>
+<body>
+<p>Preparing your download. This may take a few minutes, please wait...</p>
+</body></html>
+`)
+}
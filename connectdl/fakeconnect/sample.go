@@ -0,0 +1,279 @@
+package fakeconnect
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SampleRecording returns a public recording with everything the downloader
+// handles: an MP4, captions with anonymous speaker markers, attendees in
+// indexstream.xml, chat in transcriptstream.xml, a lecturer title pod, an
+// answered Q&A question, a closed poll, a whiteboard drawing, one shared
+// document shown page by page in a share pod and two seconds of the
+// lecturer's voice as an AAC stream.
+func SampleRecording(id string) Recording {
+	return Recording{
+		ID:          id,
+		Title:       "Fake Lecture " + id,
+		Description: "Offline sample recording",
+		DateBegin:   "2025-03-05T10:00:00.000+00:00",
+		DateEnd:     "2025-03-05T11:30:00.000+00:00",
+		Meeting:     "SE101 Lectures",
+		Creator:     "Jane Doe",
+		MP4:         SampleMP4(64 << 10),
+		Captions:    sampleCaptions,
+		Files: map[string]string{
+			"indexstream.xml":      sampleIndexStream,
+			"transcriptstream.xml": sampleTranscriptStream,
+			"fttitle0.xml":         sampleTitle,
+			"ftqa2.xml":            sampleQA,
+			"ftpoll3.xml":          samplePoll,
+			"ftnotes4.xml":         sampleNotes,
+			"ftweblinks5.xml":      sampleWebLinks,
+			"ftwhiteboard6.xml":    sampleWhiteboard,
+			"ftcontent7.xml":       sampleContent,
+			"ftfileshare1.xml":     FileShareXML(id, "Week 1 Slides.pdf"),
+			"cameraVoip_1_3.flv":   sampleVoice,
+		},
+		Documents: map[string][]byte{
+			"Week 1 Slides.pdf": []byte("%PDF-1.4\n% fake slides\n"),
+		},
+	}
+}
+
+// SampleMP4 returns size bytes that start with an MP4 ftyp box.
+func SampleMP4(size int) []byte {
+	data := make([]byte, max(size, 32))
+	copy(data, "\x00\x00\x00\x20ftypisom\x00\x00\x02\x00isomiso2avc1mp41")
+	for i := 32; i < len(data); i++ {
+		data[i] = byte(i)
+	}
+	return data
+}
+
+// FileShareXML returns an ftfileshare stream that shares the named documents,
+// with playbackFileName pointing at /system/download like Connect does.
+func FileShareXML(id string, names ...string) string {
+	var b strings.Builder
+	b.WriteString("<root>\n")
+	for i, name := range names {
+		fmt.Fprintf(&b, `  <Message time="%d" type="data">
+    <Method><![CDATA[setValue]]></Method>
+    <Object>
+      <newValue>
+        <name><![CDATA[%s]]></name>
+        <playbackFileName><![CDATA[%s]]></playbackFileName>
+      </newValue>
+    </Object>
+  </Message>
+`, (i+1)*60000, name, DocumentPath(id, name))
+	}
+	b.WriteString("</root>\n")
+	return b.String()
+}
+
+const sampleCaptions = `WEBVTT
+
+00:00:05.000 --> 00:00:10.000
+@:@User1@:@ Welcome everyone to today's lecture.
+
+00:00:12.000 --> 00:00:18.000
+@:@User2@:@ Could you share the slides?
+
+00:00:20.000 --> 00:00:25.000
+@:@User1@:@ They are in the file share pod.
+`
+
+const sampleIndexStream = `<root>
+  <Message time="0" type="data">
+    <Method><![CDATA[onMetaData]]></Method>
+    <Object>
+      <attendees>
+        <Object>
+          <anonymousName><![CDATA[User1]]></anonymousName>
+          <fullName><![CDATA[Tech Jane Doe]]></fullName>
+        </Object>
+        <Object>
+          <anonymousName><![CDATA[User2]]></anonymousName>
+          <fullName><![CDATA[James Lewis]]></fullName>
+          <role><![CDATA[viewer]]></role>
+        </Object>
+      </attendees>
+    </Object>
+  </Message>
+  <Message time="1000" type="data">
+    <Method><![CDATA[userJoined]]></Method>
+    <Object><anonymousName><![CDATA[User1]]></anonymousName></Object>
+  </Message>
+  <Message time="10000" type="data">
+    <Method><![CDATA[userJoined]]></Method>
+    <Object><anonymousName><![CDATA[User2]]></anonymousName></Object>
+  </Message>
+  <Message time="12000" type="20">
+    <Method><![CDATA[playEvent]]></Method>
+    <Object>
+      <streamName><![CDATA[/cameraVoip_1_3]]></streamName>
+      <streamType><![CDATA[cameraVoip]]></streamType>
+      <streamPublisherID><![CDATA[User1]]></streamPublisherID>
+    </Object>
+  </Message>
+</root>
+`
+
+// sampleVoice is an FLV stream of 44.1 kHz mono AAC frames, 23 ms apart.
+var sampleVoice = func() string {
+	b := []byte("FLV\x01\x04\x00\x00\x00\x09\x00\x00\x00\x00")
+	tag := func(ts int, data ...byte) {
+		n := len(data)
+		b = append(b, 8, byte(n>>16), byte(n>>8), byte(n), byte(ts>>16), byte(ts>>8), byte(ts), byte(ts>>24), 0, 0, 0)
+		b = append(b, data...)
+		size := 11 + n
+		b = append(b, byte(size>>24), byte(size>>16), byte(size>>8), byte(size))
+	}
+	tag(0, 0xae, 0x00, 0x12, 0x08) // AudioSpecificConfig: AAC LC, 44.1 kHz, mono
+	for i := range 87 {
+		tag(i*23, 0xae, 0x01, 0x21, 0x10, 0x04, 0x60, 0x8c, 0x1c)
+	}
+	return string(b)
+}()
+
+const sampleTranscriptStream = `<root>
+  <Message time="0" type="cycleEntry">
+    <Method><![CDATA[cycleEntry]]></Method>
+    <Object>
+      <CYCLEFORMAT>
+        <MSG><![CDATA[[Tech  Jane Doe] has joined the stage.]]></MSG>
+      </CYCLEFORMAT>
+    </Object>
+  </Message>
+  <Message time="14000" type="cycleEntry">
+    <Method><![CDATA[cycleEntry]]></Method>
+    <Object>
+      <iconType><![CDATA[chat]]></iconType>
+      <label><![CDATA[Slides please!]]></label>
+      <name><![CDATA[James Lewis]]></name>
+      <time><![CDATA[14000]]></time>
+    </Object>
+  </Message>
+</root>
+`
+
+const sampleQA = `<root>
+  <Message time="30000" type="data">
+    <Method><![CDATA[setValue]]></Method>
+    <Object>
+      <questionID><![CDATA[q1]]></questionID>
+      <questionText><![CDATA[Will this be on the exam?]]></questionText>
+      <fromName><![CDATA[User2]]></fromName>
+    </Object>
+  </Message>
+  <Message time="45000" type="data">
+    <Method><![CDATA[setValue]]></Method>
+    <Object>
+      <questionID><![CDATA[q1]]></questionID>
+      <questionText><![CDATA[Will this be on the exam?]]></questionText>
+      <fromName><![CDATA[User2]]></fromName>
+      <answers>
+        <Object>
+          <answerText><![CDATA[Yes, chapters 1 &amp; 2.]]></answerText>
+          <answeredBy><![CDATA[User1]]></answeredBy>
+        </Object>
+      </answers>
+    </Object>
+  </Message>
+</root>
+`
+
+const samplePoll = `<root>
+  <Message time="600000" type="data">
+    <Method><![CDATA[setValue]]></Method>
+    <Object>
+      <question><![CDATA[Did the example make sense?]]></question>
+      <questionType><![CDATA[multiple-choice]]></questionType>
+      <answers>
+        <String><![CDATA[Yes]]></String>
+        <String><![CDATA[No]]></String>
+      </answers>
+      <pollStatus><![CDATA[open]]></pollStatus>
+    </Object>
+  </Message>
+  <Message time="660000" type="data">
+    <Method><![CDATA[setValue]]></Method>
+    <Object>
+      <userVotes>
+        <Object><userID><![CDATA[User2]]></userID><answer><![CDATA[0]]></answer></Object>
+      </userVotes>
+      <pollStatus><![CDATA[closed]]></pollStatus>
+    </Object>
+  </Message>
+</root>
+`
+
+const sampleTitle = `<root>
+  <Message time="0" type="data">
+    <Object>
+      <html><![CDATA[<p>SE101</p><p>Lecturer: <b>Jane Doe</b></p>]]></html>
+    </Object>
+  </Message>
+</root>
+`
+
+const sampleNotes = `<root>
+  <Message time="0" type="data">
+    <Method><![CDATA[setValue]]></Method>
+    <Object>
+      <html><![CDATA[<P><B>Agenda</B></P>]]></html>
+    </Object>
+  </Message>
+  <Message time="300000" type="data">
+    <Method><![CDATA[setValue]]></Method>
+    <Object>
+      <html><![CDATA[<P><B>Agenda</B></P><UL><LI>Intro</LI><LI>Examples</LI></UL>]]></html>
+    </Object>
+  </Message>
+</root>
+`
+
+const sampleWebLinks = `<root>
+  <Message time="420000" type="data">
+    <Method><![CDATA[setValue]]></Method>
+    <Object>
+      <links>
+        <Object>
+          <name><![CDATA[Course page]]></name>
+          <url><![CDATA[https://example.edu/se101]]></url>
+        </Object>
+      </links>
+    </Object>
+  </Message>
+</root>
+`
+
+const sampleWhiteboard = `<root>
+  <Message time="480000" type="data">
+    <Method><![CDATA[addShape]]></Method>
+    <Object>
+      <shapeID><![CDATA[s1]]></shapeID>
+      <shapeType><![CDATA[ellipse]]></shapeType>
+      <x><![CDATA[100]]></x><y><![CDATA[80]]></y>
+      <width><![CDATA[120]]></width><height><![CDATA[60]]></height>
+      <lineColor><![CDATA[255]]></lineColor>
+    </Object>
+  </Message>
+</root>
+`
+
+const sampleContent = `<root>
+  <Message time="60000" type="data">
+    <Method><![CDATA[setValue]]></Method>
+    <Object>
+      <documentName><![CDATA[Week 1 Slides.pdf]]></documentName>
+      <slideIndex><![CDATA[0]]></slideIndex>
+    </Object>
+  </Message>
+  <Message time="300000" type="data">
+    <Method><![CDATA[setValue]]></Method>
+    <Object><slideIndex><![CDATA[1]]></slideIndex></Object>
+  </Message>
+</root>
+`
@@ -0,0 +1,131 @@
+package connectdl
+
+import (
+	"io"
+	"time"
+
+	"github.com/keanucz/AdobeConnectDL/internal/downloader"
+)
+
+// The parsers below work on the raw/ directory of a downloaded recording,
+// i.e. the extracted contents of the Connect recording ZIP.
+
+// UserMapping returns the anonymous ID to real name mapping, e.g. {"User13": "Jane Smith"}.
+func UserMapping(rawDir string) map[string]string {
+	return downloader.ParseUserMapping(rawDir)
+}
+
+// LecturerName returns the lecturer's name, or "" when it cannot be determined.
+func LecturerName(rawDir string) string {
+	return downloader.ParseLecturerName(rawDir)
+}
+
+// DocumentLinks returns the documents shared during the session. Hostname is
+// the Connect server used to build absolute download URLs.
+func DocumentLinks(rawDir, hostname string) []DocumentInfo {
+	return downloader.ParseDocumentLinks(rawDir, hostname)
+}
+
+// WriteChatLog writes the session chat as timestamped plain text to outputPath.
+func WriteChatLog(rawDir, outputPath string) error {
+	return downloader.WriteChatLog(rawDir, outputPath)
+}
+
+// ChatMessages returns the session chat with formatting decoded, offsets into
+// the recording, the audience of each message and real names applied.
+func ChatMessages(rawDir string) ([]ChatMessage, error) {
+	return downloader.ParseChatMessages(rawDir)
+}
+
+// QA returns the questions asked in the session's Q&A pods with their askers,
+// answers, answerers and offsets into the recording.
+func QA(rawDir string) ([]QAQuestion, error) {
+	return downloader.ParseQA(rawDir)
+}
+
+// Polls returns the session's polls with their options, answer type, open and
+// close offsets, vote counts and, where recorded, each participant's answer.
+func Polls(rawDir string) ([]Poll, error) {
+	return downloader.ParsePolls(rawDir)
+}
+
+// Notes returns the content of the session's Notes pods as Markdown, with
+// one revision per change and the last revision being the final content.
+func Notes(rawDir string) ([]Note, error) {
+	return downloader.ParseNotes(rawDir)
+}
+
+// Links returns the URLs shared in Web Links pods and posted in the chat, each
+// once, in the order they were first shared.
+func Links(rawDir string) []Link {
+	return downloader.ParseLinks(rawDir)
+}
+
+// Whiteboards returns the final drawing on each whiteboard and share pod page,
+// with the drawing as it was whenever the pod moved to another page.
+func Whiteboards(rawDir string) ([]Whiteboard, error) {
+	return downloader.ParseWhiteboards(rawDir)
+}
+
+// Attendance returns each participant's role, join and leave times, total
+// presence and chat and Q&A activity, as written to attendance.json. End is
+// the recording length in milliseconds; with 0, participants who never left
+// are counted until the last event.
+func Attendance(rawDir string, end int64) (AttendanceReport, error) {
+	return downloader.ParseAttendance(rawDir, end)
+}
+
+// Chapters returns the chapter menu that rules (nil = document and layout
+// changes) cut the recording into, as written to chapters.txt and
+// chapters.vtt. End is the recording length in milliseconds; with 0, the last
+// chapter ends at the last event.
+func Chapters(rawDir string, rules []ChapterRule, end int64) ([]Chapter, error) {
+	return downloader.ParseChapters(rawDir, rules, end)
+}
+
+// Slides returns which shared document and page each share pod showed and
+// when, as written to slides.json. Documents are matched to DocumentLinks;
+// hostname is the Connect server used to build their URLs.
+func Slides(rawDir, hostname string) ([]SlideChange, error) {
+	return downloader.ParseSlides(rawDir, hostname)
+}
+
+// Streams returns the codec, duration, resolution, bitrate, start offset and
+// owner of every FLV media stream (camera and VoIP, screen share, ...) in
+// rawDir, as recorded in metadata.json.
+func Streams(rawDir string) []MediaStream {
+	return downloader.ParseStreams(rawDir)
+}
+
+// Inspect returns the media streams of a recording directory or its raw/
+// directory. Once the retention policy has removed the FLV files, the
+// streams recorded in metadata.json are returned instead.
+func Inspect(dir string) ([]MediaStream, error) {
+	return downloader.InspectRecording(dir)
+}
+
+// Timeline returns the session's joins, chat, file shares, slide changes and
+// other pod events as normalized, time-ordered events with actors mapped to
+// real names, as written to session.json. A non-zero start, usually
+// RecordingDetails.DateBegin, adds wall clock timestamps.
+func Timeline(rawDir string, start time.Time) SessionTimeline {
+	return downloader.ParseSessionTimeline(rawDir, start)
+}
+
+// CleanVTT rewrites a Connect caption file with speaker markers replaced by
+// real names, using LecturerName and UserMapping results.
+func CleanVTT(srcPath, dstPath, lecturerName string, userMapping map[string]string) error {
+	return downloader.CleanVTT(srcPath, dstPath, lecturerName, userMapping)
+}
+
+// WriteTranscript converts a VTT file to a plain text transcript at outputPath.
+func WriteTranscript(vttPath, outputPath string) error {
+	return downloader.WriteTranscript(vttPath, outputPath)
+}
+
+// NewStreamDecoder reads the <Message> elements of a raw stream XML file such
+// as indexstream.xml one at a time. Pod is reported in each message; use the
+// file name without extension.
+func NewStreamDecoder(r io.Reader, pod string) *StreamDecoder {
+	return downloader.NewStreamDecoder(r, pod)
+}
@@ -0,0 +1,331 @@
+package downloader
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Participant roles reported in Attendee.Role.
+const (
+	RoleHost        = "host"
+	RolePresenter   = "presenter"
+	RoleParticipant = "participant"
+)
+
+// AttendanceReport lists who attended a session and for how long, written
+// to attendance.json and attendance.csv.
+type AttendanceReport struct {
+	Attendees []Attendee        `json:"attendees,omitempty"` // Left out in aggregate-only reports
+	Summary   AttendanceSummary `json:"summary"`
+}
+
+// Attendee is one participant's presence and activity.
+type Attendee struct {
+	Name              string              `json:"name"`
+	ID                string              `json:"id,omitempty"`   // Anonymous ID in the recording, e.g. "User13"
+	Role              string              `json:"role,omitempty"` // One of the Role* roles, when recorded
+	Sessions          []AttendanceSession `json:"sessions"`       // Each stretch between joining and leaving
+	PresenceMS        int64               `json:"presence_ms"`
+	Presence          string              `json:"presence"` // PresenceMS as HH:MM:SS
+	ChatMessages      int                 `json:"chat_messages"`
+	QuestionsAsked    int                 `json:"questions_asked"`
+	QuestionsAnswered int                 `json:"questions_answered"`
+}
+
+// AttendanceSession is one stretch of an attendee's presence. Offsets are in
+// milliseconds from the start of the recording.
+type AttendanceSession struct {
+	Joined     int64  `json:"joined_ms"`
+	JoinedTime string `json:"joined_time"`
+	Left       int64  `json:"left_ms"`
+	LeftTime   string `json:"left_time"`
+}
+
+// AttendanceSummary holds the counts that remain in aggregate-only reports.
+type AttendanceSummary struct {
+	Attendees       int            `json:"attendees"`
+	ByRole          map[string]int `json:"by_role,omitempty"`
+	PeakConcurrent  int            `json:"peak_concurrent"`
+	AveragePresence string         `json:"average_presence"` // HH:MM:SS
+	ChatMessages    int            `json:"chat_messages"`
+	Questions       int            `json:"questions"`
+	Answers         int            `json:"answers"`
+}
+
+// roleField names a participant's role in attendee objects. Roles are only
+// read from objects that also identify the participant, so the field isn't
+// taken from unrelated objects.
+const roleField = "role"
+
+// extractAttendance builds the attendance report from the join and leave
+// events in indexstream.xml, with activity in the chat messages and the Q&A
+// matched by name.
+// Participants still present at the end are counted until end, the recording
+// length in milliseconds, or the last event when end is 0. Attendees listed
+// in the recording's metadata but never seen joining have no sessions.
+func extractAttendance(
+	rawDir string,
+	userMapping map[string]string,
+	messages []ChatMessage,
+	end int64,
+) (AttendanceReport, error) {
+	byID := make(map[string]*Attendee)
+	var attendees []*Attendee
+	open := make(map[*Attendee]int64)
+	attendee := func(n *StreamNode) *Attendee {
+		id := n.Field("anonymousName")
+		name := cmp.Or(userMapping[id], strings.TrimSpace(strings.TrimPrefix(firstField(n, actorFields), "Tech ")))
+		key := cmp.Or(id, name)
+		if key == "" {
+			return nil
+		}
+		a := byID[key]
+		if a == nil {
+			a = &Attendee{Name: cmp.Or(name, id), ID: id}
+			byID[key] = a
+			attendees = append(attendees, a)
+		}
+		if role := participantRole(n.Field(roleField)); role != "" {
+			a.Role = role
+		}
+		return a
+	}
+
+	var last int64
+	err := readStream(filepath.Join(rawDir, "indexstream.xml"), func(m StreamMessage) bool {
+		last = max(last, m.Time)
+		kind := sessionEventType(m)
+		m.Walk(func(n *StreamNode) {
+			if n.Field("anonymousName") == "" && firstField(n, actorFields) == "" {
+				return
+			}
+			a := attendee(n)
+			if a == nil {
+				return
+			}
+			switch _, present := open[a]; {
+			case kind == SessionJoin && !present:
+				open[a] = m.Time
+			case kind == SessionLeave && present:
+				a.Sessions = append(a.Sessions, attendanceSession(open[a], m.Time))
+				delete(open, a)
+			case kind == SessionLeave && len(a.Sessions) == 0:
+				// Left without a recorded join, so present from the start
+				a.Sessions = append(a.Sessions, attendanceSession(0, m.Time))
+			}
+		})
+		return true
+	})
+	if err != nil && len(attendees) == 0 {
+		return AttendanceReport{}, err
+	}
+	end = cmp.Or(end, last)
+	for a, joined := range open {
+		a.Sessions = append(a.Sessions, attendanceSession(joined, max(end, joined)))
+	}
+
+	questions, _ := extractQA(rawDir, userMapping)
+	byName := make(map[string]*Attendee, len(attendees))
+	for _, a := range attendees {
+		byName[a.Name] = a
+	}
+	report := AttendanceReport{Summary: AttendanceSummary{ChatMessages: len(messages), Questions: len(questions)}}
+	for _, msg := range messages {
+		if a := byName[msg.Sender]; a != nil {
+			a.ChatMessages++
+		}
+	}
+	for _, q := range questions {
+		if a := byName[q.Asker]; a != nil {
+			a.QuestionsAsked++
+		}
+		for _, ans := range q.Answers {
+			report.Summary.Answers++
+			if a := byName[ans.Answerer]; a != nil {
+				a.QuestionsAnswered++
+			}
+		}
+	}
+
+	var total int64
+	for _, a := range attendees {
+		slices.SortFunc(a.Sessions, func(x, y AttendanceSession) int { return cmp.Compare(x.Joined, y.Joined) })
+		if a.Sessions == nil {
+			a.Sessions = []AttendanceSession{}
+		}
+		for _, s := range a.Sessions {
+			a.PresenceMS += s.Left - s.Joined
+		}
+		a.Presence = formatMilliseconds(a.PresenceMS)
+		total += a.PresenceMS
+		report.Attendees = append(report.Attendees, *a)
+	}
+	slices.SortStableFunc(report.Attendees, func(x, y Attendee) int {
+		return cmp.Compare(firstJoin(x), firstJoin(y))
+	})
+	summarizeAttendance(&report.Summary, report.Attendees, total)
+	return report, nil
+}
+
+func attendanceSession(joined, left int64) AttendanceSession {
+	return AttendanceSession{
+		Joined:     joined,
+		JoinedTime: formatMilliseconds(joined),
+		Left:       left,
+		LeftTime:   formatMilliseconds(left),
+	}
+}
+
+// firstJoin orders attendees by when they first joined; those never seen
+// joining sort last.
+func firstJoin(a Attendee) int64 {
+	if len(a.Sessions) == 0 {
+		return 1<<63 - 1
+	}
+	return a.Sessions[0].Joined
+}
+
+// summarizeAttendance fills in the counts derived from the attendees.
+func summarizeAttendance(s *AttendanceSummary, attendees []Attendee, total int64) {
+	type edge struct {
+		at    int64
+		delta int
+	}
+	var edges []edge
+	for _, a := range attendees {
+		s.Attendees++
+		if a.Role != "" {
+			if s.ByRole == nil {
+				s.ByRole = make(map[string]int)
+			}
+			s.ByRole[a.Role]++
+		}
+		for _, session := range a.Sessions {
+			edges = append(edges, edge{session.Joined, 1}, edge{session.Left, -1})
+		}
+	}
+	// Leaves sort before joins at the same offset so a rejoin isn't counted twice
+	slices.SortFunc(edges, func(x, y edge) int {
+		return cmp.Or(cmp.Compare(x.at, y.at), cmp.Compare(x.delta, y.delta))
+	})
+	present := 0
+	for _, e := range edges {
+		present += e.delta
+		s.PeakConcurrent = max(s.PeakConcurrent, present)
+	}
+	if s.Attendees > 0 {
+		s.AveragePresence = formatMilliseconds(total / int64(s.Attendees))
+	} else {
+		s.AveragePresence = formatMilliseconds(0)
+	}
+}
+
+// participantRole normalizes Connect's role and permission names: "owner"
+// and "host" are hosts, "presenter" and "mini-host" presenters, and "viewer"
+// or "participant" participants.
+func participantRole(role string) string {
+	switch role = strings.ToLower(role); {
+	case role == "":
+		return ""
+	case strings.Contains(role, "mini"), strings.Contains(role, "present"):
+		return RolePresenter
+	case strings.Contains(role, "host"), strings.Contains(role, "owner"), role == "admin":
+		return RoleHost
+	}
+	return RoleParticipant
+}
+
+// attendanceCSV renders one row per attendee, or one row per summary count
+// for aggregate-only reports.
+func attendanceCSV(report AttendanceReport, aggregateOnly bool) ([]byte, error) {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	if aggregateOnly {
+		s := report.Summary
+		cw.Write([]string{"metric", "value"})
+		cw.Write([]string{"attendees", strconv.Itoa(s.Attendees)})
+		for _, role := range []string{RoleHost, RolePresenter, RoleParticipant} {
+			if n, ok := s.ByRole[role]; ok {
+				cw.Write([]string{role + "s", strconv.Itoa(n)})
+			}
+		}
+		cw.Write([]string{"peak_concurrent", strconv.Itoa(s.PeakConcurrent)})
+		cw.Write([]string{"average_presence", s.AveragePresence})
+		cw.Write([]string{"chat_messages", strconv.Itoa(s.ChatMessages)})
+		cw.Write([]string{"questions", strconv.Itoa(s.Questions)})
+		cw.Write([]string{"answers", strconv.Itoa(s.Answers)})
+	} else {
+		cw.Write([]string{
+			"name", "role", "first_joined", "last_left", "sessions", "presence", "presence_ms",
+			"chat_messages", "questions_asked", "questions_answered",
+		})
+		for _, a := range report.Attendees {
+			var joined, left string
+			if n := len(a.Sessions); n > 0 {
+				joined, left = a.Sessions[0].JoinedTime, a.Sessions[n-1].LeftTime
+			}
+			cw.Write([]string{
+				a.Name, a.Role, joined, left, strconv.Itoa(len(a.Sessions)), a.Presence,
+				strconv.FormatInt(a.PresenceMS, 10), strconv.Itoa(a.ChatMessages),
+				strconv.Itoa(a.QuestionsAsked), strconv.Itoa(a.QuestionsAnswered),
+			})
+		}
+	}
+	cw.Flush()
+	return buf.Bytes(), cw.Error()
+}
+
+// writeAttendance writes attendance.json and attendance.csv and records the
+// status of the attendance asset. With aggregateOnly, no names are written.
+// The recording length from details bounds the presence of participants who
+// never left.
+func writeAttendance(
+	rootDir, rawDir string,
+	details *RecordingDetails,
+	userMapping map[string]string,
+	messages []ChatMessage,
+	aggregateOnly bool,
+	assets *assetTracker,
+	logger Logger,
+) {
+	start := time.Now()
+	var end int64
+	if details != nil {
+		end = int64(details.DurationSeconds * 1000)
+	}
+	report, err := extractAttendance(rawDir, userMapping, messages, end)
+	if err != nil {
+		log(logger, "attendance extraction failed", "error", err)
+		assets.fail(AssetAttendance, time.Since(start), err)
+		return
+	}
+	if aggregateOnly {
+		report.Attendees = nil
+	}
+	jsonPath := filepath.Join(rootDir, "attendance.json")
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err == nil {
+		err = os.WriteFile(jsonPath, data, 0o644)
+	}
+	if err == nil {
+		data, err = attendanceCSV(report, aggregateOnly)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(rootDir, "attendance.csv"), data, 0o644)
+	}
+	if err != nil {
+		log(logger, "attendance write failed", "error", err)
+		assets.fail(AssetAttendance, time.Since(start), err)
+		return
+	}
+	log(logger, "attendance report created", "path", jsonPath, "attendees", report.Summary.Attendees)
+	assets.ok(AssetAttendance, jsonPath, time.Since(start))
+}
@@ -0,0 +1,86 @@
+package downloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractAttendance(t *testing.T) {
+	rawDir := lectureStreams(t, "lecture11")
+	mapping := extractUserMapping(rawDir)
+	messages, _ := extractChatMessages(rawDir, mapping)
+	report, err := extractAttendance(rawDir, mapping, messages, 241000)
+	if err != nil {
+		t.Fatalf("extractAttendance error: %v", err)
+	}
+	want := AttendanceReport{
+		Attendees: []Attendee{
+			{
+				Name: "Jane Doe", ID: "User1", Role: RoleHost,
+				Sessions:   []AttendanceSession{{1000, "00:00:01", 241000, "00:04:01"}},
+				PresenceMS: 240000, Presence: "00:04:00", QuestionsAnswered: 1,
+			},
+			{
+				Name: "James Lewis", ID: "User2", Role: RoleParticipant,
+				Sessions: []AttendanceSession{
+					{61000, "00:01:01", 121000, "00:02:01"},
+					{181000, "00:03:01", 241000, "00:04:01"},
+				},
+				PresenceMS: 120000, Presence: "00:02:00", ChatMessages: 1, QuestionsAsked: 1,
+			},
+			{Name: "Ann Lee", ID: "User3", Sessions: []AttendanceSession{}, Presence: "00:00:00"},
+		},
+		Summary: AttendanceSummary{
+			Attendees: 3, ByRole: map[string]int{RoleHost: 1, RoleParticipant: 1}, PeakConcurrent: 2,
+			AveragePresence: "00:02:00", ChatMessages: 1, Questions: 1, Answers: 1,
+		},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report =\n%+v\nwant\n%+v", report, want)
+	}
+}
+
+func TestWriteAttendance(t *testing.T) {
+	rawDir := lectureStreams(t, "lecture11")
+	mapping := extractUserMapping(rawDir)
+	messages, _ := extractChatMessages(rawDir, mapping)
+	for _, aggregate := range []bool{false, true} {
+		rootDir := t.TempDir()
+		assets := &assetTracker{root: rootDir}
+		writeAttendance(rootDir, rawDir, nil, mapping, messages, aggregate, assets, nil)
+		if got := assets.list(); len(got) != 1 || got[0].State != StateOK {
+			t.Fatalf("aggregate=%v: assets = %+v", aggregate, got)
+		}
+		csvData, _ := os.ReadFile(filepath.Join(rootDir, "attendance.csv"))
+		jsonData, _ := os.ReadFile(filepath.Join(rootDir, "attendance.json"))
+		var report AttendanceReport
+		if err := json.Unmarshal(jsonData, &report); err != nil {
+			t.Fatalf("attendance.json: %v", err)
+		}
+
+		if !aggregate {
+			// Without a recording length, James is counted until the last event
+			line := "James Lewis,participant,00:01:01,00:03:01,2,00:01:00,60000,1,1,0"
+			if !strings.Contains(string(csvData), line) {
+				t.Errorf("attendance.csv missing %q:\n%s", line, csvData)
+			}
+			if len(report.Attendees) != 3 {
+				t.Errorf("attendance.json has %d attendees, want 3", len(report.Attendees))
+			}
+			continue
+		}
+		if strings.Contains(string(csvData), "James") || strings.Contains(string(jsonData), "James") {
+			t.Errorf("aggregate report names participants:\n%s\n%s", csvData, jsonData)
+		}
+		if !strings.Contains(string(csvData), "metric,value\nattendees,3\nhosts,1\nparticipants,1\n") {
+			t.Errorf("unexpected aggregate attendance.csv:\n%s", csvData)
+		}
+		if report.Attendees != nil || report.Summary.Attendees != 3 {
+			t.Errorf("aggregate attendance.json = %+v", report)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package downloader
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SessionStatus describes what the Connect server reports for a session token.
+type SessionStatus struct {
+	Host          string           `json:"host"`
+	LoggedIn      bool             `json:"logged_in"`
+	UserName      string           `json:"user_name,omitempty"`
+	Login         string           `json:"login,omitempty"`
+	ServerVersion string           `json:"server_version,omitempty"`
+	Expires       time.Time        `json:"expires,omitzero"`    // Zero when the server does not advertise an expiry
+	Recording     *RecordingAccess `json:"recording,omitempty"` // Nil when no recording was checked
+}
+
+// RecordingAccess reports whether a recording SCO can be opened with the session.
+type RecordingAccess struct {
+	ID         string `json:"id"`
+	Accessible bool   `json:"accessible"`
+	ScoID      string `json:"sco_id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Reason     string `json:"reason,omitempty"` // Connect status code/subcode when not accessible
+}
+
+// ErrAPIUnavailable indicates the host did not answer like a Connect XML API.
+var ErrAPIUnavailable = errors.New("connect XML API not available")
+
+// apiResults mirrors the <results> envelope returned by /api/xml.
+// Only the elements used by the downloader are decoded.
+type apiResults struct {
+	XMLName   xml.Name      `xml:"results"`
+	Status    apiStatus     `xml:"status"`
+	Common    *apiCommon    `xml:"common"`
+	Sco       *apiSco       `xml:"sco"`
+	ScoNav    *apiScoNav    `xml:"sco-nav"`
+	Principal *apiPrincipal `xml:"principal"`
+}
+
+type apiStatus struct {
+	Code    string `xml:"code,attr"`
+	Subcode string `xml:"subcode,attr"`
+}
+
+type apiCommon struct {
+	Version string   `xml:"version"`
+	Host    string   `xml:"host"`
+	User    *apiUser `xml:"user"`
+}
+
+type apiUser struct {
+	UserID string `xml:"user-id,attr"`
+	Name   string `xml:"name"`
+	Login  string `xml:"login"`
+}
+
+// callAPI performs a GET against the Connect XML API and decodes the results envelope.
+// The response cookies are returned so callers can inspect the session cookie.
+func (d *Downloader) callAPI(
+	ctx context.Context,
+	origin, session, action string,
+	params url.Values,
+	logger Logger,
+) (apiResults, []*http.Cookie, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("action", action)
+	apiURL := origin + "/api/xml?" + params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return apiResults{}, nil, err
+	}
+	applyRequestOptions(req, requestOptions{Cookies: mergeCookies(session, nil)})
+
+	log(logger, "calling connect api", "action", action, "host", origin)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return apiResults{}, nil, fmt.Errorf("%w: %s request failed: %w", ErrAPIUnavailable, action, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return apiResults{}, nil, fmt.Errorf("%w: %s returned status %d", ErrAPIUnavailable, action, resp.StatusCode)
+	}
+
+	var res apiResults
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&res); err != nil {
+		return apiResults{}, nil, fmt.Errorf("%w: decode %s: %v", ErrAPIUnavailable, action, err)
+	}
+	log(logger, "connect api response", "action", action, "status", res.Status.Code, "subcode", res.Status.Subcode)
+	return res, resp.Cookies(), nil
+}
+
+// ResolveSession returns the explicit session token, falling back to the
+// session query parameter of the URL.
+func ResolveSession(rawURL, session string) string {
+	if session != "" {
+		return session
+	}
+	if u, err := url.Parse(rawURL); err == nil {
+		return u.Query().Get("session")
+	}
+	return ""
+}
+
+// ParseTarget accepts a bare host, host URL or recording URL and returns the
+// origin (scheme://host) and the recording ID if the path contains one.
+// A missing scheme means https.
+func ParseTarget(target string) (origin, recordingID string, err error) {
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		target = "https://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", "", fmt.Errorf("parse url: %w", err)
+	}
+	if u.Host == "" {
+		return "", "", errors.New("invalid url: host missing")
+	}
+	origin = fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		parts := strings.Split(path, "/")
+		recordingID = parts[len(parts)-1]
+	}
+	return origin, recordingID, nil
+}
+
+// CheckSession calls common-info with the session token and, when target
+// names a recording, checks that the recording SCO is accessible.
+// The session falls back to the ?session= query parameter of target.
+func (d *Downloader) CheckSession(ctx context.Context, target, session string, logger Logger) (SessionStatus, error) {
+	origin, recordingID, err := ParseTarget(target)
+	if err != nil {
+		return SessionStatus{}, err
+	}
+	session = ResolveSession(target, session)
+
+	res, cookies, err := d.callAPI(ctx, origin, session, "common-info", nil, logger)
+	if err != nil {
+		return SessionStatus{}, err
+	}
+	if res.Common == nil {
+		return SessionStatus{}, fmt.Errorf("%w: common-info returned no session details", ErrAPIUnavailable)
+	}
+
+	status := SessionStatus{
+		Host:          origin,
+		ServerVersion: res.Common.Version,
+	}
+	if u := res.Common.User; u != nil && u.UserID != "" {
+		status.LoggedIn = true
+		status.UserName = strings.TrimSpace(u.Name)
+		status.Login = strings.TrimSpace(u.Login)
+	}
+	for _, c := range cookies {
+		if c.Name != "BREEZESESSION" {
+			continue
+		}
+		if c.MaxAge > 0 {
+			status.Expires = time.Now().Add(time.Duration(c.MaxAge) * time.Second).UTC()
+		} else if !c.Expires.IsZero() {
+			status.Expires = c.Expires.UTC()
+		}
+	}
+
+	if recordingID == "" {
+		return status, nil
+	}
+
+	access, err := d.recordingAccess(ctx, origin, recordingID, session, logger)
+	status.Recording = &access
+	return status, err
+}
+
+// CheckRecording reports whether the recording named by target can be opened
+// with the session, without checking the session itself. The session falls
+// back to the ?session= query parameter of target.
+func (d *Downloader) CheckRecording(
+	ctx context.Context,
+	target, session string,
+	logger Logger,
+) (RecordingAccess, error) {
+	origin, recordingID, err := ParseTarget(target)
+	if err != nil {
+		return RecordingAccess{}, err
+	}
+	if recordingID == "" {
+		return RecordingAccess{}, errors.New("invalid url: recording ID missing")
+	}
+	return d.recordingAccess(ctx, origin, recordingID, ResolveSession(target, session), logger)
+}
+
+// recordingAccess looks up a recording SCO by its URL path.
+func (d *Downloader) recordingAccess(
+	ctx context.Context,
+	origin, recordingID, session string,
+	logger Logger,
+) (RecordingAccess, error) {
+	access := RecordingAccess{ID: recordingID}
+	params := url.Values{"url-path": {"/" + recordingID + "/"}}
+	scoRes, _, err := d.callAPI(ctx, origin, session, "sco-by-url", params, logger)
+	if err != nil {
+		return access, err
+	}
+	if scoRes.Status.Code == "ok" && scoRes.Sco != nil {
+		access.Accessible = true
+		access.ScoID = scoRes.Sco.ScoID
+		access.Name = scoRes.Sco.Name
+	} else {
+		access.Reason = scoRes.Status.Code
+		if scoRes.Status.Subcode != "" {
+			access.Reason += "/" + scoRes.Status.Subcode
+		}
+	}
+	return access, nil
+}
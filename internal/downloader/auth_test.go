@@ -0,0 +1,124 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newAPIServer(t *testing.T, validSession string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/xml" {
+			http.NotFound(w, r)
+			return
+		}
+		c, _ := r.Cookie("BREEZESESSION")
+		loggedIn := c != nil && c.Value == validSession
+		w.Header().Set("Content-Type", "text/xml")
+		switch r.URL.Query().Get("action") {
+		case "common-info":
+			if loggedIn {
+				http.SetCookie(w, &http.Cookie{Name: "BREEZESESSION", Value: validSession, MaxAge: 3600})
+				fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><results><status code="ok"/>`+
+					`<common locale="en"><version>12.5.0</version>`+
+					`<user user-id="42" type="user"><name>Jane Doe</name><login>jane@example.com</login></user>`+
+					`</common></results>`)
+				return
+			}
+			fmt.Fprint(w, `<results><status code="ok"/><common locale="en"><version>12.5.0</version></common></results>`)
+		case "sco-by-url":
+			if !loggedIn {
+				fmt.Fprint(w, `<results><status code="no-access" subcode="no-login"/></results>`)
+				return
+			}
+			if r.URL.Query().Get("url-path") != "/rec/" {
+				fmt.Fprint(w, `<results><status code="no-access" subcode="denied"/></results>`)
+				return
+			}
+			fmt.Fprint(w, `<results><status code="ok"/><sco sco-id="1001" folder-id="900" type="content">`+
+				`<name>Lecture 1</name><url-path>/rec/</url-path></sco></results>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestCheckSessionValid(t *testing.T) {
+	server := newAPIServer(t, "good")
+	defer server.Close()
+
+	dl := New(server.Client())
+	status, err := dl.CheckSession(context.Background(), server.URL+"/rec/?session=good", "", nil)
+	if err != nil {
+		t.Fatalf("CheckSession error: %v", err)
+	}
+	if !status.LoggedIn || status.UserName != "Jane Doe" || status.Login != "jane@example.com" {
+		t.Fatalf("unexpected session status: %+v", status)
+	}
+	if status.Expires.IsZero() {
+		t.Errorf("expected expiry from session cookie")
+	}
+	if status.Recording == nil || !status.Recording.Accessible || status.Recording.ScoID != "1001" {
+		t.Fatalf("expected accessible recording, got %+v", status.Recording)
+	}
+
+	// probe --json prints the status, so its field names are part of the output format
+	data, err := json.Marshal(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"logged_in":true`, `"user_name":"Jane Doe"`, `"server_version":"12.5.0"`,
+		`"recording":{"id":"rec","accessible":true,"sco_id":"1001"`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("session status JSON missing %s: %s", key, data)
+		}
+	}
+}
+
+func TestCheckSessionExpired(t *testing.T) {
+	server := newAPIServer(t, "good")
+	defer server.Close()
+
+	dl := New(server.Client())
+	status, err := dl.CheckSession(context.Background(), server.URL+"/other/", "stale", nil)
+	if err != nil {
+		t.Fatalf("CheckSession error: %v", err)
+	}
+	if status.LoggedIn {
+		t.Fatalf("expected stale session to be reported as logged out")
+	}
+	if status.Recording == nil || status.Recording.Accessible || status.Recording.Reason != "no-access/no-login" {
+		t.Fatalf("unexpected recording access: %+v", status.Recording)
+	}
+}
+
+func TestCheckSessionHostOnly(t *testing.T) {
+	server := newAPIServer(t, "good")
+	defer server.Close()
+
+	dl := New(server.Client())
+	status, err := dl.CheckSession(context.Background(), server.URL, "good", nil)
+	if err != nil {
+		t.Fatalf("CheckSession error: %v", err)
+	}
+	if status.Recording != nil {
+		t.Errorf("expected no recording check for bare host")
+	}
+}
+
+func TestCheckSessionAPIUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	dl := New(server.Client())
+	_, err := dl.CheckSession(context.Background(), server.URL+"/rec/", "good", nil)
+	if !errors.Is(err, ErrAPIUnavailable) {
+		t.Fatalf("expected ErrAPIUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package downloader
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ChapterRule selects which recording events start a chapter.
+type ChapterRule string
+
+const (
+	ChapterDocument ChapterRule = "document" // A share pod shows another document
+	ChapterSlide    ChapterRule = "slide"    // A share pod moves to another page or slide
+	ChapterLayout   ChapterRule = "layout"   // The host switches the room layout
+)
+
+// ChapterRules lists the valid chapter rules.
+var ChapterRules = []ChapterRule{ChapterDocument, ChapterSlide, ChapterLayout}
+
+// DefaultChapterRules are used when Options.ChapterRules is nil.
+var DefaultChapterRules = []ChapterRule{ChapterDocument, ChapterLayout}
+
+// ParseChapterRules parses a comma-separated list of chapter rules such as
+// "document,layout". The empty string means DefaultChapterRules, "all" every
+// rule and "none" no rules, which turns chapters off.
+func ParseChapterRules(list string) ([]ChapterRule, error) {
+	switch strings.ToLower(strings.TrimSpace(list)) {
+	case "":
+		return DefaultChapterRules, nil
+	case "none":
+		return []ChapterRule{}, nil
+	}
+	var rules []ChapterRule
+	for _, name := range strings.Split(list, ",") {
+		rule := ChapterRule(strings.ToLower(strings.TrimSpace(name)))
+		if rule == "all" {
+			return ChapterRules, nil
+		}
+		if !slices.Contains(ChapterRules, rule) {
+			return nil, fmt.Errorf("unknown chapter rule %q (want all, none or any of document, slide, layout)", name)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Chapter is one entry of a recording's chapter menu.
+type Chapter struct {
+	Offset  int64       `json:"start_ms"` // Milliseconds from the start of the recording
+	Time    string      `json:"start_time"`
+	End     int64       `json:"end_ms"`
+	EndTime string      `json:"end_time"`
+	Title   string      `json:"title"`
+	Rule    ChapterRule `json:"rule,omitempty"` // Rule that started it; empty for the opening chapter
+}
+
+// minChapterLength is the shortest chapter in milliseconds. A chapter cut
+// shorter is taken over by the next one, so flicking through slides to find
+// the right one doesn't leave a chapter per slide.
+const minChapterLength = 10000
+
+// layoutField names the layout in layout change messages. Generic fields
+// such as "name" or "title" are left alone: pods use them for documents and
+// their own titles.
+const layoutField = "layoutName"
+
+// extractChapters builds a chapter menu from the share pod slide changes
+// and layout changes in rawDir that match rules. The last chapter ends at
+// end, the recording length in milliseconds, or the last event when end is
+// 0. A chapter from the start of the recording is added when the first one
+// starts later. It returns fs.ErrNotExist when no event matches the rules.
+func extractChapters(rawDir string, rules []ChapterRule, end int64) ([]Chapter, error) {
+	var marks []Chapter
+	if slices.Contains(rules, ChapterDocument) || slices.Contains(rules, ChapterSlide) {
+		slides, _ := extractSlides("", rawDir, nil)
+		var document string
+		for _, s := range slides {
+			title := documentTitle(s.Document)
+			switch {
+			case s.Document != "" && s.Document != document && slices.Contains(rules, ChapterDocument):
+				marks = append(marks, Chapter{Offset: s.Offset, Title: title, Rule: ChapterDocument})
+			case slices.Contains(rules, ChapterSlide):
+				if title == "" {
+					title = "Page"
+				}
+				title = fmt.Sprintf("%s, slide %d", title, s.Page)
+				marks = append(marks, Chapter{Offset: s.Offset, Title: title, Rule: ChapterSlide})
+			}
+			document = cmp.Or(s.Document, document)
+		}
+	}
+
+	var last int64
+	var layout string
+	var errs []error
+	for _, file := range globStreams(rawDir, sessionStreams) {
+		err := readStream(file, func(m StreamMessage) bool {
+			last = max(last, m.Time)
+			if !slices.Contains(rules, ChapterLayout) {
+				return true
+			}
+			var name string
+			m.Walk(func(n *StreamNode) {
+				name = cmp.Or(name, n.Field(layoutField))
+			})
+			if name != "" && name != layout {
+				marks = append(marks, Chapter{Offset: m.Time, Title: name, Rule: ChapterLayout})
+				layout = name
+			}
+			return true
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(marks) == 0 {
+		if len(errs) > 0 {
+			return nil, errs[0]
+		}
+		return nil, fmt.Errorf("no chapter events in the raw recording: %w", fs.ErrNotExist)
+	}
+	slices.SortStableFunc(marks, func(a, b Chapter) int { return cmp.Compare(a.Offset, b.Offset) })
+
+	chapters := []Chapter{{Title: "Start"}}
+	for _, mark := range marks {
+		prev := &chapters[len(chapters)-1]
+		switch {
+		case mark.Title == prev.Title:
+		case mark.Offset-prev.Offset < minChapterLength:
+			prev.Title, prev.Rule = mark.Title, mark.Rule
+			if n := len(chapters); n > 1 && chapters[n-2].Title == prev.Title {
+				// Flicked back to where the previous chapter was
+				chapters = chapters[:n-1]
+			}
+		default:
+			chapters = append(chapters, mark)
+		}
+	}
+	end = cmp.Or(end, last)
+	for i := range chapters {
+		c := &chapters[i]
+		c.End = max(end, c.Offset)
+		if i+1 < len(chapters) {
+			c.End = chapters[i+1].Offset
+		}
+		c.Time, c.EndTime = formatMilliseconds(c.Offset), formatMilliseconds(c.End)
+	}
+	return chapters, nil
+}
+
+// documentTitle names a chapter after a shared document: its file name
+// without the extension.
+func documentTitle(name string) string {
+	if name == "" {
+		return ""
+	}
+	name = path.Base(name)
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// chaptersText renders one "HH:MM:SS.mmm Title" line per chapter, a format
+// MP4Box reads with -chap.
+func chaptersText(chapters []Chapter) []byte {
+	var b strings.Builder
+	for _, c := range chapters {
+		fmt.Fprintf(&b, "%s %s\n", vttTimestamp(c.Offset), oneLine(c.Title))
+	}
+	return []byte(b.String())
+}
+
+// chaptersVTT renders the chapters as a WebVTT chapters track.
+func chaptersVTT(chapters []Chapter) []byte {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	for i, c := range chapters {
+		fmt.Fprintf(&b, "\n%d\n%s --> %s\n%s\n",
+			i+1, vttTimestamp(c.Offset), vttTimestamp(c.End), vttEscape(oneLine(c.Title)))
+	}
+	return []byte(b.String())
+}
+
+// oneLine replaces the line breaks in a document or layout name with spaces,
+// as each chapter takes one line of chapters.txt and one cue line.
+func oneLine(s string) string {
+	return strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(s)
+}
+
+// writeChapters writes chapters.txt and chapters.vtt, records the status of
+// the chapters asset and returns the path of chapters.txt, or "" when none
+// was written. Nil rules mean DefaultChapterRules and an empty list turns
+// chapters off. The recording length from details bounds the last chapter.
+func writeChapters(
+	rootDir, rawDir string,
+	details *RecordingDetails,
+	rules []ChapterRule,
+	assets *assetTracker,
+	logger Logger,
+) string {
+	if rules == nil {
+		rules = DefaultChapterRules
+	} else if len(rules) == 0 {
+		assets.skip(AssetChapters, "chapters turned off")
+		return ""
+	}
+	start := time.Now()
+	var end int64
+	if details != nil {
+		end = int64(details.DurationSeconds * 1000)
+	}
+	chapters, err := extractChapters(rawDir, rules, end)
+	if err != nil {
+		log(logger, "chapter extraction failed", "error", err)
+		assets.fail(AssetChapters, time.Since(start), err)
+		return ""
+	}
+	textPath := filepath.Join(rootDir, "chapters.txt")
+	err = os.WriteFile(textPath, chaptersText(chapters), 0o644)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(rootDir, "chapters.vtt"), chaptersVTT(chapters), 0o644)
+	}
+	if err != nil {
+		log(logger, "chapters write failed", "error", err)
+		assets.fail(AssetChapters, time.Since(start), err)
+		return ""
+	}
+	log(logger, "chapters created", "path", textPath, "chapters", len(chapters))
+	assets.ok(AssetChapters, textPath, time.Since(start))
+	return textPath
+}
@@ -0,0 +1,185 @@
+package downloader
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractChapters(t *testing.T) {
+	rawDir := lectureStreams(t, "lecture12")
+	chapters, err := extractChapters(rawDir, DefaultChapterRules, 0)
+	if err != nil {
+		t.Fatalf("extractChapters error: %v", err)
+	}
+	want := []Chapter{
+		{0, "00:00:00", 90000, "00:01:30", "Lecture 1", ChapterDocument},
+		{90000, "00:01:30", 600000, "00:10:00", "Handout", ChapterDocument},
+		{600000, "00:10:00", 700000, "00:11:40", "Discussion", ChapterLayout},
+	}
+	if !reflect.DeepEqual(chapters, want) {
+		t.Errorf("chapters =\n%+v\nwant\n%+v", chapters, want)
+	}
+
+	// The jump to page 4 of the handout comes too soon after the handout was
+	// shown to be a chapter of its own
+	chapters, err = extractChapters(rawDir, []ChapterRule{ChapterSlide}, 720000)
+	if err != nil {
+		t.Fatalf("extractChapters error: %v", err)
+	}
+	wantText := "00:00:00.000 Lecture 1, slide 1\n00:00:30.000 Lecture 1, slide 3\n00:01:30.000 Handout, slide 4\n"
+	if got := string(chaptersText(chapters)); got != wantText {
+		t.Errorf("chapters.txt =\n%s\nwant\n%s", got, wantText)
+	}
+	wantVTT := "WEBVTT\n\n1\n00:00:00.000 --> 00:00:30.000\nLecture 1, slide 1\n\n" +
+		"2\n00:00:30.000 --> 00:01:30.000\nLecture 1, slide 3\n\n" +
+		"3\n00:01:30.000 --> 00:12:00.000\nHandout, slide 4\n"
+	if got := string(chaptersVTT(chapters)); got != wantVTT {
+		t.Errorf("chapters.vtt =\n%s\nwant\n%s", got, wantVTT)
+	}
+}
+
+// fakeEmbedder records the files it was asked to embed, each subtitle track as
+// "path lang name", and the number of passes.
+type fakeEmbedder struct {
+	subtitles []string
+	chapters  string
+	passes    int
+}
+
+func (f *fakeEmbedder) EmbedSubtitles(_ context.Context, _, vttPath, lang string, _, _ io.Writer) error {
+	f.subtitles = append(f.subtitles, vttPath+" "+lang)
+	f.passes++
+	return nil
+}
+
+func (f *fakeEmbedder) EmbedTracks(
+	_ context.Context,
+	_ string,
+	subtitles []SubtitleTrack,
+	chaptersPath string,
+	_, _ io.Writer,
+) error {
+	for _, s := range subtitles {
+		f.subtitles = append(f.subtitles, strings.TrimSpace(s.Path+" "+s.Lang+" "+s.Name))
+	}
+	f.chapters = chaptersPath
+	f.passes++
+	return nil
+}
+
+func TestWriteAndEmbedChapters(t *testing.T) {
+	rawDir := lectureStreams(t, "lecture12")
+	rootDir := t.TempDir()
+	mp4Path := filepath.Join(rootDir, "recording.mp4")
+	assets := &assetTracker{root: rootDir}
+	embedder := &fakeEmbedder{}
+
+	chaptersPath := writeChapters(rootDir, rawDir, &RecordingDetails{DurationSeconds: 720}, nil, assets, nil)
+	embedTracks(context.Background(), mp4Path, "", "", chaptersPath, embedder, assets, nil)
+	if embedder.chapters != filepath.Join(rootDir, "chapters.txt") {
+		t.Errorf("embedded %q, want chapters.txt", embedder.chapters)
+	}
+	vtt, _ := os.ReadFile(filepath.Join(rootDir, "chapters.vtt"))
+	if want := "00:10:00.000 --> 00:12:00.000\nDiscussion\n"; !strings.Contains(string(vtt), want) {
+		t.Errorf("chapters.vtt missing %q:\n%s", want, vtt)
+	}
+	got := assets.list()
+	if len(got) != 2 || got[0].Name != AssetChapters || got[0].State != StateOK ||
+		got[1].Name != AssetMarkers || got[1].State != StateOK {
+		t.Errorf("assets = %+v", got)
+	}
+
+	assets = &assetTracker{root: rootDir}
+	if path := writeChapters(rootDir, rawDir, nil, []ChapterRule{}, assets, nil); path != "" {
+		t.Errorf("chapters written with no rules: %s", path)
+	}
+	embedTracks(context.Background(), mp4Path, "", "", "", embedder, assets, nil)
+	for _, a := range assets.list() {
+		if a.State != StateSkipped {
+			t.Errorf("asset %s = %s, want skipped", a.Name, a.State)
+		}
+	}
+}
+
+func TestChaptersEscapeTitles(t *testing.T) {
+	chapters := []Chapter{
+		{Offset: 0, End: 60000, Title: "Intro"},
+		{Offset: 60000, End: 120000, Title: "Q&A <live> --> part 2\r\n00:05:00.000 --> 00:06:00.000\nInjected"},
+	}
+	wantTitle := "Q&A <live> --> part 2 00:05:00.000 --> 00:06:00.000 Injected"
+	if got := string(chaptersText(chapters)); got != "00:00:00.000 Intro\n00:01:00.000 "+wantTitle+"\n" {
+		t.Errorf("chapters.txt =\n%s", got)
+	}
+	want := "WEBVTT\n\n1\n00:00:00.000 --> 00:01:00.000\nIntro\n\n2\n00:01:00.000 --> 00:02:00.000\n" +
+		"Q&amp;A &lt;live&gt; --&gt; part 2 00:05:00.000 --&gt; 00:06:00.000 Injected\n"
+	if got := string(chaptersVTT(chapters)); got != want {
+		t.Errorf("chapters.vtt =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// subtitleOnlyEmbedder is a SubtitleEmbedder that is no TrackEmbedder.
+type subtitleOnlyEmbedder struct{ fake *fakeEmbedder }
+
+func (e subtitleOnlyEmbedder) EmbedSubtitles(
+	ctx context.Context,
+	mp4Path, vttPath, lang string,
+	stdout, stderr io.Writer,
+) error {
+	return e.fake.EmbedSubtitles(ctx, mp4Path, vttPath, lang, stdout, stderr)
+}
+
+func TestEmbedTracks(t *testing.T) {
+	ctx := context.Background()
+	embedder := &fakeEmbedder{}
+	assets := &assetTracker{root: "/rec"}
+	embedTracks(ctx, "/rec/recording.mp4", "captions.vtt", "chat.vtt", "chapters.txt", embedder, assets, nil)
+	if want := []string{"captions.vtt en", "chat.vtt und Chat"}; embedder.passes != 1 ||
+		!reflect.DeepEqual(embedder.subtitles, want) || embedder.chapters != "chapters.txt" {
+		t.Errorf("embedded %v and %q in %d passes, want %v and chapters.txt in 1",
+			embedder.subtitles, embedder.chapters, embedder.passes, want)
+	}
+	for _, a := range assets.list() {
+		if a.State != StateOK {
+			t.Errorf("asset %s = %s, want ok", a.Name, a.State)
+		}
+	}
+
+	// Other embedders add the subtitle tracks one by one
+	embedder = &fakeEmbedder{}
+	assets = &assetTracker{root: "/rec"}
+	embedTracks(ctx, "/rec/recording.mp4", "captions.vtt", "chat.vtt", "chapters.txt",
+		subtitleOnlyEmbedder{embedder}, assets, nil)
+	if want := []string{"captions.vtt en", "chat.vtt und"}; embedder.passes != 2 ||
+		!reflect.DeepEqual(embedder.subtitles, want) || embedder.chapters != "" {
+		t.Errorf("embedded %v and %q in %d passes", embedder.subtitles, embedder.chapters, embedder.passes)
+	}
+	got := assets.list()
+	if len(got) != 3 || got[2].Name != AssetMarkers || got[2].State != StateSkipped {
+		t.Errorf("assets = %+v", got)
+	}
+}
+
+func TestParseChapterRules(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []ChapterRule
+		wantErr bool
+	}{
+		{"", DefaultChapterRules, false},
+		{"Slide, layout", []ChapterRule{ChapterSlide, ChapterLayout}, false},
+		{"all", ChapterRules, false},
+		{"none", []ChapterRule{}, false},
+		{"document,poll", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseChapterRules(tt.in)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseChapterRules(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package downloader
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// ChatFormat is an output format for the session chat.
+type ChatFormat string
+
+const (
+	ChatText     ChatFormat = "txt"  // chat_log.txt, one "[HH:MM:SS] Name: text" line per message
+	ChatJSON     ChatFormat = "json" // chat.json with every field of ChatMessage
+	ChatCSV      ChatFormat = "csv"  // chat.csv with a header row
+	ChatMarkdown ChatFormat = "md"   // chat.md
+	ChatHTML     ChatFormat = "html" // chat.html, a standalone page
+)
+
+// ChatFormats lists the valid chat formats in the order they are written.
+var ChatFormats = []ChatFormat{ChatText, ChatJSON, ChatCSV, ChatMarkdown, ChatHTML}
+
+// chatFiles are the file names each chat format is written to.
+var chatFiles = map[ChatFormat]string{
+	ChatText:     "chat_log.txt",
+	ChatJSON:     "chat.json",
+	ChatCSV:      "chat.csv",
+	ChatMarkdown: "chat.md",
+	ChatHTML:     "chat.html",
+}
+
+// ParseChatFormats parses a comma-separated list of chat formats such as
+// "txt,json". The empty string means ChatText only and "all" every format.
+func ParseChatFormats(list string) ([]ChatFormat, error) {
+	if strings.TrimSpace(list) == "" {
+		return []ChatFormat{ChatText}, nil
+	}
+	var formats []ChatFormat
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "all" {
+			return ChatFormats, nil
+		}
+		if _, ok := chatFiles[ChatFormat(name)]; !ok {
+			return nil, fmt.Errorf("unknown chat format %q (want all or any of txt, json, csv, md, html)", name)
+		}
+		formats = append(formats, ChatFormat(name))
+	}
+	return formats, nil
+}
+
+// ChatAudience is who could read a chat message during the session.
+type ChatAudience string
+
+const (
+	AudiencePublic  ChatAudience = "public"  // Everyone in the room
+	AudiencePrivate ChatAudience = "private" // One recipient
+	AudienceHosts   ChatAudience = "hosts"   // Hosts and presenters only
+)
+
+// ChatMessage is one message from the session chat.
+type ChatMessage struct {
+	Offset    int64        `json:"offset_ms"` // Milliseconds from the start of the recording
+	Time      string       `json:"time"`      // Offset as HH:MM:SS
+	Sender    string       `json:"sender,omitempty"`
+	Recipient string       `json:"recipient,omitempty"` // For private messages
+	Audience  ChatAudience `json:"audience"`
+	Text      string       `json:"text"`           // Plain text, with formatting and entities decoded
+	HTML      string       `json:"html,omitempty"` // The message as recorded, when it had formatting
+}
+
+// recipientField names the recipient of a private message; hostField marks
+// messages sent to the hosts and presenters only. Chat entries name their
+// sender in the generic "name" field, so no other generic field is read.
+const (
+	recipientField = "toName"
+	hostField      = "toRole"
+)
+
+// extractChatMessages reads the chat from transcriptstream.xml, with anonymous
+// sender and recipient IDs replaced through userMapping.
+func extractChatMessages(rawDir string, userMapping map[string]string) ([]ChatMessage, error) {
+	transcriptPath := filepath.Join(rawDir, "transcriptstream.xml")
+	if _, err := os.Stat(transcriptPath); err != nil {
+		return nil, err
+	}
+	realName := func(name string) string {
+		if mapped, ok := userMapping[name]; ok {
+			return mapped
+		}
+		return name
+	}
+
+	var messages []ChatMessage
+	err := readStream(transcriptPath, func(m StreamMessage) bool {
+		m.Walk(func(n *StreamNode) {
+			label := n.Field("label")
+			if n.Field("iconType") != "chat" || label == "" {
+				return
+			}
+			msg := ChatMessage{Offset: m.Time, Sender: realName(n.Field("name")), Audience: AudiencePublic}
+			if t, err := strconv.ParseInt(n.Field("time"), 10, 64); err == nil {
+				msg.Offset = t
+			}
+			msg.Time = formatMilliseconds(msg.Offset)
+			msg.Text = htmlToText(label)
+			if msg.Text != label {
+				msg.HTML = label
+			}
+			msg.Recipient, msg.Audience = chatAudience(n)
+			msg.Recipient = realName(msg.Recipient)
+			messages = append(messages, msg)
+		})
+		return true
+	})
+	return messages, err
+}
+
+// chatAudience works out who a chat message was sent to.
+func chatAudience(n *StreamNode) (string, ChatAudience) {
+	if v := strings.ToLower(n.Field(hostField)); strings.Contains(v, "host") || strings.Contains(v, "presenter") {
+		return "", AudienceHosts
+	}
+	recipient := n.Field(recipientField)
+	switch lower := strings.ToLower(recipient); {
+	case recipient == "" || lower == "everyone" || lower == "all":
+	case strings.HasPrefix(lower, "host") || strings.HasPrefix(lower, "presenter"):
+		return "", AudienceHosts
+	default:
+		return recipient, AudiencePrivate
+	}
+	if private, _ := strconv.ParseBool(n.Field("isPrivate")); private {
+		return "", AudiencePrivate
+	}
+	return "", AudiencePublic
+}
+
+// htmlToText strips the formatting Connect records in chat labels and decodes
+// entities. Line breaks and paragraph ends become newlines.
+func htmlToText(s string) string {
+	if !strings.ContainsAny(s, "<&") {
+		return s
+	}
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return strings.TrimSpace(b.String())
+		case html.TextToken:
+			b.Write(z.Text())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if name, _ := z.TagName(); string(name) == "br" {
+				b.WriteByte('\n')
+			}
+		case html.EndTagToken:
+			switch name, _ := z.TagName(); string(name) {
+			case "p", "div", "li":
+				b.WriteByte('\n')
+			}
+		default:
+			// Comments and doctypes carry no message text
+		}
+	}
+}
+
+// describe returns how the sender appears in text formats, e.g.
+// "Jane Doe → James Lewis (private)".
+func (m ChatMessage) describe() string {
+	switch m.Audience {
+	case AudiencePrivate:
+		if m.Recipient != "" {
+			return fmt.Sprintf("%s → %s (private)", m.Sender, m.Recipient)
+		}
+		return m.Sender + " (private)"
+	case AudienceHosts:
+		return m.Sender + " → hosts"
+	}
+	return m.Sender
+}
+
+// extractChatLog parses transcriptstream.xml and creates a readable chat log.
+func extractChatLog(rawDir, outputPath string) error {
+	messages, err := extractChatMessages(rawDir, extractUserMapping(rawDir))
+	if err != nil && messages == nil {
+		return err
+	}
+	if werr := writeChatFile(outputPath, ChatText, "", messages); werr != nil {
+		return werr
+	}
+	return err
+}
+
+// writeChatOutputs writes messages in every format into rootDir and returns
+// the path of the first one. err is the error reading them, if any; messages
+// read before it are still written.
+func writeChatOutputs(rootDir, title string, formats []ChatFormat, messages []ChatMessage, err error) (string, error) {
+	if err != nil && messages == nil {
+		return "", err
+	}
+	if len(formats) == 0 {
+		formats = []ChatFormat{ChatText}
+	}
+	var first string
+	var errs []error
+	for _, format := range formats {
+		path := filepath.Join(rootDir, chatFiles[format])
+		if werr := writeChatFile(path, format, title, messages); werr != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", chatFiles[format], werr))
+			continue
+		}
+		if first == "" {
+			first = path
+		}
+	}
+	if first == "" {
+		return "", errors.Join(append(errs, err)...)
+	}
+	return first, nil
+}
+
+// writeChatFile writes messages to path in the given format.
+func writeChatFile(path string, format ChatFormat, title string, messages []ChatMessage) error {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case ChatText:
+		writeChatText(&buf, messages)
+	case ChatJSON:
+		err = writeChatJSON(&buf, messages)
+	case ChatCSV:
+		err = writeChatCSV(&buf, messages)
+	case ChatMarkdown:
+		writeChatMarkdown(&buf, title, messages)
+	case ChatHTML:
+		writeChatHTML(&buf, title, messages)
+	default:
+		err = fmt.Errorf("unknown chat format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+func writeChatText(w io.Writer, messages []ChatMessage) {
+	fmt.Fprintf(w, "CHAT LOG\n")
+	fmt.Fprintf(w, "========\n\n")
+	for _, m := range messages {
+		if sender := m.describe(); sender != "" {
+			fmt.Fprintf(w, "[%s] %s: %s\n", m.Time, sender, m.Text)
+		} else {
+			fmt.Fprintf(w, "[%s] %s\n", m.Time, m.Text)
+		}
+	}
+}
+
+func writeChatJSON(w io.Writer, messages []ChatMessage) error {
+	if messages == nil {
+		messages = []ChatMessage{}
+	}
+	data, err := json.MarshalIndent(messages, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func writeChatCSV(w io.Writer, messages []ChatMessage) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"offset_ms", "time", "sender", "recipient", "audience", "text"})
+	for _, m := range messages {
+		cw.Write([]string{
+			strconv.FormatInt(m.Offset, 10), m.Time, m.Sender, m.Recipient, string(m.Audience), m.Text,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeChatMarkdown(w io.Writer, title string, messages []ChatMessage) {
+	fmt.Fprintf(w, "# %s\n\n", cmp.Or(title, "Chat log"))
+	for _, m := range messages {
+		// Continuation lines are indented to stay inside the list item
+		text := strings.ReplaceAll(m.Text, "\n", "  \n  ")
+		if sender := m.describe(); sender != "" {
+			fmt.Fprintf(w, "- **[%s] %s:** %s\n", m.Time, sender, text)
+		} else {
+			fmt.Fprintf(w, "- **[%s]** %s\n", m.Time, text)
+		}
+	}
+}
+
+func writeChatHTML(w io.Writer, title string, messages []ChatMessage) {
+	title = html.EscapeString(cmp.Or(title, "Chat log"))
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; }
+.msg { margin: 0.4em 0; }
+.time { color: #888; font-family: monospace; }
+.sender { font-weight: bold; }
+.private, .hosts { background: #fff4d6; }
+.text { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>%s</h1>
+`, title, title)
+	for _, m := range messages {
+		fmt.Fprintf(w, `<div class="msg %s"><span class="time">[%s]</span> `, m.Audience, m.Time)
+		if sender := m.describe(); sender != "" {
+			fmt.Fprintf(w, `<span class="sender">%s:</span> `, html.EscapeString(sender))
+		}
+		fmt.Fprintf(w, "<span class=\"text\">%s</span></div>\n", html.EscapeString(m.Text))
+	}
+	fmt.Fprintf(w, "</body>\n</html>\n")
+}
+
+// DefaultChatCueDuration is how long each chat message is shown in chat.vtt.
+const DefaultChatCueDuration = 5 * time.Second
+
+// chatVTT renders the public chat messages as a WebVTT subtitle track, each
+// shown for cue after it was sent and prefixed with the sender's name.
+// Private and host-only messages are left out, as players show the track to
+// anyone watching the recording.
+func chatVTT(messages []ChatMessage, cue time.Duration) []byte {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	n := 0
+	for _, m := range messages {
+		if m.Audience != AudiencePublic || strings.TrimSpace(m.Text) == "" {
+			continue
+		}
+		n++
+		// A blank line would end the cue early
+		text := strings.ReplaceAll(strings.TrimSpace(m.Text), "\n\n", "\n")
+		if m.Sender != "" {
+			text = m.Sender + ": " + text
+		}
+		fmt.Fprintf(&b, "\n%d\n%s --> %s\n%s\n",
+			n, vttTimestamp(m.Offset), vttTimestamp(m.Offset+cue.Milliseconds()), vttEscape(text))
+	}
+	return []byte(b.String())
+}
+
+// vttEscape escapes the characters WebVTT cue text reserves for markup.
+func vttEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// writeChatSubtitles writes the public messages as chat.vtt, records the
+// status of the chat subtitles asset and returns the path for embedding.
+// When none is written, nothing is embedded either, which it records as the
+// chat track asset, and it returns "". err is the error reading messages, if
+// any. Cue is how long each message is shown (0 = DefaultChatCueDuration,
+// <0 = no chat.vtt).
+func writeChatSubtitles(
+	rootDir string,
+	messages []ChatMessage,
+	err error,
+	cue time.Duration,
+	assets *assetTracker,
+	logger Logger,
+) string {
+	if cue < 0 {
+		assets.skip(AssetChatVTT, "chat subtitles turned off")
+		assets.skip(AssetChatTrack, "chat subtitles turned off")
+		return ""
+	}
+	cue = cmp.Or(cue, DefaultChatCueDuration)
+	start := time.Now()
+	if err == nil && !slices.ContainsFunc(messages, func(m ChatMessage) bool { return m.Audience == AudiencePublic }) {
+		err = fmt.Errorf("no public chat messages: %w", fs.ErrNotExist)
+	}
+	path := filepath.Join(rootDir, "chat.vtt")
+	if err == nil {
+		err = os.WriteFile(path, chatVTT(messages, cue), 0o644)
+	}
+	if err != nil {
+		log(logger, "chat subtitles failed", "error", err)
+		assets.fail(AssetChatVTT, time.Since(start), err)
+		assets.skip(AssetChatTrack, "no chat subtitles")
+		return ""
+	}
+	log(logger, "chat subtitles created", "path", path)
+	assets.ok(AssetChatVTT, path, time.Since(start))
+	return path
+}
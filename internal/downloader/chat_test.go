@@ -0,0 +1,176 @@
+package downloader
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const chatStream = `<root>
+  <Message time="1000" type="cycleEntry">
+    <Method><![CDATA[cycleEntry]]></Method>
+    <Object>
+      <iconType><![CDATA[chat]]></iconType>
+      <label><![CDATA[<b>Welcome</b> &amp; hello<br>everyone]]></label>
+      <name><![CDATA[Jane Doe]]></name>
+      <to><![CDATA[Week 1 group]]></to>
+    </Object>
+  </Message>
+  <Message time="2500" type="cycleEntry">
+    <Method><![CDATA[cycleEntry]]></Method>
+    <Object>
+      <iconType><![CDATA[chat]]></iconType>
+      <label><![CDATA[Can you repeat that?]]></label>
+      <name><![CDATA[User2]]></name>
+      <toName><![CDATA[User1]]></toName>
+      <time><![CDATA[3000]]></time>
+    </Object>
+  </Message>
+  <Message time="4000" type="cycleEntry">
+    <Method><![CDATA[cycleEntry]]></Method>
+    <Object>
+      <iconType><![CDATA[chat]]></iconType>
+      <label><![CDATA[Running five minutes late, "sorry"]]></label>
+      <name><![CDATA[James Lewis]]></name>
+      <toRole><![CDATA[Hosts and Presenters]]></toRole>
+    </Object>
+  </Message>
+</root>`
+
+func TestExtractChatMessages(t *testing.T) {
+	rawDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(rawDir, "transcriptstream.xml"), []byte(chatStream), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mapping := map[string]string{"User1": "Jane Doe", "User2": "James Lewis"}
+
+	messages, err := extractChatMessages(rawDir, mapping)
+	if err != nil {
+		t.Fatalf("extractChatMessages error: %v", err)
+	}
+	want := []ChatMessage{
+		{
+			Offset: 1000, Time: "00:00:01", Sender: "Jane Doe", Audience: AudiencePublic,
+			Text: "Welcome & hello\neveryone", HTML: "<b>Welcome</b> &amp; hello<br>everyone",
+		},
+		{
+			Offset: 3000, Time: "00:00:03", Sender: "James Lewis", Recipient: "Jane Doe",
+			Audience: AudiencePrivate, Text: "Can you repeat that?",
+		},
+		{
+			Offset: 4000, Time: "00:00:04", Sender: "James Lewis", Audience: AudienceHosts,
+			Text: `Running five minutes late, "sorry"`,
+		},
+	}
+	if !reflect.DeepEqual(messages, want) {
+		t.Fatalf("messages = %+v\nwant %+v", messages, want)
+	}
+
+	rootDir := t.TempDir()
+	first, err := writeChatOutputs(rootDir, "SE101 <Week 1>", ChatFormats, messages, nil)
+	if err != nil || first != filepath.Join(rootDir, "chat_log.txt") {
+		t.Fatalf("writeChatOutputs = %q, %v", first, err)
+	}
+
+	text, _ := os.ReadFile(filepath.Join(rootDir, "chat_log.txt"))
+	for _, line := range []string{
+		"[00:00:01] Jane Doe: Welcome & hello\neveryone\n",
+		"[00:00:03] James Lewis → Jane Doe (private): Can you repeat that?\n",
+		"[00:00:04] James Lewis → hosts: Running",
+	} {
+		if !strings.Contains(string(text), line) {
+			t.Errorf("chat_log.txt missing %q:\n%s", line, text)
+		}
+	}
+
+	var decoded []ChatMessage
+	data, _ := os.ReadFile(filepath.Join(rootDir, "chat.json"))
+	if err := json.Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(decoded, want) {
+		t.Errorf("chat.json = %s, err = %v", data, err)
+	}
+
+	f, _ := os.Open(filepath.Join(rootDir, "chat.csv"))
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil || len(records) != 4 || records[2][3] != "Jane Doe" || records[3][4] != "hosts" {
+		t.Errorf("chat.csv = %v, err = %v", records, err)
+	}
+
+	md, _ := os.ReadFile(filepath.Join(rootDir, "chat.md"))
+	if !strings.Contains(string(md), "- **[00:00:01] Jane Doe:** Welcome & hello  \n  everyone\n") {
+		t.Errorf("unexpected chat.md:\n%s", md)
+	}
+
+	page, _ := os.ReadFile(filepath.Join(rootDir, "chat.html"))
+	for _, s := range []string{"<title>SE101 &lt;Week 1&gt;</title>", "Welcome &amp; hello", `class="msg private"`} {
+		if !strings.Contains(string(page), s) {
+			t.Errorf("chat.html missing %q:\n%s", s, page)
+		}
+	}
+}
+
+func TestParseChatFormats(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []ChatFormat
+		wantErr bool
+	}{
+		{"", []ChatFormat{ChatText}, false},
+		{"json, CSV", []ChatFormat{ChatJSON, ChatCSV}, false},
+		{"all", ChatFormats, false},
+		{"txt,pdf", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseChatFormats(tt.in)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseChatFormats(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestWriteChatSubtitles(t *testing.T) {
+	rawDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(rawDir, "transcriptstream.xml"), []byte(chatStream), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rootDir := t.TempDir()
+	assets := &assetTracker{root: rootDir}
+	embedder := &fakeEmbedder{}
+
+	messages, err := extractChatMessages(rawDir, nil)
+	vttPath := writeChatSubtitles(rootDir, messages, err, 3*time.Second, assets, nil)
+	if vttPath != filepath.Join(rootDir, "chat.vtt") {
+		t.Fatalf("writeChatSubtitles = %q", vttPath)
+	}
+	// Only the public message is shown; private and host-only ones are left out
+	data, _ := os.ReadFile(vttPath)
+	want := "WEBVTT\n\n1\n00:00:01.000 --> 00:00:04.000\nJane Doe: Welcome &amp; hello\neveryone\n"
+	if string(data) != want {
+		t.Errorf("chat.vtt =\n%s\nwant\n%s", data, want)
+	}
+
+	embedTracks(context.Background(), filepath.Join(rootDir, "recording.mp4"), "", vttPath, "", embedder, assets, nil)
+	if !reflect.DeepEqual(embedder.subtitles, []string{vttPath + " und Chat"}) {
+		t.Errorf("embedded %v, want chat.vtt named Chat", embedder.subtitles)
+	}
+	// No chapters were written, so only the markers are skipped
+	if got := assets.list(); len(got) != 3 || got[0].Name != AssetChatVTT || got[0].State != StateOK ||
+		got[0].Path != "chat.vtt" || got[1].Name != AssetChatTrack || got[1].State != StateOK ||
+		got[2].Name != AssetMarkers || got[2].State != StateSkipped {
+		t.Errorf("assets = %+v", got)
+	}
+
+	assets = &assetTracker{root: rootDir}
+	if path := writeChatSubtitles(rootDir, messages, nil, -1, assets, nil); path != "" {
+		t.Errorf("chat.vtt written when turned off: %s", path)
+	}
+	if got := assets.list(); len(got) != 2 || got[0].State != StateSkipped || got[1].State != StateSkipped {
+		t.Errorf("assets = %+v", got)
+	}
+}
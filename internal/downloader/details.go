@@ -0,0 +1,196 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RecordingDetails holds authoritative recording information from the Connect API.
+// Every field is best-effort: servers differ in what sco-info exposes.
+type RecordingDetails struct {
+	ScoID           string    `json:"sco_id"`
+	Name            string    `json:"name,omitempty"`
+	Description     string    `json:"description,omitempty"`
+	URLPath         string    `json:"url_path,omitempty"`
+	DateBegin       time.Time `json:"date_begin,omitzero"`
+	DateEnd         time.Time `json:"date_end,omitzero"`
+	DateCreated     time.Time `json:"date_created,omitzero"`
+	DurationSeconds float64   `json:"duration_seconds,omitempty"`
+	MeetingScoID    string    `json:"meeting_sco_id,omitempty"`
+	MeetingName     string    `json:"meeting_name,omitempty"`
+	FolderPath      []string  `json:"folder_path,omitempty"`
+	Creator         string    `json:"creator,omitempty"`
+}
+
+// apiSco is a <sco> element as returned by sco-by-url, sco-info and sco-nav.
+type apiSco struct {
+	ScoID            string `xml:"sco-id,attr"`
+	FolderID         string `xml:"folder-id,attr"`
+	SourceScoID      string `xml:"source-sco-id,attr"`
+	Type             string `xml:"type,attr"`
+	Icon             string `xml:"icon,attr"`
+	Depth            int    `xml:"depth,attr"`
+	Name             string `xml:"name"`
+	Description      string `xml:"description"`
+	URLPath          string `xml:"url-path"`
+	DateBegin        string `xml:"date-begin"`
+	DateEnd          string `xml:"date-end"`
+	DateCreated      string `xml:"date-created"`
+	Duration         string `xml:"duration"`
+	OwnerPrincipalID string `xml:"owner-principal-id"`
+}
+
+type apiScoNav struct {
+	Scos []apiSco `xml:"sco"`
+}
+
+type apiPrincipal struct {
+	PrincipalID string `xml:"principal-id,attr"`
+	Name        string `xml:"name"`
+}
+
+// connectTimeLayout is the timestamp format used throughout the XML API.
+const connectTimeLayout = "2006-01-02T15:04:05.000-07:00"
+
+// parseConnectTime parses a Connect API timestamp, returning the zero time on failure.
+func parseConnectTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+	for _, layout := range []string{connectTimeLayout, time.RFC3339Nano, time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// parseConnectDuration parses durations reported either as seconds or as HH:MM:SS(.fff).
+func parseConnectDuration(s string) float64 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return secs
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0
+	}
+	var total float64
+	for _, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return 0
+		}
+		total = total*60 + v
+	}
+	return total
+}
+
+// fetchRecordingDetails queries sco-by-url, sco-info, sco-nav and principal-info
+// to describe the recording. Only the sco-by-url lookup is required; the rest
+// are filled in when the server allows it.
+func (d *Downloader) fetchRecordingDetails(
+	ctx context.Context,
+	info recordingInfo,
+	session string,
+	logger Logger,
+) (*RecordingDetails, error) {
+	origin, _, err := ParseTarget(info.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{"url-path": {"/" + info.ID + "/"}}
+	res, err := d.callAPIOK(ctx, origin, session, "sco-by-url", params, logger)
+	if err != nil {
+		return nil, err
+	}
+	if res.Sco == nil {
+		return nil, errors.New("sco-by-url: no sco in response")
+	}
+	scoID := res.Sco.ScoID
+
+	detail := *res.Sco
+	infoRes, err := d.callAPIOK(ctx, origin, session, "sco-info", url.Values{"sco-id": {scoID}}, logger)
+	if err == nil && infoRes.Sco != nil {
+		detail = *infoRes.Sco
+	} else {
+		log(logger, "sco-info unavailable, using sco-by-url details", "error", err)
+	}
+
+	details := &RecordingDetails{
+		ScoID:           scoID,
+		Name:            strings.TrimSpace(detail.Name),
+		Description:     strings.TrimSpace(detail.Description),
+		URLPath:         detail.URLPath,
+		DateBegin:       parseConnectTime(detail.DateBegin),
+		DateEnd:         parseConnectTime(detail.DateEnd),
+		DateCreated:     parseConnectTime(detail.DateCreated),
+		DurationSeconds: parseConnectDuration(detail.Duration),
+	}
+	if details.DurationSeconds == 0 && !details.DateBegin.IsZero() && details.DateEnd.After(details.DateBegin) {
+		details.DurationSeconds = details.DateEnd.Sub(details.DateBegin).Seconds()
+	}
+
+	// Recordings live inside their meeting's folder
+	if detail.FolderID != "" {
+		params := url.Values{"sco-id": {detail.FolderID}}
+		parentRes, err := d.callAPIOK(ctx, origin, session, "sco-info", params, logger)
+		if err == nil && parentRes.Sco != nil && parentRes.Sco.Type == "meeting" {
+			details.MeetingScoID = parentRes.Sco.ScoID
+			details.MeetingName = strings.TrimSpace(parentRes.Sco.Name)
+		}
+	}
+
+	navRes, err := d.callAPIOK(ctx, origin, session, "sco-nav", url.Values{"sco-id": {scoID}}, logger)
+	if err == nil && navRes.ScoNav != nil {
+		// Ancestors have negative depth; the most negative one is the root
+		ancestors := make([]apiSco, 0, len(navRes.ScoNav.Scos))
+		for _, s := range navRes.ScoNav.Scos {
+			if s.Depth < 0 {
+				ancestors = append(ancestors, s)
+			}
+		}
+		sort.Slice(ancestors, func(i, j int) bool { return ancestors[i].Depth < ancestors[j].Depth })
+		for _, s := range ancestors {
+			details.FolderPath = append(details.FolderPath, strings.TrimSpace(s.Name))
+		}
+	}
+
+	if detail.OwnerPrincipalID != "" {
+		params := url.Values{"principal-id": {detail.OwnerPrincipalID}}
+		principalRes, err := d.callAPIOK(ctx, origin, session, "principal-info", params, logger)
+		if err == nil && principalRes.Principal != nil {
+			details.Creator = strings.TrimSpace(principalRes.Principal.Name)
+		}
+	}
+
+	return details, nil
+}
+
+// callAPIOK calls an action and fails unless the status code is "ok".
+func (d *Downloader) callAPIOK(
+	ctx context.Context,
+	origin, session, action string,
+	params url.Values,
+	logger Logger,
+) (apiResults, error) {
+	res, _, err := d.callAPI(ctx, origin, session, action, params, logger)
+	if err != nil {
+		return apiResults{}, err
+	}
+	if res.Status.Code != "ok" {
+		return apiResults{}, fmt.Errorf("%s: %s %s", action, res.Status.Code, res.Status.Subcode)
+	}
+	return res, nil
+}
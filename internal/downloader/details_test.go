@@ -0,0 +1,136 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// apiDetailsHandler answers the API calls made by fetchRecordingDetails.
+func apiDetailsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	switch q.Get("action") {
+	case "sco-by-url":
+		fmt.Fprint(w, `<results><status code="ok"/><sco sco-id="1001" folder-id="900" type="content">`+
+			`<name>Lecture 1</name><url-path>/rec/</url-path></sco></results>`)
+	case "sco-info":
+		if q.Get("sco-id") == "900" {
+			fmt.Fprint(w, `<results><status code="ok"/><sco sco-id="900" folder-id="10" type="meeting">`+
+				`<name>SE101 Lectures</name></sco></results>`)
+			return
+		}
+		fmt.Fprint(w, `<results><status code="ok"/><sco sco-id="1001" folder-id="900" type="content" icon="archive">`+
+			`<name>Lecture 1</name><description>Intro to testing</description><url-path>/rec/</url-path>`+
+			`<date-begin>2025-03-05T10:00:00.000+00:00</date-begin>`+
+			`<date-end>2025-03-05T11:30:00.000+00:00</date-end>`+
+			`<owner-principal-id>77</owner-principal-id></sco></results>`)
+	case "sco-nav":
+		fmt.Fprint(w, `<results><status code="ok"/><sco-nav>`+
+			`<sco sco-id="1001" depth="0"><name>Lecture 1</name></sco>`+
+			`<sco sco-id="900" depth="-1"><name>SE101 Lectures</name></sco>`+
+			`<sco sco-id="10" depth="-2"><name>Shared Meetings</name></sco>`+
+			`</sco-nav></results>`)
+	case "principal-info":
+		fmt.Fprint(w, `<results><status code="ok"/>`+
+			`<principal principal-id="77"><name>Jane Doe</name></principal></results>`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestFetchRecordingDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(apiDetailsHandler))
+	defer server.Close()
+
+	info, err := parseRecordingURL(server.URL + "/rec/")
+	if err != nil {
+		t.Fatalf("parseRecordingURL error: %v", err)
+	}
+	dl := New(server.Client())
+	details, err := dl.fetchRecordingDetails(context.Background(), info, "", nil)
+	if err != nil {
+		t.Fatalf("fetchRecordingDetails error: %v", err)
+	}
+
+	if details.ScoID != "1001" || details.Description != "Intro to testing" {
+		t.Errorf("unexpected details: %+v", details)
+	}
+	if details.DurationSeconds != 5400 {
+		t.Errorf("DurationSeconds = %v, want 5400", details.DurationSeconds)
+	}
+	if details.MeetingName != "SE101 Lectures" {
+		t.Errorf("MeetingName = %q, want %q", details.MeetingName, "SE101 Lectures")
+	}
+	if len(details.FolderPath) != 2 || details.FolderPath[0] != "Shared Meetings" {
+		t.Errorf("FolderPath = %v, want root first", details.FolderPath)
+	}
+	if details.Creator != "Jane Doe" {
+		t.Errorf("Creator = %q, want %q", details.Creator, "Jane Doe")
+	}
+}
+
+func TestParseConnectDuration(t *testing.T) {
+	tests := map[string]float64{
+		"":             0,
+		"90":           90,
+		"01:02:03.500": 3723.5,
+		"bogus":        0,
+	}
+	for in, want := range tests {
+		if got := parseConnectDuration(in); got != want {
+			t.Errorf("parseConnectDuration(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestMetadataIncludesRecordingDetails(t *testing.T) {
+	zipContent := createZip(t, map[string]string{"a.txt": "hello"})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/xml":
+			apiDetailsHandler(w, r)
+		case "/rec/":
+			w.Write([]byte("<title>Lecture 1</title>"))
+		case "/rec/output/rec.zip":
+			w.Write(zipContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tmp := t.TempDir()
+	res, err := New(server.Client()).Download(context.Background(), server.URL+"/rec/", Options{OutputDir: tmp})
+	if err != nil {
+		t.Fatalf("download error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(res.RootDir, "metadata.json"))
+	if err != nil {
+		t.Fatalf("read metadata: %v", err)
+	}
+	var m metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("decode metadata: %v", err)
+	}
+	if m.SchemaVersion != metadataSchemaVersion {
+		t.Errorf("schema_version = %d, want %d", m.SchemaVersion, metadataSchemaVersion)
+	}
+	if m.Recording == nil || m.Recording.ScoID != "1001" || m.Recording.MeetingName != "SE101 Lectures" {
+		t.Errorf("unexpected recording details: %+v", m.Recording)
+	}
+	var sawZip bool
+	for _, f := range m.Files {
+		if f.Path == "raw.zip" && f.Size == int64(len(zipContent)) {
+			sawZip = true
+		}
+	}
+	if !sawZip {
+		t.Errorf("expected raw.zip with size in files, got %+v", m.Files)
+	}
+}
@@ -4,8 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"os"
-	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -48,71 +47,40 @@ type documentEntry struct {
 // Only ftfileshare*.xml contains reliable document URLs. The playbackFileName is
 // preferred as it's the persistent URL for archived recordings, while downloadUrl
 // may point to the original upload location which may no longer exist.
-func extractDocumentLinks(rawDir, hostname string) []DocumentInfo {
+// Origin is the scheme and host of the recording, e.g. "https://acme.adobeconnect.com".
+func extractDocumentLinks(rawDir, origin string) []DocumentInfo {
 	// Map by filename to deduplicate and prefer playbackFileName entries
 	docsByName := make(map[string]*documentEntry)
 
 	// Only parse ftfileshare*.xml - these contain the file share pod data with reliable URLs
-	files, _ := filepath.Glob(filepath.Join(rawDir, "ftfileshare*.xml"))
-
-	// Match newValue blocks containing downloadUrl or playbackFileName
-	newValueRe := regexp.MustCompile(`(?s)<newValue>(.*?)</newValue>`)
-	// playbackFileName is the persistent playback URL (preferred)
-	playbackRe := regexp.MustCompile(`<playbackFileName><!\[CDATA\[([^\]]+)\]\]></playbackFileName>`)
-	// downloadUrl is the original upload URL (fallback, may return 404)
-	downloadRe := regexp.MustCompile(`<downloadUrl><!\[CDATA\[([^\]]+)\]\]></downloadUrl>`)
-	nameRe := regexp.MustCompile(`<name><!\[CDATA\[([^\]]+)\]\]></name>`)
-	sizeRe := regexp.MustCompile(`<size><!\[CDATA\[(\d+)\]\]></size>`)
-
-	for _, file := range files {
-		data, err := os.ReadFile(file)
-		if err != nil {
-			continue
-		}
-		content := string(data)
-
-		// Find all newValue blocks
-		newValueMatches := newValueRe.FindAllStringSubmatch(content, -1)
-		for _, nvMatch := range newValueMatches {
-			if len(nvMatch) < 2 {
-				continue
+	_ = readStreams(rawDir, "ftfileshare*.xml", func(m StreamMessage) bool {
+		m.Walk(func(n *StreamNode) {
+			if n.Name != "newValue" || len(n.Children) == 0 {
+				return
 			}
-			block := nvMatch[1]
-
-			// Extract playbackFileName and downloadUrl from this block
-			playbackMatch := playbackRe.FindStringSubmatch(block)
-			dlMatch := downloadRe.FindStringSubmatch(block)
-			nameMatch := nameRe.FindStringSubmatch(block)
-			sizeMatch := sizeRe.FindStringSubmatch(block)
-
-			// Need at least a name
-			if len(nameMatch) < 2 {
-				continue
+			name := findText(n, "name")
+			if name == "" {
+				return
 			}
-			name := nameMatch[1]
 
-			// Determine URL: prefer playbackFileName over downloadUrl
+			// Determine URL: prefer playbackFileName (persistent playback URL) over
+			// downloadUrl (original upload URL, may return 404)
 			var downloadURL string
 			hasPlayback := false
-			if len(playbackMatch) >= 2 && strings.Contains(playbackMatch[1], "/system/download") {
-				downloadURL = playbackMatch[1]
+			if playback := findText(n, "playbackFileName"); strings.Contains(playback, "/system/download") {
+				downloadURL = playback
 				hasPlayback = true
-			} else if len(dlMatch) >= 2 && strings.Contains(dlMatch[1], "/system/download") {
-				downloadURL = dlMatch[1]
+			} else if dl := findText(n, "downloadUrl"); strings.Contains(dl, "/system/download") {
+				downloadURL = dl
 			}
-
 			if downloadURL == "" {
-				continue
+				return
 			}
 
-			var size int64
-			if len(sizeMatch) >= 2 {
-				_, _ = fmt.Sscanf(sizeMatch[1], "%d", &size)
-			}
+			size, _ := strconv.ParseInt(findText(n, "size"), 10, 64)
 
 			// Convert /system/download URL to direct download URL
-			directURL := convertToDirectDownloadURL(downloadURL, name)
-			fullURL := fmt.Sprintf("https://%s%s", hostname, directURL)
+			fullURL := origin + convertToDirectDownloadURL(downloadURL, name)
 
 			// Check if we already have this document
 			existing, exists := docsByName[name]
@@ -134,8 +102,9 @@ func extractDocumentLinks(rawDir, hostname string) []DocumentInfo {
 					existing.info.Size = size
 				}
 			}
-		}
-	}
+		})
+		return true
+	})
 
 	// Convert map to slice
 	docs := make([]DocumentInfo, 0, len(docsByName))
@@ -146,6 +115,14 @@ func extractDocumentLinks(rawDir, hostname string) []DocumentInfo {
 	return docs
 }
 
+// findText returns the text of the first descendant of n with the given name.
+func findText(n *StreamNode, name string) string {
+	if found := n.Find(name); found != nil {
+		return found.Text
+	}
+	return ""
+}
+
 // writeDocumentList writes the document list to a file.
 func writeDocumentList(path string, docs []DocumentInfo) error {
 	f, err := os.Create(path)
@@ -3,16 +3,18 @@ package downloader
 import (
 	"bufio"
 	"bytes"
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // HTTPClient describes the subset of http.Client used by the downloader.
@@ -38,14 +40,65 @@ type SubtitleEmbedder interface {
 	EmbedSubtitles(ctx context.Context, mp4Path, vttPath, lang string, stdout, stderr io.Writer) error
 }
 
+// TrackEmbedder is implemented by SubtitleEmbedders that can add several
+// named subtitle tracks and a chapter menu to a video file in a single pass.
+// Without one, subtitle tracks are added one by one, unnamed, and no chapters.
+type TrackEmbedder interface {
+	// EmbedTracks adds subtitles and, when chaptersPath is non-empty, the
+	// chapters listed in it ("HH:MM:SS.mmm Title" lines) to mp4Path.
+	EmbedTracks(
+		ctx context.Context,
+		mp4Path string,
+		subtitles []SubtitleTrack,
+		chaptersPath string,
+		stdout, stderr io.Writer,
+	) error
+}
+
+// SubtitleTrack is a WebVTT file to add to a video as a subtitle track.
+type SubtitleTrack struct {
+	Path string
+	Lang string // ISO 639 language code
+	Name string // Track name shown by players; empty leaves the track unnamed
+}
+
 // Options control how a recording is downloaded.
 type Options struct {
 	OutputDir  string
 	Session    string
-	Log        Logger           // Structured logger (compatible with charmbracelet/log)
-	OnProgress ProgressCallback // Called during MP4 download with progress
-	Overwrite  bool             // If true, overwrite existing directories without prompting
-	MP4Box     SubtitleEmbedder // Optional: embed subtitles into MP4 using MP4Box
+	Log        Logger             // Structured logger (compatible with charmbracelet/log)
+	OnProgress ProgressCallback   // Called during MP4 download with progress
+	Overwrite  bool               // If true, overwrite existing directories without prompting
+	MP4Box     SubtitleEmbedder   // Optional: embed subtitles into MP4 using MP4Box
+	Extractors *ExtractorRegistry // Page extractors for video/caption discovery (nil = DefaultExtractors)
+	OnEvent    EventHandler       // Optional: receives typed progress and asset events
+	ZipWait    time.Duration      // Wait for the server to prepare the ZIP (0 = DefaultZipWait, <0 = no wait)
+	// SelectiveZip fetches only the raw ZIP entries the downloader reads (captions,
+	// chat, attendees, titles, shared documents) with range requests and keeps no
+	// raw.zip. The FLV streams are only fetched when there is no MP4 rendition
+	// and recording.mp4 has to be rebuilt from them.
+	// Falls back to the full download when the server doesn't support ranges.
+	SelectiveZip bool
+	// StreamExtract extracts the raw ZIP while it downloads instead of after;
+	// entries that can't be read front to back are extracted once it completes.
+	StreamExtract bool
+	ExtractLimits ExtractLimits // Zip bomb protection (zero value = DefaultExtractLimits)
+	Retention     Retention     // Raw data kept after processing ("" = RetainAll)
+	ChatFormats   []ChatFormat  // Chat outputs to write (empty = ChatText only)
+	// WhiteboardSnapshots also renders each whiteboard page as it was whenever
+	// the pod moved to another page, not just its final state.
+	WhiteboardSnapshots bool
+	// AttendanceAggregate writes only aggregate counts to the attendance
+	// report, leaving out participant names and times.
+	AttendanceAggregate bool
+	// ChatCueDuration is how long each chat message is shown in chat.vtt, the
+	// chat subtitle track embedded next to the captions (0 =
+	// DefaultChatCueDuration, <0 = no chat.vtt).
+	ChatCueDuration time.Duration
+	// ChapterRules select the events that start a chapter (nil =
+	// DefaultChapterRules, empty = no chapters). Chapters are embedded into
+	// the MP4 when MP4Box is also a TrackEmbedder.
+	ChapterRules []ChapterRule
 }
 
 // progressReader wraps an io.Reader and reports progress.
@@ -74,7 +127,14 @@ type Result struct {
 	MP4Path      string
 	ZipPath      string
 	ExtractedDir string
-	Warnings     []string
+	Details      *RecordingDetails // Recording details from the Connect API, nil when the lookup failed
+	Lecturer     string            // Lecturer name discovered in the raw recording
+	Participants int               // Number of named attendees in the raw recording
+	Documents    []DocumentInfo    // Documents shared during the session
+	Streams      []MediaStream     // Media streams of the raw recording, also written to metadata.json
+	Assets       []AssetStatus     // Outcome of every asset, also written to metadata.json
+	Retention    Retention         // Raw data retention policy that was applied
+	Warnings     []string          // Human-readable summary of problems
 }
 
 // ErrNotFound indicates the resource was not found.
@@ -108,6 +168,9 @@ func NewWithPool(client HTTPClient, pool *DownloadPool) *Downloader {
 }
 
 // Download grabs the MP4 and VTT assets for the provided recording URL.
+// Recording details are looked up from the Connect API meanwhile. The API may
+// refuse callers the recording page lets in, so a failed lookup, even for
+// lack of access, only leaves Result.Details nil and never stops a download.
 func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options) (Result, error) {
 	logger := opts.Log
 
@@ -115,21 +178,31 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 	if err != nil {
 		return Result{}, err
 	}
+	retention, err := ParseRetention(string(opts.Retention))
+	if err != nil {
+		return Result{}, err
+	}
 	log(logger, "parsed recording URL", "id", info.ID, "host", info.Hostname, "base", info.BaseURL)
 
-	session := opts.Session
-	if session == "" {
-		if u, parseErr := url.Parse(rawURL); parseErr == nil {
-			if qs := u.Query().Get("session"); qs != "" {
-				session = qs
-				log(logger, "session token taken from query param")
-			}
-		}
+	session := ResolveSession(rawURL, opts.Session)
+	if opts.Session == "" && session != "" {
+		log(logger, "session token taken from query param")
 	}
 	if session != "" {
 		log(logger, "using session token", "length", len(session))
 	}
 
+	// Look up authoritative recording details while the assets download. Only
+	// the page and the assets decide whether the download fails
+	detailsCh := make(chan *RecordingDetails, 1)
+	go func() {
+		details, detailsErr := d.fetchRecordingDetails(ctx, info, session, logger)
+		if detailsErr != nil {
+			log(logger, "recording details unavailable", "error", detailsErr)
+		}
+		detailsCh <- details
+	}()
+
 	// Create initial cookies for ZIP download (session-based)
 	initialCookies := mergeCookies(session, nil)
 
@@ -147,43 +220,89 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 	type pageResult struct {
 		info pageInfo
 		err  error
+		took time.Duration
 	}
 	pageCh := make(chan pageResult, 1)
 	go func() {
-		pi, perr := d.fetchPageInfo(ctx, rawURL, session, logger)
-		pageCh <- pageResult{info: pi, err: perr}
+		start := time.Now()
+		pi, perr := d.fetchPageInfo(ctx, rawURL, session, opts.Extractors, logger)
+		pageCh <- pageResult{info: pi, err: perr, took: time.Since(start)}
 	}()
 
 	// Start ZIP download immediately to a temp location
 	// We'll move it to the final location once we know the title
 	tempZipPath := filepath.Join(baseOutputDir, fmt.Sprintf(".%s_temp.zip", info.ID))
 	tempMP4Path := filepath.Join(baseOutputDir, fmt.Sprintf(".%s_temp.mp4", info.ID))
+	tempRawDir := filepath.Join(baseOutputDir, fmt.Sprintf(".%s_temp_raw", info.ID))
+	var remoteExtracted bool
 	var zipDownloadErr error
+	var zipTook time.Duration
 	var zipDownloadDone = make(chan struct{})
-
-	if d.pool != nil {
-		// Use shared download pool
-		zipResult := d.pool.SubmitZip(ctx, zipURL, tempZipPath, rawURL, initialCookies)
-		go func() {
-			defer close(zipDownloadDone)
-			logInfo(logger, "downloading recording data via pool", "url", zipURL)
-			res := <-zipResult
-			zipDownloadErr = res.Err
-		}()
-	} else {
-		// Use direct goroutine
-		go func() {
-			defer close(zipDownloadDone)
-			logInfo(logger, "downloading recording data", "url", zipURL)
-			if err := d.downloadFile(ctx, zipURL, tempZipPath, downloadOptions{
+	zipStart := time.Now()
+
+	var streamed map[string]int64 // Entries extracted to tempRawDir during the download
+	fetchZip := func() error {
+		var tee io.Writer
+		var stream *zipStream
+		if opts.StreamExtract {
+			os.RemoveAll(tempRawDir)
+			stream = startZipStream(tempRawDir, opts.ExtractLimits)
+			tee = stream
+		}
+		var err error
+		if d.pool != nil {
+			// Use shared download pool
+			res := <-d.pool.SubmitZipStream(ctx, zipURL, tempZipPath, rawURL, initialCookies, tee)
+			err = res.Err
+		} else {
+			err = d.downloadFile(ctx, zipURL, tempZipPath, downloadOptions{
 				Cookies: initialCookies,
 				Referer: rawURL,
 				Kind:    fileKindZip,
-			}, logger); err != nil {
-				zipDownloadErr = err
+				Tee:     tee,
+			}, logger)
+		}
+		if stream != nil {
+			entries, streamErr := stream.finish(err)
+			switch {
+			case err != nil:
+				os.RemoveAll(tempRawDir)
+			case streamErr != nil:
+				logInfo(logger, "extraction during download stopped, extracting afterwards", "error", streamErr)
+				os.RemoveAll(tempRawDir)
+			default:
+				streamed = entries
+				log(logger, "extracted during download", "entries", len(entries))
 			}
-		}()
+		}
+		return err
 	}
+	go func() {
+		defer close(zipDownloadDone)
+		if opts.SelectiveZip {
+			logInfo(logger, "fetching raw recording entries with range requests", "url", zipURL)
+			reqOpts := requestOptions{Cookies: initialCookies, Referer: rawURL}
+			err := d.extractRemoteZip(ctx, zipURL, tempRawDir, isNeededRawEntry, reqOpts, opts.ExtractLimits, logger)
+			if err == nil {
+				remoteExtracted = true
+				zipTook = time.Since(zipStart)
+				return
+			}
+			os.RemoveAll(tempRawDir)
+			logInfo(logger, "selective extraction unavailable, downloading the full zip", "error", err)
+		}
+		if d.pool != nil {
+			logInfo(logger, "downloading recording data via pool", "url", zipURL)
+		} else {
+			logInfo(logger, "downloading recording data", "url", zipURL)
+		}
+		zipDownloadErr = waitWhilePreparing(ctx, zipWait(opts.ZipWait), fetchZip, func(waited, next time.Duration) {
+			msg := fmt.Sprintf("server is preparing the recording ZIP, retrying in %s", next)
+			logInfo(logger, msg, "waited", waited.Round(time.Second))
+			emit(opts.OnEvent, Event{Kind: EventWaiting, URL: rawURL, Asset: "zip", Message: msg})
+		})
+		zipTook = time.Since(zipStart)
+	}()
 
 	// Wait for page info to get the real title
 	pageRes := <-pageCh
@@ -199,8 +318,9 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 		// Check if this is an authentication error
 		if errors.Is(pageErr, ErrAuthRequired) {
 			// Clean up temp zip if it exists
-			os.Remove(tempZipPath)
 			<-zipDownloadDone
+			os.Remove(tempZipPath)
+			os.RemoveAll(tempRawDir)
 			return Result{}, fmt.Errorf("%w\n\n"+
 				"To access private recordings, you need to include a session token in the URL.\n"+
 				"Example: https://your-domain.adobeconnect.com/recording-id/?session=YOUR_SESSION_TOKEN\n\n"+
@@ -219,15 +339,17 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 	if !opts.Overwrite {
 		if entries, err := os.ReadDir(rootDir); err == nil && len(entries) > 0 {
 			// Clean up temp zip
-			os.Remove(tempZipPath)
 			<-zipDownloadDone
+			os.Remove(tempZipPath)
+			os.RemoveAll(tempRawDir)
 			return Result{Title: title, RootDir: rootDir}, fmt.Errorf("%w: %s", ErrDirectoryExists, rootDir)
 		}
 	}
 
 	if err := os.MkdirAll(rootDir, 0o755); err != nil {
-		os.Remove(tempZipPath)
 		<-zipDownloadDone
+		os.Remove(tempZipPath)
+		os.RemoveAll(tempRawDir)
 		return Result{}, fmt.Errorf("create output dir: %w", err)
 	}
 
@@ -235,6 +357,17 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 		Title:   title,
 		RootDir: rootDir,
 	}
+	emit(opts.OnEvent, Event{Kind: EventStarted, URL: rawURL, Path: rootDir, Message: title})
+	warn := func(msg string) {
+		result.Warnings = append(result.Warnings, msg)
+		emit(opts.OnEvent, Event{Kind: EventWarning, URL: rawURL, Message: msg})
+	}
+	assets := &assetTracker{root: rootDir}
+	if pageErr != nil {
+		assets.fail(AssetPage, pageRes.took, pageErr)
+	} else {
+		assets.ok(AssetPage, "", pageRes.took)
+	}
 
 	// Prepare final paths
 	cookies := mergeCookies(session, pageInfo.Cookies)
@@ -245,11 +378,13 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 
 	// Start MP4 download (now that we have the video URL from page info) to a temp path
 	var mp4ResultCh <-chan DownloadResult
+	var mp4Took time.Duration
 	if pageInfo.VideoSrc != "" {
+		onProgress := progressWithEvents(opts, rawURL, "mp4")
 		startMP4 := func(dest string) <-chan DownloadResult {
 			if d.pool != nil {
 				logInfo(logger, "downloading video via pool", "url", pageInfo.VideoSrc)
-				return d.pool.SubmitMP4(ctx, pageInfo.VideoSrc, dest, referer, cookies, opts.OnProgress)
+				return d.pool.SubmitMP4(ctx, pageInfo.VideoSrc, dest, referer, cookies, onProgress)
 			}
 
 			resultCh := make(chan DownloadResult, 1)
@@ -259,7 +394,7 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 					Cookies:    cookies,
 					Referer:    referer,
 					Kind:       fileKindVideo,
-					OnProgress: opts.OnProgress,
+					OnProgress: onProgress,
 				}, logger); err != nil {
 					log(logger, "video src download failed", "error", err)
 					resultCh <- DownloadResult{Err: err}
@@ -271,7 +406,16 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 			return resultCh
 		}
 
-		mp4ResultCh = startMP4(tempMP4Path)
+		// Time the transfer itself, not how long the ZIP kept us from collecting it
+		mp4Start := time.Now()
+		started := startMP4(tempMP4Path)
+		timed := make(chan DownloadResult, 1)
+		go func() {
+			res := <-started
+			mp4Took = time.Since(mp4Start)
+			timed <- res
+		}()
+		mp4ResultCh = timed
 	}
 
 	// Wait for ZIP download to complete
@@ -279,18 +423,26 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 
 	// Handle ZIP result - move from temp location to final location
 	var zipErr error
-	if zipDownloadErr != nil {
-		if errors.Is(zipDownloadErr, ErrNotFound) {
-			result.Warnings = append(result.Warnings, "Raw recording ZIP not available")
+	if remoteExtracted {
+		assets.skip(AssetZip, "selective extraction fetched only the needed entries")
+	} else if zipDownloadErr != nil {
+		if errors.Is(zipDownloadErr, ErrZipPreparing) {
+			warn("Raw recording ZIP was still being prepared by the server")
+			log(logger, "zip still preparing", "url", zipURL)
+		} else if errors.Is(zipDownloadErr, ErrNotFound) {
+			warn("Raw recording ZIP not available")
 			log(logger, "zip not available", "url", zipURL)
 		} else if errors.Is(zipDownloadErr, ErrInvalidZip) {
-			result.Warnings = append(result.Warnings, "ZIP response was invalid")
+			warn("ZIP response was invalid")
 			log(logger, "zip invalid", "url", zipURL)
 		} else {
+			warn(fmt.Sprintf("Raw recording ZIP failed: %v", zipDownloadErr))
 			log(logger, "zip download failed", "error", zipDownloadErr)
 		}
 		zipErr = zipDownloadErr
+		assets.fail(AssetZip, zipTook, zipDownloadErr)
 		os.Remove(tempZipPath)
+		emit(opts.OnEvent, Event{Kind: EventAssetFailed, URL: rawURL, Asset: "zip", Err: zipDownloadErr})
 	} else {
 		// Move temp zip to final location
 		if err := os.Rename(tempZipPath, zipPath); err != nil {
@@ -298,6 +450,7 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 			if copyErr := copyFile(zipPath, tempZipPath); copyErr != nil {
 				log(logger, "zip move/copy failed", "error", copyErr)
 				zipErr = copyErr
+				assets.fail(AssetZip, zipTook, writeError(AssetZip, zipURL, copyErr))
 			} else {
 				os.Remove(tempZipPath)
 				result.ZipPath = zipPath
@@ -309,6 +462,11 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 		}
 	}
 
+	if result.ZipPath != "" {
+		assets.ok(AssetZip, zipPath, zipTook)
+		emit(opts.OnEvent, Event{Kind: EventAssetDone, URL: rawURL, Asset: "zip", Path: zipPath})
+	}
+
 	// Start ZIP extraction immediately (in parallel with MP4 download)
 	// This is key for performance - don't wait for MP4 to finish before extracting
 	var extractDir string
@@ -317,30 +475,59 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 	var lecturerName string
 	var userMapping map[string]string
 	var vttPath string
+	var vttErr error
 	var docs []DocumentInfo
+	var docsDownloaded int
+	var chatVTTPath string
+	var chatMessages []ChatMessage // Read once for every output that uses the chat
 
-	if zipErr == nil && result.ZipPath != "" {
+	if zipErr != nil && streamed != nil {
+		os.RemoveAll(tempRawDir)
+	}
+	if zipErr == nil && (result.ZipPath != "" || remoteExtracted) {
 		extractDir = filepath.Join(rootDir, "raw")
 		go func() {
 			defer close(extractDone)
-			logInfo(logger, "extracting zip", "path", zipPath)
-			if err := extractZip(zipPath, extractDir); err != nil {
+			extractStart := time.Now()
+			var err error
+			if remoteExtracted {
+				// Entries were already fetched; move them into place
+				os.RemoveAll(extractDir)
+				err = os.Rename(tempRawDir, extractDir)
+			} else if streamed != nil {
+				// Most entries were extracted during the download; finish from raw.zip
+				os.RemoveAll(extractDir)
+				if err = os.Rename(tempRawDir, extractDir); err == nil {
+					err = completeStreamExtract(zipPath, extractDir, streamed, opts.ExtractLimits)
+				}
+			} else {
+				logInfo(logger, "extracting zip", "path", zipPath)
+				err = extractZip(zipPath, extractDir, opts.ExtractLimits)
+			}
+			took := time.Since(extractStart)
+			if remoteExtracted {
+				took += zipTook
+			}
+			if err != nil {
 				logError(logger, "zip extraction failed", "path", zipPath, "error", err)
+				os.RemoveAll(tempRawDir)
 				extractErr = err
+				assets.fail(AssetExtraction, took, err)
+				assets.skipRaw("raw recording could not be extracted")
 				return
 			}
 			result.ExtractedDir = extractDir
+			assets.ok(AssetExtraction, extractDir, took)
 			logInfo(logger, "zip extracted", "path", extractDir)
 
 			// Find VTT file in ZIP (named *.vtt)
 			vttFiles, _ := filepath.Glob(filepath.Join(extractDir, "*.vtt"))
 			if len(vttFiles) > 0 {
 				// Copy VTT to output directory
-				vttPath = filepath.Join(rootDir, "captions.vtt")
-				if data, err := os.ReadFile(vttFiles[0]); err == nil {
-					if err := os.WriteFile(vttPath, data, 0o644); err == nil {
-						log(logger, "vtt extracted from zip", "source", vttFiles[0])
-					}
+				dest := filepath.Join(rootDir, "captions.vtt")
+				if vttErr = copyFile(dest, vttFiles[0]); vttErr == nil {
+					vttPath = dest
+					log(logger, "vtt extracted from zip", "source", vttFiles[0])
 				}
 			}
 
@@ -355,7 +542,8 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 			}
 
 			// Extract document links
-			docs = extractDocumentLinks(extractDir, info.Hostname)
+			origin, _, _ := ParseTarget(info.BaseURL)
+			docs = extractDocumentLinks(extractDir, origin)
 
 			// Start document downloads immediately (don't wait for MP4)
 			if len(docs) > 0 {
@@ -367,24 +555,38 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 				}
 				docsDir := filepath.Join(rootDir, "documents")
 				logInfo(logger, "downloading documents via pool", "count", len(docs))
-				downloaded := d.downloadDocuments(ctx, docs, docsDir, cookies, referer, logger)
-				log(logger, "documents downloaded", "downloaded", downloaded, "total", len(docs))
+				docsDownloaded = d.downloadDocuments(ctx, docs, docsDir, cookies, referer, assets, logger)
+				log(logger, "documents downloaded", "downloaded", docsDownloaded, "total", len(docs))
 			}
 
 			// Generate chat log
-			chatLogPath := filepath.Join(rootDir, "chat_log.txt")
-			if err := extractChatLog(extractDir, chatLogPath); err != nil {
+			chatStart := time.Now()
+			var chatErr error
+			chatMessages, chatErr = extractChatMessages(extractDir, userMapping)
+			chatLogPath, err := writeChatOutputs(rootDir, title, opts.ChatFormats, chatMessages, chatErr)
+			if err != nil {
 				log(logger, "chat log extraction failed", "error", err)
+				assets.fail(AssetChat, time.Since(chatStart), err)
 			} else {
 				log(logger, "chat log created", "path", chatLogPath)
+				assets.ok(AssetChat, chatLogPath, time.Since(chatStart))
 			}
+			chatVTTPath = writeChatSubtitles(rootDir, chatMessages, chatErr, opts.ChatCueDuration, assets, logger)
+			writeQA(rootDir, extractDir, title, userMapping, assets, logger)
+			writePolls(rootDir, extractDir, title, userMapping, assets, logger)
+			writeNotes(rootDir, extractDir, title, chatMessages, assets, logger)
+			writeWhiteboards(rootDir, extractDir, opts.WhiteboardSnapshots, assets, logger)
+			writeSlides(rootDir, extractDir, docs, assets, logger)
 		}()
 	} else {
 		close(extractDone)
+		assets.skip(AssetExtraction, "raw recording ZIP not available")
+		assets.skipRaw("raw recording ZIP not available")
 	}
 
 	// Wait for MP4 download to complete and move it into place
 	var mp4DownloadErr error
+	var mp4MoveErr error
 	var mp4DownloadedPath string
 	if mp4ResultCh != nil {
 		mp4Res := <-mp4ResultCh
@@ -400,6 +602,7 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 		if err := os.Rename(mp4DownloadedPath, mp4Path); err != nil {
 			if copyErr := copyFile(mp4Path, mp4DownloadedPath); copyErr != nil {
 				log(logger, "mp4 move/copy failed", "error", copyErr)
+				mp4MoveErr = writeError(AssetMP4, pageInfo.VideoSrc, copyErr)
 			} else {
 				os.Remove(mp4DownloadedPath)
 				result.MP4Path = mp4Path
@@ -416,16 +619,74 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 	}
 
 	// Check MP4 result
-	if result.MP4Path == "" {
-		result.Warnings = append(result.Warnings, "MP4 rendition not available")
+	switch {
+	case result.MP4Path != "":
+		assets.ok(AssetMP4, result.MP4Path, mp4Took)
+		emit(opts.OnEvent, Event{Kind: EventAssetDone, URL: rawURL, Asset: "mp4", Path: result.MP4Path})
+	case pageErr != nil:
+		assets.skip(AssetMP4, "page unavailable")
+	case pageInfo.VideoSrc == "":
+		assets.missing(AssetMP4, "no video URL found on the recording page")
+	default:
+		failure := cmp.Or(mp4DownloadErr, mp4MoveErr)
+		assets.fail(AssetMP4, mp4Took, failure)
+		emit(opts.OnEvent, Event{Kind: EventAssetFailed, URL: rawURL, Asset: "mp4", Err: failure})
 	}
 
 	// Wait for extraction and document downloads to complete
 	<-extractDone
+	if extractErr != nil {
+		warn(fmt.Sprintf("Raw recording ZIP could not be extracted: %v", extractErr))
+	}
+	result.Lecturer = lecturerName
+	result.Participants = len(userMapping)
+	result.Documents = docs
+	if failedDocs := len(docs) - docsDownloaded; failedDocs > 0 {
+		warn(fmt.Sprintf("%d of %d documents could not be downloaded", failedDocs, len(docs)))
+	}
+
+	// Without an MP4 rendition, rebuild one from the raw media streams
+	rendition := result.MP4Path != ""
+	if result.ExtractedDir != "" {
+		switch {
+		case rendition:
+			assets.skip(AssetRemux, "MP4 rendition downloaded")
+		case remoteExtracted:
+			// Selective extraction left the FLV streams on the server; fetch
+			// them now that they are needed
+			logInfo(logger, "fetching raw media streams with range requests", "url", zipURL)
+			fetchStart := time.Now()
+			reqOpts := requestOptions{Cookies: initialCookies, Referer: rawURL}
+			err := d.extractRemoteZip(
+				ctx, zipURL, result.ExtractedDir, isMediaEntry, reqOpts, opts.ExtractLimits, logger,
+			)
+			if err != nil {
+				logWarn(logger, "raw media streams could not be fetched", "error", err)
+				assets.fail(AssetRemux, time.Since(fetchStart), err)
+				break
+			}
+			fallthrough
+		default:
+			result.MP4Path = remuxRecording(ctx, mp4Path, result.ExtractedDir, assets, logger)
+			if result.MP4Path != "" {
+				emit(opts.OnEvent, Event{Kind: EventAssetDone, URL: rawURL, Asset: "mp4", Path: result.MP4Path})
+			}
+		}
+		result.Streams = inspectStreams(result.ExtractedDir, userMapping)
+	}
+	switch {
+	case result.MP4Path == "":
+		warn("MP4 rendition not available")
+	case !rendition:
+		warn("MP4 rendition not available; recording.mp4 was rebuilt from the raw streams")
+	}
 
 	// Process VTT after both extraction and MP4 are done (VTT embedding needs MP4)
+	var captionsPath string
 	if extractErr == nil && vttPath != "" {
-		d.processVTT(ctx, vttPath, result.MP4Path, rootDir, lecturerName, userMapping, opts.MP4Box, logger)
+		assets.ok(AssetCaptions, vttPath, 0)
+		d.processVTT(vttPath, rootDir, lecturerName, userMapping, assets, logger)
+		captionsPath = vttPath
 	}
 
 	// Fallback: Download VTT separately if not found in ZIP
@@ -433,39 +694,82 @@ func (d *Downloader) Download(ctx context.Context, rawURL string, opts Options)
 		vttURL := resolveVTTURL(info.BaseURL, pageInfo.VTTPath)
 		vttPath = filepath.Join(rootDir, "captions.vtt")
 		logInfo(logger, "downloading captions", "url", vttURL)
+		vttStart := time.Now()
 		if err := d.downloadFile(ctx, vttURL, vttPath, downloadOptions{
 			Cookies: cookies,
 			Referer: referer,
 			Kind:    fileKindBinary,
+			Asset:   "vtt",
 		}, logger); err != nil {
 			log(logger, "vtt download failed", "error", err)
+			assets.fail(AssetCaptions, time.Since(vttStart), err)
 			vttPath = "" // Mark as not available
 		} else {
 			log(logger, "vtt downloaded", "path", vttPath)
+			assets.ok(AssetCaptions, vttPath, time.Since(vttStart))
 			// Process the downloaded VTT
-			d.processVTT(ctx, vttPath, result.MP4Path, rootDir, lecturerName, userMapping, opts.MP4Box, logger)
+			d.processVTT(vttPath, rootDir, lecturerName, userMapping, assets, logger)
+			captionsPath = vttPath
+		}
+	} else if vttPath == "" {
+		switch {
+		case vttErr != nil:
+			assets.fail(AssetCaptions, 0, vttErr)
+		case pageInfo.VTTPath == "":
+			assets.missing(AssetCaptions, "no captions in the raw recording or on the recording page")
+		default:
+			assets.skip(AssetCaptions, "captions are only fetched separately alongside the MP4")
+		}
+	}
+	if vttPath == "" {
+		assets.skip(AssetTranscript, "no captions")
+		assets.skip(AssetSubtitles, "no captions")
+	}
+	if result.MP4Path == "" && result.ZipPath == "" && result.ExtractedDir == "" {
+		result.Assets = assets.list()
+		err := errors.New("no assets could be downloaded (MP4 and ZIP unavailable)")
+		if cause := errors.Join(cmp.Or(mp4DownloadErr, mp4MoveErr), zipErr); cause != nil {
+			err = fmt.Errorf("no assets could be downloaded (MP4 and ZIP unavailable): %w", cause)
 		}
+		return result, err
+	}
+
+	result.Details = <-detailsCh
+
+	// The timeline, attendance and chapters are built last so they can use the
+	// recording times from the details
+	var chaptersPath string
+	if result.ExtractedDir != "" {
+		writeSession(rootDir, result.ExtractedDir, result.Details, userMapping, assets, logger)
+		writeAttendance(
+			rootDir, result.ExtractedDir, result.Details, userMapping, chatMessages, opts.AttendanceAggregate,
+			assets, logger,
+		)
+		chaptersPath = writeChapters(rootDir, result.ExtractedDir, result.Details, opts.ChapterRules, assets, logger)
 	}
+	embedTracks(ctx, result.MP4Path, captionsPath, chatVTTPath, chaptersPath, opts.MP4Box, assets, logger)
+	result.Assets = assets.list()
 
-	if result.MP4Path == "" && result.ZipPath == "" {
-		return result, errors.New("no assets could be downloaded (MP4 and ZIP unavailable)")
+	// Raw data is only needed to build the artifacts above
+	if err := applyRetention(rootDir, retention, &result); err != nil {
+		warn(fmt.Sprintf("apply retention policy %s: %v", retention, err))
 	}
 
 	if err := writeMetadata(rootDir, info, result); err != nil {
-		result.Warnings = append(result.Warnings, fmt.Sprintf("write metadata: %v", err))
+		warn(fmt.Sprintf("write metadata: %v", err))
 		log(logger, "metadata write warning", "error", err)
 	}
 
+	emit(opts.OnEvent, Event{Kind: EventFinished, URL: rawURL, Path: rootDir, Message: title})
 	return result, nil
 }
 
-// processVTT handles VTT cleaning, transcript creation, and subtitle embedding.
+// processVTT handles VTT cleaning and transcript creation.
 func (d *Downloader) processVTT(
-	ctx context.Context,
-	vttPath, mp4Path, rootDir string,
+	vttPath, rootDir string,
 	lecturerName string,
 	userMapping map[string]string,
-	embedder SubtitleEmbedder,
+	assets *assetTracker,
 	logger Logger,
 ) {
 	// Clean the VTT file (fix speaker markers, use real names from user mapping)
@@ -483,25 +787,122 @@ func (d *Downloader) processVTT(
 
 	// Create a readable transcript from the VTT
 	transcriptPath := filepath.Join(rootDir, "transcript.txt")
+	start := time.Now()
 	if err := vttToTranscript(vttPath, transcriptPath); err != nil {
 		log(logger, "transcript creation failed", "error", err)
+		assets.fail(AssetTranscript, time.Since(start), err)
 	} else {
 		log(logger, "transcript created", "path", transcriptPath)
+		assets.ok(AssetTranscript, transcriptPath, time.Since(start))
+	}
+}
+
+// embedTracks adds the captions, chat subtitles and chapters that were written
+// to the MP4 and records the status of the subtitles, chat track and markers
+// assets. A TrackEmbedder adds them all in one pass, as each pass rewrites the
+// whole file.
+func embedTracks(
+	ctx context.Context,
+	mp4Path, vttPath, chatVTTPath, chaptersPath string,
+	embedder SubtitleEmbedder,
+	assets *assetTracker,
+	logger Logger,
+) {
+	switch {
+	case embedder == nil:
+		skipEmbedding("MP4Box not available", vttPath, chatVTTPath, chaptersPath, assets)
+		return
+	case mp4Path == "":
+		skipEmbedding("no MP4 to embed into", vttPath, chatVTTPath, chaptersPath, assets)
+		return
+	}
+
+	var tracks []SubtitleTrack
+	var trackAssets []string
+	if vttPath != "" {
+		tracks = append(tracks, SubtitleTrack{Path: vttPath, Lang: "en"})
+		trackAssets = append(trackAssets, AssetSubtitles)
+	}
+	if chatVTTPath != "" {
+		// Chat may be in any language; the name tells it from the captions
+		tracks = append(tracks, SubtitleTrack{Path: chatVTTPath, Lang: "und", Name: "Chat"})
+		trackAssets = append(trackAssets, AssetChatTrack)
 	}
 
-	// Embed subtitles into MP4 if embedder is available and MP4 exists
-	if embedder != nil && mp4Path != "" {
-		logInfo(logger, "embedding subtitles", "path", vttPath)
-		if err := embedder.EmbedSubtitles(ctx, mp4Path, vttPath, "en", nil, nil); err != nil {
-			logWarn(logger, "failed to embed subtitles", "error", err)
+	trackEmbedder, ok := embedder.(TrackEmbedder)
+	if !ok {
+		if chaptersPath == "" {
+			assets.skip(AssetMarkers, "no chapters")
 		} else {
-			logInfo(logger, "subtitles embedded successfully")
+			assets.skip(AssetMarkers, "embedder cannot add chapters")
 		}
+		for i, track := range tracks {
+			logInfo(logger, "embedding subtitles", "path", track.Path)
+			start := time.Now()
+			err := embedder.EmbedSubtitles(ctx, mp4Path, track.Path, track.Lang, nil, nil)
+			recordEmbed(mp4Path, []string{trackAssets[i]}, time.Since(start), err, assets, logger)
+		}
+		return
+	}
+
+	if chaptersPath == "" {
+		assets.skip(AssetMarkers, "no chapters")
+	} else {
+		trackAssets = append(trackAssets, AssetMarkers)
+	}
+	if len(trackAssets) == 0 {
+		return
 	}
+	logInfo(logger, "embedding tracks", "subtitles", len(tracks), "chapters", chaptersPath != "")
+	start := time.Now()
+	err := trackEmbedder.EmbedTracks(ctx, mp4Path, tracks, chaptersPath, nil, nil)
+	recordEmbed(mp4Path, trackAssets, time.Since(start), err, assets, logger)
 }
 
-// fetchPageInfo fetches the recording page and extracts video URLs and VTT paths.
-func (d *Downloader) fetchPageInfo(ctx context.Context, pageURL, session string, logger Logger) (pageInfo, error) {
+// recordEmbed records the outcome of one embedding pass for each of the
+// assets it added.
+func recordEmbed(mp4Path string, names []string, elapsed time.Duration, err error, assets *assetTracker, logger Logger) {
+	if err != nil {
+		logWarn(logger, "failed to embed tracks", "error", err)
+	} else {
+		logInfo(logger, "tracks embedded successfully")
+	}
+	for _, name := range names {
+		if err != nil {
+			assets.fail(name, elapsed, err)
+		} else {
+			assets.ok(name, mp4Path, elapsed)
+		}
+	}
+}
+
+// skipEmbedding records why captions, chat and chapters were not embedded,
+// for each of them that was written.
+func skipEmbedding(reason, vttPath, chatVTTPath, chaptersPath string, assets *assetTracker) {
+	if vttPath != "" {
+		assets.skip(AssetSubtitles, reason)
+	}
+	if chatVTTPath != "" {
+		assets.skip(AssetChatTrack, reason)
+	}
+	if chaptersPath == "" {
+		assets.skip(AssetMarkers, "no chapters")
+	} else {
+		assets.skip(AssetMarkers, reason)
+	}
+}
+
+// fetchPageInfo fetches the recording page and extracts video URLs and VTT paths
+// using the given extractors (DefaultExtractors when nil).
+func (d *Downloader) fetchPageInfo(
+	ctx context.Context,
+	pageURL, session string,
+	extractors *ExtractorRegistry,
+	logger Logger,
+) (pageInfo, error) {
+	if extractors == nil {
+		extractors = DefaultExtractors()
+	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
 	if err != nil {
 		return pageInfo{}, err
@@ -513,7 +914,7 @@ func (d *Downloader) fetchPageInfo(ctx context.Context, pageURL, session string,
 	log(logger, "fetching page", "url", pageURL)
 	resp, err := d.client.Do(req)
 	if err != nil {
-		return pageInfo{}, err
+		return pageInfo{}, newDownloadError(CategoryNetwork, "page", pageURL, 0, err)
 	}
 	defer resp.Body.Close()
 	log(logger, "page response", "status", resp.StatusCode, "content-type", resp.Header.Get("Content-Type"))
@@ -521,46 +922,47 @@ func (d *Downloader) fetchPageInfo(ctx context.Context, pageURL, session string,
 	if resp.StatusCode == http.StatusInternalServerError || resp.StatusCode == http.StatusUnauthorized ||
 		resp.StatusCode == http.StatusForbidden {
 		// These status codes typically indicate authentication issues
-		return pageInfo{}, ErrAuthRequired
+		return pageInfo{}, newDownloadError(CategoryAuthExpired, "page", pageURL, resp.StatusCode, ErrAuthRequired)
 	}
 	if resp.StatusCode >= 400 {
-		return pageInfo{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+		return pageInfo{}, statusError("page", pageURL, resp.StatusCode, fileKindBinary)
 	}
 
 	body, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20)) // limit to avoid large downloads
 	if err != nil {
-		return pageInfo{}, err
+		return pageInfo{}, newDownloadError(CategoryNetwork, "page", pageURL, resp.StatusCode, err)
+	}
+	if isLoginPage(body) {
+		return pageInfo{}, newDownloadError(CategoryLoginWall, "page", pageURL, resp.StatusCode, ErrLoginWall)
 	}
 
 	title, _ := parseHTMLTitle(bytes.NewReader(body))
-	casURL := findCASRecordingURL(body)
-	videoSrc, vttPath := parseVideoElement(body)
-	jsVTT := findVTTFromJS(body)
-
-	// Prefer casRecordingURL over video src tag
-	if casURL != "" {
-		videoSrc = casURL
-	}
-	// Prefer JS VTT if HTML track not found
-	if vttPath == "" && jsVTT != "" {
-		vttPath = jsVTT
+	video, captions := extractors.Discover(body, logger)
+	page := req.URL
+	if resp.Request != nil {
+		// Relative URLs are resolved against the page after redirects
+		page = resp.Request.URL
 	}
+	video.URL = resolveCandidate(page, video)
+	captions.URL = resolveCandidate(page, captions)
 
-	if videoSrc != "" {
-		log(logger, "video src discovered", "url", videoSrc)
-	}
-	if vttPath != "" {
-		log(logger, "vtt track discovered", "path", vttPath)
+	if video.URL != "" {
+		log(logger, "video src discovered", "url", video.URL, "extractor", video.Extractor)
+	} else {
+		logWarn(logger, "no video url discovered in page; the player markup may have changed",
+			"extractors", strings.Join(extractors.names(), ","))
 	}
-	if videoSrc == "" {
-		log(logger, "no video url discovered in page")
+	if captions.URL != "" {
+		log(logger, "vtt track discovered", "path", captions.URL, "extractor", captions.Extractor)
 	}
 
 	return pageInfo{
-		Title:    title,
-		VideoSrc: videoSrc,
-		VTTPath:  vttPath,
-		Cookies:  resp.Cookies(),
+		Title:             title,
+		VideoSrc:          video.URL,
+		VTTPath:           captions.URL,
+		VideoExtractor:    video.Extractor,
+		CaptionsExtractor: captions.Extractor,
+		Cookies:           resp.Cookies(),
 	}, nil
 }
 
@@ -573,12 +975,26 @@ const (
 	fileKindVideo
 )
 
+// assetName returns the default asset name used in errors for a file kind.
+func (k fileKind) assetName() string {
+	switch k {
+	case fileKindZip:
+		return "zip"
+	case fileKindVideo:
+		return "mp4"
+	default:
+		return "file"
+	}
+}
+
 // downloadOptions configures file download behavior.
 type downloadOptions struct {
 	Cookies    []*http.Cookie
 	Referer    string
 	Kind       fileKind
+	Asset      string // Asset name for errors; defaults to the kind's name
 	OnProgress ProgressCallback
+	Tee        io.Writer // Optional: also receives the file contents; must not fail
 }
 
 // downloadFile downloads a file from the given URL to the destination path.
@@ -590,6 +1006,11 @@ func (d *Downloader) downloadFile(
 	opts downloadOptions,
 	logger Logger,
 ) error {
+	asset := opts.Asset
+	if asset == "" {
+		asset = opts.Kind.assetName()
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
 	if err != nil {
 		return err
@@ -608,7 +1029,7 @@ func (d *Downloader) downloadFile(
 
 	resp, err := d.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return newDownloadError(CategoryNetwork, asset, fileURL, 0, fmt.Errorf("request failed: %w", err))
 	}
 	defer resp.Body.Close()
 
@@ -624,30 +1045,34 @@ func (d *Downloader) downloadFile(
 	)
 
 	// Handle error status codes
-	if resp.StatusCode == http.StatusForbidden {
-		return errors.New("returned 403 (token may be expired or already used)")
+	if resp.StatusCode >= 300 {
+		return statusError(asset, fileURL, resp.StatusCode, opts.Kind)
 	}
-	if resp.StatusCode == http.StatusNotFound {
-		return ErrNotFound
+
+	// Read initial bytes for validation
+	var head [4096]byte
+	n, readErr := io.ReadFull(resp.Body, head[:])
+	if readErr != nil && !errors.Is(readErr, io.ErrUnexpectedEOF) && !errors.Is(readErr, io.EOF) {
+		return readError(asset, fileURL, readErr)
 	}
-	if resp.StatusCode >= 300 {
-		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+
+	// Validate content based on file kind
+	if isHTMLResponse(resp.Header, head[:n]) {
+		log(logger, "html response detected", "url", fileURL)
+		return htmlError(asset, fileURL, resp.StatusCode, opts.Kind, head[:n])
 	}
 
-	// For video files, check Content-Type header early
-	if opts.Kind == fileKindVideo {
-		contentType := resp.Header.Get("Content-Type")
-		if strings.Contains(contentType, "text/html") {
-			return errors.New("returned HTML instead of video")
-		}
+	if opts.Kind == fileKindZip && n >= 4 && !isZipSignature(head[:n]) {
+		log(logger, "invalid zip signature", "url", fileURL)
+		return newDownloadError(CategoryInvalidContent, asset, fileURL, resp.StatusCode, ErrInvalidZip)
 	}
 
 	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
-		return err
+		return writeError(asset, fileURL, err)
 	}
 	file, err := os.Create(dest)
 	if err != nil {
-		return err
+		return writeError(asset, fileURL, err)
 	}
 	defer file.Close()
 
@@ -655,28 +1080,14 @@ func (d *Downloader) downloadFile(
 	// This reduces the number of syscalls when writing to disk
 	bufferedFile := bufio.NewWriterSize(file, 64*1024)
 	defer bufferedFile.Flush()
-
-	// Read initial bytes for validation
-	var head [4096]byte
-	n, readErr := io.ReadFull(resp.Body, head[:])
-	if readErr != nil && !errors.Is(readErr, io.ErrUnexpectedEOF) && !errors.Is(readErr, io.EOF) {
-		return readErr
-	}
-
-	// Validate content based on file kind
-	if isHTMLResponse(resp.Header, head[:n]) {
-		log(logger, "html response detected", "url", fileURL)
-		return ErrNotFound
-	}
-
-	if opts.Kind == fileKindZip && n >= 4 && !isZipSignature(head[:n]) {
-		log(logger, "invalid zip signature", "url", fileURL)
-		return ErrInvalidZip
+	var out io.Writer = bufferedFile
+	if opts.Tee != nil {
+		out = io.MultiWriter(bufferedFile, opts.Tee)
 	}
 
 	// Write the head bytes we already read
-	if _, err := bufferedFile.Write(head[:n]); err != nil {
-		return err
+	if _, err := out.Write(head[:n]); err != nil {
+		return writeError(asset, fileURL, err)
 	}
 
 	// Copy the rest, with optional progress reporting
@@ -692,14 +1103,18 @@ func (d *Downloader) downloadFile(
 		opts.OnProgress(int64(n), resp.ContentLength)
 	}
 
-	written, err := io.Copy(bufferedFile, reader)
+	body := &bodyReader{r: reader}
+	written, err := io.Copy(out, body)
 	if err != nil {
-		return fmt.Errorf("write file: %w", err)
+		if body.err != nil {
+			return readError(asset, fileURL, err)
+		}
+		return writeError(asset, fileURL, fmt.Errorf("write file: %w", err))
 	}
 
 	// Ensure all buffered data is written
 	if err := bufferedFile.Flush(); err != nil {
-		return fmt.Errorf("flush buffer: %w", err)
+		return writeError(asset, fileURL, fmt.Errorf("flush buffer: %w", err))
 	}
 
 	totalWritten := int64(n) + written
@@ -707,7 +1122,8 @@ func (d *Downloader) downloadFile(
 
 	// Video files must have minimum size
 	if opts.Kind == fileKindVideo && totalWritten < 1024 {
-		return fmt.Errorf("file too small (%d bytes), likely an error page", totalWritten)
+		return newDownloadError(CategoryInvalidContent, asset, fileURL, resp.StatusCode,
+			fmt.Errorf("file too small (%d bytes), likely an error page", totalWritten))
 	}
 
 	return nil
@@ -734,7 +1150,8 @@ type downloadJob struct {
 	TotalDocs int
 }
 
-// downloadDocuments downloads all documents to the specified directory using a worker pool.
+// downloadDocuments downloads all documents to the specified directory using a worker pool,
+// recording each outcome in assets. It returns the number downloaded.
 // This is more efficient than spawning a goroutine per document as it:
 // 1. Reuses goroutines instead of creating new ones for each document
 // 2. Reduces goroutine scheduling overhead
@@ -745,42 +1162,42 @@ func (d *Downloader) downloadDocuments(
 	destDir string,
 	cookies []*http.Cookie,
 	referer string,
+	assets *assetTracker,
 	logger Logger,
 ) int {
 	if len(docs) == 0 {
 		return 0
 	}
 
-	// Use shared pool if available
-	if d.pool != nil {
-		logInfo(logger, "downloading documents via pool", "count", len(docs))
-		return d.pool.WaitForDocuments(ctx, docs, destDir, cookies, referer)
-	}
-
-	// Fall back to local worker pool
 	if err := os.MkdirAll(destDir, 0o755); err != nil {
 		log(logger, "create documents dir failed", "error", err)
+		for _, doc := range docs {
+			assets.document(doc, "", 0, writeError(AssetDocument, doc.DownloadURL, err))
+		}
 		return 0
 	}
 
+	// Use shared pool if available
+	if d.pool != nil {
+		logInfo(logger, "downloading documents via pool", "count", len(docs))
+		return d.downloadDocumentsViaPool(ctx, docs, destDir, cookies, referer, assets)
+	}
+
 	// Use min(DefaultConcurrency, len(docs)) workers to avoid idle workers
 	numWorkers := minInt(DefaultConcurrency, len(docs))
 
 	// Create buffered jobs channel - buffer size equals docs to allow non-blocking sends
 	jobs := make(chan downloadJob, len(docs))
 
-	// Results channel for counting successful downloads
-	results := make(chan bool, len(docs))
-
 	// Start worker pool
 	var wg sync.WaitGroup
+	var downloaded atomic.Int32
 	for range make([]struct{}, numWorkers) {
-		wg.Add(1)
 		wg.Go(func() {
 			for job := range jobs {
 				// Check context cancellation
 				if ctx.Err() != nil {
-					results <- false
+					assets.document(job.Doc, "", 0, ctx.Err())
 					continue
 				}
 
@@ -788,16 +1205,19 @@ func (d *Downloader) downloadDocuments(
 				numStr := fmt.Sprintf("%d/%d", job.Index+1, job.TotalDocs)
 				logInfo(logger, "downloading document", "num", numStr, "name", job.Doc.Name)
 
-				if err := d.downloadFile(ctx, job.Doc.DownloadURL, destPath, downloadOptions{
+				start := time.Now()
+				err := d.downloadFile(ctx, job.Doc.DownloadURL, destPath, downloadOptions{
 					Cookies: job.Cookies,
 					Referer: job.Referer,
 					Kind:    fileKindBinary,
-				}, logger); err != nil {
+					Asset:   "document",
+				}, logger)
+				assets.document(job.Doc, destPath, time.Since(start), err)
+				if err != nil {
 					log(logger, "document download failed", "name", job.Doc.Name, "error", err)
-					results <- false
 					continue
 				}
-				results <- true
+				downloaded.Add(1)
 			}
 		})
 	}
@@ -817,15 +1237,48 @@ func (d *Downloader) downloadDocuments(
 
 	// Wait for all workers to finish
 	wg.Wait()
-	close(results)
 
-	// Count successful downloads
-	var downloaded int
-	for success := range results {
-		if success {
-			downloaded++
+	return int(downloaded.Load())
+}
+
+// downloadDocumentsViaPool queues every document on the shared pool and waits for them.
+// Durations include the time a document spent queued behind other recordings.
+func (d *Downloader) downloadDocumentsViaPool(
+	ctx context.Context,
+	docs []DocumentInfo,
+	destDir string,
+	cookies []*http.Cookie,
+	referer string,
+	assets *assetTracker,
+) int {
+	var pending sync.WaitGroup
+	var downloaded atomic.Int32
+	for _, doc := range docs {
+		destPath := filepath.Join(destDir, sanitize(doc.Name))
+		start := time.Now()
+		pending.Add(1)
+		submitted := d.pool.Submit(DownloadJob{
+			Type:     JobTypeDocument,
+			Name:     doc.Name,
+			URL:      doc.DownloadURL,
+			DestPath: destPath,
+			Cookies:  cookies,
+			Referer:  referer,
+			Kind:     fileKindBinary,
+			Ctx:      ctx,
+			OnComplete: func(err error) {
+				assets.document(doc, destPath, time.Since(start), err)
+				if err == nil {
+					downloaded.Add(1)
+				}
+				pending.Done()
+			},
+		})
+		if !submitted {
+			assets.document(doc, "", 0, errors.New("download pool stopped"))
+			pending.Done()
 		}
 	}
-
-	return downloaded
+	pending.Wait()
+	return int(downloaded.Load())
 }
@@ -101,6 +101,41 @@ func TestMissingMP4StillSucceedsWithZip(t *testing.T) {
 	if len(res.Warnings) == 0 {
 		t.Fatalf("expected warnings for missing mp4")
 	}
+	for name, state := range map[string]AssetState{
+		AssetMP4:        StateMissing,
+		AssetZip:        StateOK,
+		AssetCaptions:   StateMissing,
+		AssetTranscript: StateSkipped,
+		AssetChat:       StateMissing,
+	} {
+		if a, ok := res.Asset(name); !ok || a.State != state {
+			t.Errorf("%s: got %+v, want state %s", name, a, state)
+		}
+	}
+}
+
+func TestPageFailureSkipsMP4(t *testing.T) {
+	zipContent := createZip(t, map[string]string{"indexstream.xml": "<root/>"})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rec/output/rec.zip":
+			w.Write(zipContent)
+		default:
+			http.Error(w, "unavailable", http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	res, err := New(server.Client()).Download(context.Background(), server.URL+"/rec/", Options{OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("download error: %v", err)
+	}
+	if a, _ := res.Asset(AssetPage); a.State != StateFailed {
+		t.Errorf("page: got %+v, want failed", a)
+	}
+	if a, _ := res.Asset(AssetMP4); a.State != StateSkipped || a.Error != "page unavailable" {
+		t.Errorf("mp4: got %+v, want skipped because the page is unavailable", a)
+	}
 }
 
 func TestDiscoversMP4FromCASRecordingURL(t *testing.T) {
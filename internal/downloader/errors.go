@@ -0,0 +1,287 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// ErrorCategory classifies why a download failed, so callers can pick a
+// remediation without matching on error strings.
+type ErrorCategory string
+
+const (
+	CategoryUnknown          ErrorCategory = "unknown"
+	CategoryAuthExpired      ErrorCategory = "auth_expired"       // Session missing, expired or lacking permission
+	CategoryLoginWall        ErrorCategory = "login_wall"         // Server answered with its HTML login page
+	CategorySignedURLExpired ErrorCategory = "signed_url_expired" // Signed CAS media URL rejected
+	CategoryNotGenerated     ErrorCategory = "not_generated"      // Recording package not generated (yet)
+	CategoryRateLimited      ErrorCategory = "rate_limited"       // Server returned 429
+	CategoryInvalidContent   ErrorCategory = "invalid_content"    // Response body is not the expected file
+	CategoryNetwork          ErrorCategory = "network"            // Connection failed or was cut short
+	CategoryDiskFull         ErrorCategory = "disk_full"          // No space left on the output device
+	CategoryFilesystem       ErrorCategory = "filesystem"         // Output could not be created or written
+	CategoryToolMissing      ErrorCategory = "tool_missing"       // External tool such as MP4Box not installed
+)
+
+// ErrLoginWall indicates the server returned its login page instead of the requested resource.
+var ErrLoginWall = errors.New("server returned a login page")
+
+// ErrZipPreparing indicates the server is still generating the raw recording ZIP.
+var ErrZipPreparing = errors.New("server is still preparing the download")
+
+// DownloadError describes a failed request for a recording asset.
+type DownloadError struct {
+	Category ErrorCategory
+	Asset    string // "page", "zip", "mp4", "vtt", "document", ...
+	URL      string // Redacted: query values other than download/name are removed
+	Status   int    // HTTP status, 0 when no response was received
+	Err      error  // Underlying cause; may be one of the package sentinels
+}
+
+func (e *DownloadError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Asset)
+	b.WriteString(": ")
+	b.WriteString(e.summary())
+	if e.URL != "" {
+		b.WriteString(" [")
+		b.WriteString(e.URL)
+		b.WriteString("]")
+	}
+	return b.String()
+}
+
+func (e *DownloadError) Unwrap() error { return e.Err }
+
+// summary describes the failure without the asset and URL.
+func (e *DownloadError) summary() string {
+	s := e.Category.describe()
+	if e.Status != 0 {
+		s += fmt.Sprintf(" (HTTP %d)", e.Status)
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// Is matches the package sentinels implied by the category, so existing
+// errors.Is(err, ErrAuthRequired) and errors.Is(err, ErrNotFound) checks keep working.
+func (e *DownloadError) Is(target error) bool {
+	switch target {
+	case ErrAuthRequired:
+		return e.Category == CategoryAuthExpired || e.Category == CategoryLoginWall
+	case ErrNotFound:
+		return e.Category == CategoryNotGenerated
+	case ErrLoginWall:
+		return e.Category == CategoryLoginWall
+	}
+	return false
+}
+
+// newDownloadError builds a DownloadError with a redacted URL.
+func newDownloadError(category ErrorCategory, asset, rawURL string, status int, err error) *DownloadError {
+	return &DownloadError{Category: category, Asset: asset, URL: RedactURL(rawURL), Status: status, Err: err}
+}
+
+// describe returns a short human description of the category.
+func (c ErrorCategory) describe() string {
+	switch c {
+	case CategoryAuthExpired:
+		return "session expired or not authorised"
+	case CategoryLoginWall:
+		return "redirected to the login page"
+	case CategorySignedURLExpired:
+		return "signed media URL expired"
+	case CategoryNotGenerated:
+		return "not generated on the server"
+	case CategoryRateLimited:
+		return "rate limited by the server"
+	case CategoryInvalidContent:
+		return "unexpected response content"
+	case CategoryNetwork:
+		return "network error"
+	case CategoryDiskFull:
+		return "disk full"
+	case CategoryFilesystem:
+		return "could not write the output"
+	case CategoryToolMissing:
+		return "required tool missing"
+	default:
+		return "download failed"
+	}
+}
+
+// Remediation returns advice for the user on how to resolve a category of failure.
+func Remediation(c ErrorCategory) string {
+	switch c {
+	case CategoryAuthExpired:
+		return "Log into Adobe Connect in your browser, copy a fresh session token and pass it with --session."
+	case CategoryLoginWall:
+		return "The server asked for a login. Pass a valid session token with --session or append ?session=TOKEN."
+	case CategorySignedURLExpired:
+		return "The signed video URL expired or was already used. Run the download again to get a new one."
+	case CategoryNotGenerated:
+		return "Open the recording in your browser once so Connect generates it, then retry."
+	case CategoryRateLimited:
+		return "The server is throttling requests. Wait a few minutes or download fewer recordings at once."
+	case CategoryInvalidContent:
+		return "The server returned something other than the expected file. Retry, or run with -v for details."
+	case CategoryNetwork:
+		return "Check your network connection and retry."
+	case CategoryDiskFull:
+		return "Free up disk space or choose another output directory with --output."
+	case CategoryFilesystem:
+		return "Check that the output directory is writable and its disk works, or choose another with --output."
+	case CategoryToolMissing:
+		return "Install MP4Box (GPAC) or set ADOBECONNECTDL_MP4BOX to its path."
+	default:
+		return ""
+	}
+}
+
+// CategoryOf classifies any error returned by the downloader.
+func CategoryOf(err error) ErrorCategory {
+	if err == nil {
+		return ""
+	}
+	var de *DownloadError
+	if errors.As(err, &de) {
+		return de.Category
+	}
+	var netErr net.Error
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, syscall.ENOSPC):
+		return CategoryDiskFull
+	case errors.As(err, &pathErr):
+		return CategoryFilesystem
+	case errors.Is(err, exec.ErrNotFound):
+		return CategoryToolMissing
+	case errors.Is(err, ErrLoginWall):
+		return CategoryLoginWall
+	case errors.Is(err, ErrAuthRequired):
+		return CategoryAuthExpired
+	case errors.Is(err, ErrNotFound):
+		return CategoryNotGenerated
+	case errors.Is(err, ErrInvalidZip), errors.Is(err, ErrCorruptZip), errors.Is(err, ErrExtractLimit):
+		return CategoryInvalidContent
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr):
+		return CategoryNetwork
+	}
+	return CategoryUnknown
+}
+
+// RedactURL removes query values that may carry credentials (session tokens,
+// CAS signatures) so URLs are safe to log and print.
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+	q := u.Query()
+	for key := range q {
+		switch strings.ToLower(key) {
+		case "download", "name":
+		default:
+			q[key] = []string{"REDACTED"}
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// isLoginPage reports whether an HTML body is the Connect login form.
+func isLoginPage(body []byte) bool {
+	lower := bytes.ToLower(body)
+	if bytes.Contains(lower, []byte(`type="password"`)) || bytes.Contains(lower, []byte(`type='password'`)) {
+		return true
+	}
+	// Recording pages may link to /system/login, so only the title is trusted otherwise
+	return bytes.Contains(lower, []byte("<title>")) && bytes.Contains(lower, []byte("login</title>"))
+}
+
+// isPreparingPage reports whether an HTML body is the page Connect shows while
+// it generates a download package. Generic phrases like "please wait" are also
+// on login, SSO and proxy interstitials, so only Connect's own wording counts.
+func isPreparingPage(body []byte) bool {
+	lower := bytes.ToLower(body)
+	for _, marker := range []string{"preparing your download", "being prepared", "being generated"} {
+		if bytes.Contains(lower, []byte(marker)) {
+			return true
+		}
+	}
+	return false
+}
+
+// statusError maps an HTTP error status to a DownloadError.
+func statusError(asset, rawURL string, status int, kind fileKind) *DownloadError {
+	switch {
+	case status == 401 || status == 403:
+		if kind == fileKindVideo {
+			return newDownloadError(CategorySignedURLExpired, asset, rawURL, status, nil)
+		}
+		return newDownloadError(CategoryAuthExpired, asset, rawURL, status, nil)
+	case status == 404:
+		return newDownloadError(CategoryNotGenerated, asset, rawURL, status, ErrNotFound)
+	case status == 429:
+		return newDownloadError(CategoryRateLimited, asset, rawURL, status, nil)
+	default:
+		return newDownloadError(CategoryUnknown, asset, rawURL, status, fmt.Errorf("unexpected status %d", status))
+	}
+}
+
+// htmlError classifies an HTML page served in place of a file, given the
+// start of its body.
+func htmlError(asset, rawURL string, status int, kind fileKind, head []byte) *DownloadError {
+	switch {
+	case isLoginPage(head):
+		return newDownloadError(CategoryLoginWall, asset, rawURL, status, ErrLoginWall)
+	case kind == fileKindVideo:
+		return newDownloadError(CategoryInvalidContent, asset, rawURL, status,
+			errors.New("returned HTML instead of video"))
+	case kind == fileKindZip && (status == http.StatusAccepted || isPreparingPage(head)):
+		return newDownloadError(CategoryNotGenerated, asset, rawURL, status, ErrZipPreparing)
+	default:
+		return newDownloadError(CategoryNotGenerated, asset, rawURL, status, ErrNotFound)
+	}
+}
+
+// readError classifies an error reading a response body, which means the
+// transfer was cut short.
+func readError(asset, rawURL string, err error) *DownloadError {
+	return newDownloadError(CategoryNetwork, asset, rawURL, 0, err)
+}
+
+// writeError classifies an error creating, writing or moving a local file.
+func writeError(asset, rawURL string, err error) *DownloadError {
+	if errors.Is(err, syscall.ENOSPC) {
+		return newDownloadError(CategoryDiskFull, asset, rawURL, 0, err)
+	}
+	return newDownloadError(CategoryFilesystem, asset, rawURL, 0, err)
+}
+
+// bodyReader remembers the error of the last failed read, so a failed copy
+// can be told apart from a failed write.
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		b.err = err
+	}
+	return n, err
+}
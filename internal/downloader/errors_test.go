@@ -0,0 +1,207 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+const loginPageHTML = `<html><head><title>Adobe Connect Central Login</title></head>
+<body><form action="/system/login"><input type="password" name="password"></form></body></html>`
+
+func TestStatusErrorCategories(t *testing.T) {
+	tests := []struct {
+		status int
+		kind   fileKind
+		want   ErrorCategory
+	}{
+		{401, fileKindBinary, CategoryAuthExpired},
+		{403, fileKindBinary, CategoryAuthExpired},
+		{403, fileKindVideo, CategorySignedURLExpired},
+		{404, fileKindZip, CategoryNotGenerated},
+		{429, fileKindZip, CategoryRateLimited},
+		{502, fileKindBinary, CategoryUnknown},
+	}
+	for _, tt := range tests {
+		err := statusError("zip", "https://example.com/rec/output/rec.zip", tt.status, tt.kind)
+		if err.Category != tt.want || err.Status != tt.status {
+			t.Errorf("status %d kind %v: got %s (HTTP %d), want %s",
+				tt.status, tt.kind, err.Category, err.Status, tt.want)
+		}
+	}
+	if err := statusError("zip", "", 404, fileKindZip); !errors.Is(err, ErrNotFound) {
+		t.Errorf("404 should match ErrNotFound: %v", err)
+	}
+}
+
+func TestCategoryOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorCategory
+	}{
+		{nil, ""},
+		{errors.New("boom"), CategoryUnknown},
+		{fmt.Errorf("write: %w", syscall.ENOSPC), CategoryDiskFull},
+		{fmt.Errorf("MP4Box %w", exec.ErrNotFound), CategoryToolMissing},
+		{&fs.PathError{Op: "open", Path: "/out/rec", Err: syscall.EROFS}, CategoryFilesystem},
+		{fmt.Errorf("preflight: %w", ErrAuthRequired), CategoryAuthExpired},
+		{ErrInvalidZip, CategoryInvalidContent},
+		{context.DeadlineExceeded, CategoryNetwork},
+		{newDownloadError(CategoryRateLimited, "zip", "", 429, nil), CategoryRateLimited},
+		{errors.Join(newDownloadError(CategoryLoginWall, "page", "", 200, ErrLoginWall)), CategoryLoginWall},
+	}
+	for _, tt := range tests {
+		if got := CategoryOf(tt.err); got != tt.want {
+			t.Errorf("CategoryOf(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadFileSplitsLocalAndNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cut" {
+			// Promise more than is sent, so the body read fails midway
+			w.Header().Set("Content-Length", "1000000")
+			w.Write(make([]byte, 8192))
+			return
+		}
+		w.Write([]byte("%PDF-1.4\n"))
+	}))
+	defer server.Close()
+
+	readOnly := t.TempDir()
+	if err := os.Chmod(readOnly, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(readOnly, 0o755) })
+	notDir := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(notDir, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, path, dest string
+		want             ErrorCategory
+	}{
+		{"read-only directory", "/doc", filepath.Join(readOnly, "doc.pdf"), CategoryFilesystem},
+		{"parent is a file", "/doc", filepath.Join(notDir, "doc.pdf"), CategoryFilesystem},
+		{"body cut short", "/cut", filepath.Join(t.TempDir(), "doc.pdf"), CategoryNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.dest == filepath.Join(readOnly, "doc.pdf") && os.Geteuid() == 0 {
+				t.Skip("root can write to read-only directories")
+			}
+			err := New(server.Client()).downloadFile(context.Background(), server.URL+tt.path, tt.dest,
+				downloadOptions{Kind: fileKindBinary, Asset: AssetDocument}, nil)
+			if got := CategoryOf(err); got != tt.want {
+				t.Errorf("CategoryOf(%v) = %q, want %q", err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	got := RedactURL("https://example.com/rec/output/rec.zip?download=zip&session=secret&token=sig")
+	if strings.Contains(got, "secret") || strings.Contains(got, "sig") {
+		t.Errorf("credentials leaked: %s", got)
+	}
+	if !strings.Contains(got, "download=zip") {
+		t.Errorf("download parameter should be kept: %s", got)
+	}
+	if got := RedactURL("https://example.com/rec/"); got != "https://example.com/rec/" {
+		t.Errorf("URL without query changed: %s", got)
+	}
+}
+
+func TestIsLoginPage(t *testing.T) {
+	if !isLoginPage([]byte(loginPageHTML)) {
+		t.Error("expected login form to be detected")
+	}
+	recording := `<html><head><title>Week 1</title></head><body><a href="/system/login">Log in</a></body></html>`
+	if isLoginPage([]byte(recording)) {
+		t.Error("recording page with a login link is not a login page")
+	}
+}
+
+func TestHTMLErrorForZip(t *testing.T) {
+	tests := []struct {
+		name, body string
+		want       error
+		preparing  bool
+	}{
+		{"preparing", `<p>Preparing your download. This may take a few minutes, please wait...</p>`, ErrNotFound, true},
+		{"login", strings.Replace(loginPageHTML, "<form", "<p>Please wait</p><form", 1), ErrLoginWall, false},
+		{"sso", `<html><head><title>Signing in</title></head><body>Please wait while we redirect you.</body></html>`,
+			ErrNotFound, false},
+	}
+	for _, tt := range tests {
+		err := htmlError(AssetZip, "https://connect.example.edu/p1/output/p1.zip", http.StatusOK, fileKindZip,
+			[]byte(tt.body))
+		if !errors.Is(err, tt.want) || errors.Is(err, ErrZipPreparing) != tt.preparing {
+			t.Errorf("%s: htmlError = %v, want %v (preparing %v)", tt.name, err, tt.want, tt.preparing)
+		}
+	}
+}
+
+func TestLoginPageForRecordingPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, loginPageHTML)
+	}))
+	defer server.Close()
+
+	_, err := New(server.Client()).Download(context.Background(), server.URL+"/rec/?session=secret",
+		Options{OutputDir: t.TempDir()})
+	if !errors.Is(err, ErrLoginWall) || !errors.Is(err, ErrAuthRequired) {
+		t.Fatalf("expected login wall error, got %v", err)
+	}
+	var de *DownloadError
+	if !errors.As(err, &de) || de.Asset != "page" {
+		t.Fatalf("expected page DownloadError, got %#v", err)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("session leaked into error: %v", err)
+	}
+}
+
+func TestLoginPageForZipIsWarning(t *testing.T) {
+	mp4Content := make([]byte, 2048) // Large enough to pass size check
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rec/":
+			fmt.Fprintf(w, `<html><head><title>Login Zip</title></head>
+<body><script>var casRecordingURL = '%s/rec/output/rec.mp4';</script></body></html>`, server.URL)
+		case "/rec/output/rec.mp4":
+			w.Write(mp4Content)
+		case "/rec/output/rec.zip":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, loginPageHTML)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	res, err := New(server.Client()).Download(context.Background(), server.URL+"/rec/", Options{OutputDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("download error: %v", err)
+	}
+	var found bool
+	for _, w := range res.Warnings {
+		found = found || strings.Contains(w, "redirected to the login page")
+	}
+	if !found {
+		t.Errorf("expected login wall warning, got %v", res.Warnings)
+	}
+}
@@ -0,0 +1,54 @@
+package downloader
+
+// EventKind identifies the type of a download Event.
+type EventKind string
+
+const (
+	EventStarted     EventKind = "started"      // Title and output directory resolved
+	EventProgress    EventKind = "progress"     // Bytes received for a large asset
+	EventWaiting     EventKind = "waiting"      // Server is still preparing an asset; Message says when we retry
+	EventAssetDone   EventKind = "asset_done"   // An asset was saved
+	EventAssetFailed EventKind = "asset_failed" // An asset could not be saved
+	EventWarning     EventKind = "warning"      // Non-fatal problem, also recorded in the result
+	EventFinished    EventKind = "finished"     // Download returned successfully
+)
+
+// Event reports download progress to Options.OnEvent.
+type Event struct {
+	Kind       EventKind
+	URL        string // Recording URL passed to Download, redacted like RedactURL
+	Asset      string // Asset name ("zip", "mp4", "captions", ...), empty for recording-level events
+	Path       string // Local path of the asset or recording directory
+	Downloaded int64  // Bytes received so far (EventProgress)
+	Total      int64  // Total bytes expected (EventProgress)
+	Message    string
+	Err        error
+}
+
+// EventHandler receives download events. It may be called from several
+// goroutines at once and must not block.
+type EventHandler func(Event)
+
+// emit sends an event if a handler is configured. The URL is redacted, as
+// it may carry a session token.
+func emit(handler EventHandler, e Event) {
+	if handler != nil {
+		e.URL = RedactURL(e.URL)
+		handler(e)
+	}
+}
+
+// progressWithEvents combines the progress callback with progress events for an asset.
+func progressWithEvents(opts Options, rawURL, asset string) ProgressCallback {
+	if opts.OnEvent == nil {
+		return opts.OnProgress
+	}
+	// Redacted once here rather than by emit for every progress event
+	redacted := RedactURL(rawURL)
+	return func(downloaded, total int64) {
+		if opts.OnProgress != nil {
+			opts.OnProgress(downloaded, total)
+		}
+		opts.OnEvent(Event{Kind: EventProgress, URL: redacted, Asset: asset, Downloaded: downloaded, Total: total})
+	}
+}
@@ -0,0 +1,177 @@
+package downloader
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrExtractLimit indicates a ZIP archive would expand beyond the extraction
+// limits, as a zip bomb would.
+var ErrExtractLimit = errors.New("zip exceeds extraction limits")
+
+// ErrCorruptZip indicates a ZIP entry failed its CRC or size check.
+var ErrCorruptZip = errors.New("zip archive is corrupt")
+
+// ExtractLimits bounds what a ZIP archive may expand to. Zero fields use the
+// defaults from DefaultExtractLimits; negative fields disable that check.
+type ExtractLimits struct {
+	MaxFiles     int   // Entries extracted
+	MaxTotalSize int64 // Uncompressed bytes across all entries
+	MaxRatio     int   // Uncompressed to compressed size of one entry larger than 1 MiB
+}
+
+// ratioMinSize is the smallest entry the compression ratio limit applies to;
+// small, highly repetitive XML streams legitimately compress very well.
+const ratioMinSize = 1 << 20
+
+// DefaultExtractLimits returns limits that comfortably fit long recordings.
+func DefaultExtractLimits() ExtractLimits {
+	return ExtractLimits{
+		MaxFiles:     10000,
+		MaxTotalSize: 64 << 30,
+		MaxRatio:     200,
+	}
+}
+
+// withDefaults fills zero fields from DefaultExtractLimits.
+func (l ExtractLimits) withDefaults() ExtractLimits {
+	def := DefaultExtractLimits()
+	if l.MaxFiles == 0 {
+		l.MaxFiles = def.MaxFiles
+	}
+	if l.MaxTotalSize == 0 {
+		l.MaxTotalSize = def.MaxTotalSize
+	}
+	if l.MaxRatio == 0 {
+		l.MaxRatio = def.MaxRatio
+	}
+	return l
+}
+
+// checkEntry checks the declared sizes of one more entry, given the number of
+// entries and bytes accepted so far.
+func (l ExtractLimits) checkEntry(name string, files int, total int64, size, compressed uint64) error {
+	if l.MaxFiles > 0 && files >= l.MaxFiles {
+		return fmt.Errorf("%w: more than %d entries", ErrExtractLimit, l.MaxFiles)
+	}
+	if l.MaxTotalSize > 0 && size > uint64(l.MaxTotalSize-total) {
+		return fmt.Errorf("%w: more than %d bytes uncompressed", ErrExtractLimit, l.MaxTotalSize)
+	}
+	if l.MaxRatio > 0 && size > ratioMinSize && size/max(compressed, 1) > uint64(l.MaxRatio) {
+		return fmt.Errorf("%w: %s compresses more than %d:1", ErrExtractLimit, name, l.MaxRatio)
+	}
+	return nil
+}
+
+// safeTarget joins an entry name to dest, rejecting names that escape it.
+func safeTarget(dest, name string) (string, error) {
+	target := filepath.Join(dest, name)
+	if !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("unsafe path in zip: %s", name)
+	}
+	return target, nil
+}
+
+// extractZip extracts a ZIP archive to the destination directory.
+func extractZip(zipPath, dest string, limits ExtractLimits) error {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return extractFiles(&r.Reader, dest, nil, limits)
+}
+
+// extractFiles extracts the entries of r accepted by keep (all entries when
+// keep is nil) to the destination directory. Limits are checked against the
+// central directory before anything is written. Symlinks, devices and other
+// special entries are skipped, and permissions from the archive are ignored:
+// files are written 0644 and directories 0755.
+func extractFiles(r *zip.Reader, dest string, keep func(name string) bool, limits ExtractLimits) error {
+	files, err := checkFiles(r, keep, limits)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		target, err := safeTarget(dest, f.Name)
+		if err != nil {
+			return err
+		}
+		switch mode := f.Mode(); {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			if err := extractFile(f, target); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkFiles returns the entries of r accepted by keep (all entries when keep
+// is nil) after checking them against the limits.
+func checkFiles(r *zip.Reader, keep func(name string) bool, limits ExtractLimits) ([]*zip.File, error) {
+	limits = limits.withDefaults()
+	var files []*zip.File
+	var total int64
+	for _, f := range r.File {
+		if keep != nil && !keep(f.Name) {
+			continue
+		}
+		// Only regular files are written, but every entry counts towards MaxFiles
+		size, compressed := f.UncompressedSize64, f.CompressedSize64
+		if !f.Mode().IsRegular() {
+			size, compressed = 0, 0
+		}
+		if err := limits.checkEntry(f.Name, len(files), total, size, compressed); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+		total += int64(size)
+	}
+	return files, nil
+}
+
+// extractFile writes one regular entry. archive/zip stops reading at the
+// declared size and verifies the CRC at EOF, so the entry is always read to
+// the end; a partially written file is removed on failure.
+func extractFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return corruptEntry(f.Name, err)
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, rc)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(target)
+		return corruptEntry(f.Name, err)
+	}
+	return nil
+}
+
+// corruptEntry wraps archive/zip checksum and format errors in ErrCorruptZip.
+func corruptEntry(name string, err error) error {
+	if errors.Is(err, zip.ErrChecksum) || errors.Is(err, zip.ErrFormat) {
+		return fmt.Errorf("%w: %s: %w", ErrCorruptZip, name, err)
+	}
+	return err
+}
@@ -0,0 +1,231 @@
+package downloader
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeZipFile writes data to a temporary file and returns its path.
+func writeZipFile(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.zip")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// createStoredZip creates a ZIP whose entry is stored without a data
+// descriptor, using crc as its checksum.
+func createStoredZip(t *testing.T, name, content string, crc uint32) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	fw, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               name,
+		Method:             zip.Store,
+		CRC32:              crc,
+		CompressedSize64:   uint64(len(content)),
+		UncompressedSize64: uint64(len(content)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte(content))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractLimits(t *testing.T) {
+	bomb := createZip(t, map[string]string{"zeros.flv": strings.Repeat("\x00", 4<<20)})
+	many := createZip(t, map[string]string{"a.xml": "a", "b.xml": "b", "c.xml": "c"})
+	large := createZip(t, map[string]string{"a.xml": strings.Repeat("x", 200)})
+	dirs := createZip(t, map[string]string{"a/": "", "b/": "", "c/": ""})
+
+	tests := []struct {
+		name   string
+		data   []byte
+		limits ExtractLimits
+	}{
+		{"compression ratio", bomb, ExtractLimits{}},
+		{"entry count", many, ExtractLimits{MaxFiles: 2}},
+		{"directory count", dirs, ExtractLimits{MaxFiles: 2}},
+		{"total size", large, ExtractLimits{MaxTotalSize: 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			err := extractZip(writeZipFile(t, tt.data), dest, tt.limits)
+			if !errors.Is(err, ErrExtractLimit) {
+				t.Fatalf("expected ErrExtractLimit, got %v", err)
+			}
+			if CategoryOf(err) != CategoryInvalidContent {
+				t.Errorf("category = %s", CategoryOf(err))
+			}
+			if entries, _ := os.ReadDir(dest); len(entries) != 0 {
+				t.Errorf("nothing should be extracted, got %d entries", len(entries))
+			}
+
+			_, err = streamExtract(bytes.NewReader(tt.data), t.TempDir(), tt.limits)
+			if !errors.Is(err, ErrExtractLimit) {
+				t.Errorf("stream: expected ErrExtractLimit, got %v", err)
+			}
+		})
+	}
+
+	if err := extractZip(writeZipFile(t, many), t.TempDir(), ExtractLimits{MaxFiles: -1}); err != nil {
+		t.Errorf("negative limit should disable the check: %v", err)
+	}
+}
+
+func TestExtractSkipsSpecialEntriesAndNormalisesModes(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range []struct {
+		name    string
+		mode    os.FileMode
+		content string
+	}{
+		{"link.xml", os.ModeSymlink | 0o777, "/etc/passwd"},
+		{"fifo", os.ModeNamedPipe | 0o644, ""},
+		{"script.sh", os.ModeSetuid | 0o777, "echo hi"},
+		{"dir/", os.ModeDir | 0o700, ""},
+	} {
+		fh := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		fh.SetMode(e.mode)
+		fw, err := zw.CreateHeader(fh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(e.content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zipPath := writeZipFile(t, buf.Bytes())
+	dest := t.TempDir()
+	if err := extractZip(zipPath, dest, ExtractLimits{}); err != nil {
+		t.Fatalf("extractZip error: %v", err)
+	}
+	// Streaming can't see entry modes, so completion removes what it wrote for them
+	streamDest := t.TempDir()
+	streamed, err := streamExtract(bytes.NewReader(buf.Bytes()), streamDest, ExtractLimits{})
+	if err != nil {
+		t.Fatalf("streamExtract error: %v", err)
+	}
+	if err := completeStreamExtract(zipPath, streamDest, streamed, ExtractLimits{}); err != nil {
+		t.Fatalf("completeStreamExtract error: %v", err)
+	}
+	for _, name := range []string{"link.xml", "fifo"} {
+		for _, dir := range []string{dest, streamDest} {
+			if _, err := os.Lstat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+				t.Errorf("%s should be skipped, got %v", name, err)
+			}
+		}
+	}
+	if st, err := os.Stat(filepath.Join(dest, "script.sh")); err != nil || st.Mode() != 0o644 {
+		t.Errorf("script.sh mode = %v, err = %v", st.Mode(), err)
+	}
+	if st, err := os.Stat(filepath.Join(dest, "dir")); err != nil || st.Mode().Perm() != 0o755 {
+		t.Errorf("dir mode = %v, err = %v", st.Mode(), err)
+	}
+}
+
+func TestExtractDetectsChecksumMismatch(t *testing.T) {
+	data := createStoredZip(t, "chat.xml", "hello", crc32.ChecksumIEEE([]byte("hellp")))
+
+	dest := t.TempDir()
+	err := extractZip(writeZipFile(t, data), dest, ExtractLimits{})
+	if !errors.Is(err, ErrCorruptZip) || !strings.Contains(err.Error(), "chat.xml") {
+		t.Fatalf("expected ErrCorruptZip naming the entry, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "chat.xml")); !os.IsNotExist(err) {
+		t.Error("corrupt entry should be removed")
+	}
+
+	if _, err := streamExtract(bytes.NewReader(data), t.TempDir(), ExtractLimits{}); !errors.Is(err, ErrCorruptZip) {
+		t.Errorf("stream: expected ErrCorruptZip, got %v", err)
+	}
+}
+
+func TestStreamExtract(t *testing.T) {
+	files := map[string]string{
+		"indexstream.xml": "<root>" + strings.Repeat("<Message/>", 1000) + "</root>",
+		"rec.vtt":         "WEBVTT\n",
+		"sub/chat.xml":    "<root/>",
+	}
+	dest := t.TempDir()
+	written, err := streamExtract(bytes.NewReader(createZip(t, files)), dest, ExtractLimits{})
+	if err != nil {
+		t.Fatalf("streamExtract error: %v", err)
+	}
+	if len(written) != len(files) {
+		t.Errorf("written = %v", written)
+	}
+	for name, content := range files {
+		assertFileContent(t, filepath.Join(dest, name), []byte(content))
+	}
+
+	stored := createStoredZip(t, "fttitle0.xml", "<root/>", crc32.ChecksumIEEE([]byte("<root/>")))
+	if written, err := streamExtract(bytes.NewReader(stored), t.TempDir(), ExtractLimits{}); err != nil ||
+		written["fttitle0.xml"] != 7 {
+		t.Errorf("stored entry: written = %v, err = %v", written, err)
+	}
+}
+
+func TestStreamExtractCompletesFromArchive(t *testing.T) {
+	// The stored FLV has a data descriptor, so streaming stops at the first entry
+	data := createRawZip(t)
+	dest := t.TempDir()
+
+	stream := startZipStream(dest, ExtractLimits{})
+	if _, err := stream.Write(data); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	streamed, err := stream.finish(nil)
+	if err != nil {
+		t.Fatalf("finish error: %v", err)
+	}
+	if len(streamed) != 0 {
+		t.Errorf("expected nothing streamed, got %v", streamed)
+	}
+
+	if err := completeStreamExtract(writeZipFile(t, data), dest, streamed, ExtractLimits{}); err != nil {
+		t.Fatalf("completeStreamExtract error: %v", err)
+	}
+	for _, name := range []string{"cameraVoip_1_3.flv", "indexstream.xml", "rec.vtt"} {
+		if _, err := os.Stat(filepath.Join(dest, name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+}
+
+func TestCompleteStreamExtractCountsStreamedEntries(t *testing.T) {
+	data := createZip(t, map[string]string{"a.xml": "<root/>", "b.xml": "<root/>", "c.xml": "<root/>"})
+	dest := t.TempDir()
+	streamed, err := streamExtract(bytes.NewReader(data), dest, ExtractLimits{})
+	if err != nil {
+		t.Fatalf("streamExtract error: %v", err)
+	}
+	// Two entries were streamed within the limit, and the remaining one is
+	// within it on its own
+	delete(streamed, "c.xml")
+	os.Remove(filepath.Join(dest, "c.xml"))
+
+	err = completeStreamExtract(writeZipFile(t, data), dest, streamed, ExtractLimits{MaxFiles: 2})
+	if !errors.Is(err, ErrExtractLimit) {
+		t.Errorf("completeStreamExtract error = %v, want ErrExtractLimit", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "c.xml")); !os.IsNotExist(err) {
+		t.Errorf("c.xml extracted past the limit: %v", err)
+	}
+}
@@ -0,0 +1,315 @@
+package downloader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// CandidateKind identifies what a discovered URL points to.
+type CandidateKind string
+
+const (
+	CandidateVideo    CandidateKind = "video"
+	CandidateCaptions CandidateKind = "captions"
+)
+
+// Candidate is a media URL discovered in a recording page.
+type Candidate struct {
+	Kind       CandidateKind
+	URL        string
+	Confidence float64 // 0..1, higher wins when extractors disagree
+	Extractor  string  // Name of the extractor that produced this candidate
+	// PageRelative marks a URL that may be relative to the recording page
+	// rather than to the recording's output folder.
+	PageRelative bool
+}
+
+// PageExtractor discovers candidate video and caption URLs in a recording page.
+// Implementations should return nil when the page doesn't match their player.
+type PageExtractor interface {
+	Name() string
+	Extract(body []byte) []Candidate
+}
+
+// ExtractorRegistry holds page extractors, tried in registration order.
+type ExtractorRegistry struct {
+	extractors []PageExtractor
+}
+
+// NewExtractorRegistry creates a registry with the given extractors.
+func NewExtractorRegistry(extractors ...PageExtractor) *ExtractorRegistry {
+	return &ExtractorRegistry{extractors: extractors}
+}
+
+// DefaultExtractors returns a registry with the built-in extractors for the
+// HTML5 player, the legacy Flash player and the CAS JavaScript variables.
+func DefaultExtractors() *ExtractorRegistry {
+	return NewExtractorRegistry(html5Extractor{}, flashExtractor{}, casJSExtractor{})
+}
+
+// Register appends an extractor; it is tried after those already registered.
+func (r *ExtractorRegistry) Register(e PageExtractor) {
+	r.extractors = append(r.extractors, e)
+}
+
+// Extractors returns the registered extractors in order.
+func (r *ExtractorRegistry) Extractors() []PageExtractor {
+	return append([]PageExtractor(nil), r.extractors...)
+}
+
+// Discover runs every extractor over the page and picks the best video and
+// captions candidates. Higher confidence wins; ties go to the earlier extractor.
+func (r *ExtractorRegistry) Discover(body []byte, logger Logger) (video, captions Candidate) {
+	for _, e := range r.extractors {
+		for _, c := range e.Extract(body) {
+			if c.URL == "" {
+				continue
+			}
+			c.Extractor = e.Name()
+			log(logger, "extractor matched", "extractor", c.Extractor, "kind", c.Kind,
+				"confidence", c.Confidence, "url", truncateURL(c.URL))
+			switch c.Kind {
+			case CandidateVideo:
+				if c.Confidence > video.Confidence {
+					video = c
+				}
+			case CandidateCaptions:
+				if c.Confidence > captions.Confidence {
+					captions = c
+				}
+			}
+		}
+	}
+	return video, captions
+}
+
+// resolveCandidate returns the URL of c, resolved against the page it was
+// found on when it is page-relative.
+func resolveCandidate(page *url.URL, c Candidate) string {
+	if !c.PageRelative || page == nil || c.URL == "" {
+		return c.URL
+	}
+	ref, err := url.Parse(c.URL)
+	if err != nil {
+		return c.URL
+	}
+	return page.ResolveReference(ref).String()
+}
+
+// names returns the registered extractor names for diagnostics.
+func (r *ExtractorRegistry) names() []string {
+	names := make([]string, 0, len(r.extractors))
+	for _, e := range r.extractors {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+// html5Extractor reads <video src> and <track src> from the HTML5 player markup.
+type html5Extractor struct{}
+
+func (html5Extractor) Name() string { return "html5-player" }
+
+func (html5Extractor) Extract(body []byte) []Candidate {
+	videoSrc, vttPath := parseVideoElement(body)
+	var out []Candidate
+	if videoSrc != "" {
+		// The video element often points at a player shim, so CAS JS is preferred
+		out = append(out, Candidate{Kind: CandidateVideo, URL: videoSrc, Confidence: 0.7})
+	}
+	if vttPath != "" {
+		out = append(out, Candidate{Kind: CandidateCaptions, URL: vttPath, Confidence: 0.9})
+	}
+	return out
+}
+
+// casJSExtractor reads casRecordingURL and transcriptFilename from the page JavaScript.
+type casJSExtractor struct{}
+
+func (casJSExtractor) Name() string { return "cas-js" }
+
+func (casJSExtractor) Extract(body []byte) []Candidate {
+	var out []Candidate
+	if casURL := findCASRecordingURL(body); casURL != "" {
+		out = append(out, Candidate{Kind: CandidateVideo, URL: casURL, Confidence: 0.9})
+	}
+	if vtt := findVTTFromJS(body); vtt != "" {
+		confidence := 0.8
+		if strings.HasPrefix(vtt, "/") {
+			// Bare .vtt path literal rather than the transcriptFilename variable
+			confidence = 0.5
+		}
+		out = append(out, Candidate{Kind: CandidateCaptions, URL: vtt, Confidence: confidence})
+	}
+	return out
+}
+
+// flashExtractor reads media URLs from the flashvars of the legacy Flash player,
+// either as an <embed>/<object> attribute or a swfobject JavaScript object.
+// Its URLs are relative to the page. FLV videos are left out: the downloader
+// only fetches MP4 renditions and rebuilds the video from the raw streams
+// otherwise.
+type flashExtractor struct{}
+
+func (flashExtractor) Name() string { return "legacy-flash-player" }
+
+var (
+	// flashVarsJSRe matches a swfobject flashvars object literal, e.g.
+	// "var flashvars = { file: '...' };".
+	flashVarsJSRe = regexp.MustCompile(`(?i)\bflashvars\s*=\s*\{([^}]*)\}`)
+	// flashVarJSRe matches a media property of a flashvars object literal.
+	flashVarJSRe = regexp.MustCompile(
+		`(?i)(?:^|[{,])\s*['"]?(?:file|src|video|url|captions|transcript)['"]?\s*:\s*['"]([^'"]+)['"]`,
+	)
+)
+
+func (flashExtractor) Extract(body []byte) []Candidate {
+	var values []string
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for tt := z.Next(); tt != html.ErrorToken; tt = z.Next() {
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		t := z.Token()
+		switch t.Data {
+		case "embed", "object":
+			for _, a := range t.Attr {
+				if strings.EqualFold(a.Key, "flashvars") {
+					values = append(values, flashVarValues(a.Val)...)
+				}
+			}
+		case "param":
+			var name, value string
+			for _, a := range t.Attr {
+				switch strings.ToLower(a.Key) {
+				case "name":
+					name = a.Val
+				case "value":
+					value = a.Val
+				}
+			}
+			if strings.EqualFold(name, "flashvars") {
+				values = append(values, flashVarValues(value)...)
+			}
+		}
+	}
+	for _, obj := range flashVarsJSRe.FindAllSubmatch(body, -1) {
+		for _, m := range flashVarJSRe.FindAllSubmatch(obj[1], -1) {
+			values = append(values, unescapeJS(string(m[1])))
+		}
+	}
+
+	var out []Candidate
+	for _, v := range values {
+		lower := strings.ToLower(v)
+		if i := strings.IndexAny(lower, "?#"); i >= 0 {
+			lower = lower[:i]
+		}
+		switch {
+		case strings.HasSuffix(lower, ".mp4"):
+			out = append(out, Candidate{Kind: CandidateVideo, URL: v, Confidence: 0.4, PageRelative: true})
+		case strings.HasSuffix(lower, ".vtt"):
+			out = append(out, Candidate{Kind: CandidateCaptions, URL: v, Confidence: 0.4, PageRelative: true})
+		}
+	}
+	return out
+}
+
+// flashVarValues returns the values of a URL-encoded flashvars string.
+func flashVarValues(flashVars string) []string {
+	q, err := url.ParseQuery(flashVars)
+	if err != nil {
+		return nil
+	}
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var out []string
+	for _, k := range keys {
+		out = append(out, q[k]...)
+	}
+	return out
+}
+
+// PatternRule is a per-institution extraction rule loaded from configuration.
+// The first capture group of Pattern (or the whole match) is the URL.
+type PatternRule struct {
+	Name       string  `json:"name"`
+	Kind       string  `json:"kind"` // "video" or "captions"
+	Pattern    string  `json:"pattern"`
+	Confidence float64 `json:"confidence,omitempty"` // Defaults to 1.0
+}
+
+// patternExtractor applies a PatternRule to the raw page body.
+type patternExtractor struct {
+	name       string
+	kind       CandidateKind
+	re         *regexp.Regexp
+	confidence float64
+}
+
+// NewPatternExtractor compiles a PatternRule into a PageExtractor.
+func NewPatternExtractor(rule PatternRule) (PageExtractor, error) {
+	kind := CandidateKind(strings.ToLower(rule.Kind))
+	if kind != CandidateVideo && kind != CandidateCaptions {
+		return nil, fmt.Errorf("pattern %q: kind must be %q or %q", rule.Name, CandidateVideo, CandidateCaptions)
+	}
+	if rule.Pattern == "" {
+		return nil, fmt.Errorf("pattern %q: empty pattern", rule.Name)
+	}
+	re, err := regexp.Compile(rule.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("pattern %q: %w", rule.Name, err)
+	}
+	confidence := rule.Confidence
+	if confidence <= 0 {
+		confidence = 1.0
+	}
+	name := rule.Name
+	if name == "" {
+		name = "custom"
+	}
+	return &patternExtractor{name: "pattern:" + name, kind: kind, re: re, confidence: confidence}, nil
+}
+
+func (p *patternExtractor) Name() string { return p.name }
+
+func (p *patternExtractor) Extract(body []byte) []Candidate {
+	m := p.re.FindSubmatch(body)
+	if m == nil {
+		return nil
+	}
+	match := m[0]
+	if len(m) > 1 {
+		match = m[1]
+	}
+	return []Candidate{{Kind: p.kind, URL: unescapeJS(string(match)), Confidence: p.confidence}}
+}
+
+// LoadPatternRules reads a JSON array of PatternRule from path.
+func LoadPatternRules(path string) ([]PatternRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []PatternRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse pattern rules: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil, errors.New("pattern rules file contains no rules")
+	}
+	return rules, nil
+}
@@ -0,0 +1,95 @@
+package downloader
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultExtractorsPreferCASOverVideoSrc(t *testing.T) {
+	body := []byte(`<html><body>
+<video src="/player/shim.mp4"><track src="/rec/output/captions.vtt"></video>
+<script>var casRecordingURL = 'https://cdn.example.com/rec.mp4?sign=abc';
+var transcriptFilename = 'Lecture\x201.vtt';</script></body></html>`)
+
+	video, captions := DefaultExtractors().Discover(body, nil)
+	if video.URL != "https://cdn.example.com/rec.mp4?sign=abc" || video.Extractor != "cas-js" {
+		t.Errorf("unexpected video candidate: %+v", video)
+	}
+	if captions.URL != "/rec/output/captions.vtt" || captions.Extractor != "html5-player" {
+		t.Errorf("unexpected captions candidate: %+v", captions)
+	}
+}
+
+func TestFlashExtractor(t *testing.T) {
+	body := []byte(`<object type="application/x-shockwave-flash" data="player.swf">
+<param name="movie" value="player.swf">
+<param name="flashvars" value="file=lecture.mp4&amp;captions=%2Frec%2Foutput%2Flecture.vtt&amp;autoplay=true">
+</object>`)
+
+	page, _ := url.Parse("https://connect.example.com/p1abc/?launcher=false")
+	video, captions := DefaultExtractors().Discover(body, nil)
+	if video.Extractor != "legacy-flash-player" ||
+		resolveCandidate(page, video) != "https://connect.example.com/p1abc/lecture.mp4" {
+		t.Errorf("unexpected video candidate: %+v", video)
+	}
+	if got := resolveCandidate(page, captions); got != "https://connect.example.com/rec/output/lecture.vtt" {
+		t.Errorf("captions resolved to %q", got)
+	}
+}
+
+func TestFlashExtractorSkipsFLVAndOtherScripts(t *testing.T) {
+	body := []byte(`<embed src="player.swf" flashvars="file=%2Frec%2Foutput%2Flecture.flv">
+<script>var flashvars = { file: "/rec/output/lecture.flv", "captions": "/rec/output/lecture.vtt" };
+slider.init({ src: "/img/banner.mp4" });</script>`)
+
+	got := flashExtractor{}.Extract(body)
+	if len(got) != 1 || got[0].Kind != CandidateCaptions || got[0].URL != "/rec/output/lecture.vtt" {
+		t.Errorf("candidates = %+v, want only the flashvars captions", got)
+	}
+}
+
+func TestPatternExtractorOverridesDefaults(t *testing.T) {
+	body := []byte(`<script>var casRecordingURL = 'https://cdn.example.com/old.mp4';
+window.player.load({ mediaUrl: "https://cdn.example.com/new.mp4" });</script>`)
+
+	rules := []PatternRule{{Name: "uni", Kind: "video", Pattern: `mediaUrl:\s*"([^"]+)"`}}
+	path := filepath.Join(t.TempDir(), "patterns.json")
+	if err := os.WriteFile(path, []byte(`[{"name":"uni","kind":"video","pattern":"mediaUrl:\\s*\"([^\"]+)\""}]`),
+		0o644); err != nil {
+		t.Fatalf("write patterns: %v", err)
+	}
+	loaded, err := LoadPatternRules(path)
+	if err != nil {
+		t.Fatalf("LoadPatternRules error: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0] != rules[0] {
+		t.Fatalf("unexpected rules: %+v", loaded)
+	}
+
+	registry := DefaultExtractors()
+	extractor, err := NewPatternExtractor(loaded[0])
+	if err != nil {
+		t.Fatalf("NewPatternExtractor error: %v", err)
+	}
+	registry.Register(extractor)
+
+	video, _ := registry.Discover(body, nil)
+	if video.URL != "https://cdn.example.com/new.mp4" || video.Extractor != "pattern:uni" {
+		t.Errorf("unexpected video candidate: %+v", video)
+	}
+}
+
+func TestNewPatternExtractorRejectsBadRules(t *testing.T) {
+	bad := []PatternRule{
+		{Name: "kind", Kind: "audio", Pattern: "x"},
+		{Name: "empty", Kind: "video"},
+		{Name: "regex", Kind: "captions", Pattern: "("},
+	}
+	for _, rule := range bad {
+		if _, err := NewPatternExtractor(rule); err == nil {
+			t.Errorf("expected error for rule %q", rule.Name)
+		}
+	}
+}
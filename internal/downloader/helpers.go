@@ -1,11 +1,11 @@
 package downloader
 
 import (
-	"archive/zip"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"net/url"
 	"os"
@@ -15,6 +15,8 @@ import (
 	"time"
 
 	"golang.org/x/net/html"
+
+	"github.com/keanucz/AdobeConnectDL/internal/version"
 )
 
 // recordingInfo holds parsed information about a recording URL.
@@ -31,19 +33,50 @@ type pageInfo struct {
 	VideoSrc string // <video src="..."> URL that redirects to actual MP4
 	VTTPath  string // <track src="..."> relative path to VTT
 	Cookies  []*http.Cookie
+
+	VideoExtractor    string // Name of the extractor that found VideoSrc
+	CaptionsExtractor string // Name of the extractor that found VTTPath
 }
 
+// metadataSchemaVersion is bumped whenever the metadata.json layout changes.
+const metadataSchemaVersion = 5
+
 // metadata represents the JSON metadata written for each download.
 type metadata struct {
-	Title        string    `json:"title"`
-	SourceURL    string    `json:"source_url"`
-	RecordingID  string    `json:"recording_id"`
-	Hostname     string    `json:"hostname"`
-	MP4Path      string    `json:"mp4_path,omitempty"`
-	ZipPath      string    `json:"zip_path,omitempty"`
-	ExtractedDir string    `json:"extracted_dir,omitempty"`
-	DownloadedAt time.Time `json:"downloaded_at"`
-	Warnings     []string  `json:"warnings,omitempty"`
+	SchemaVersion    int                `json:"schema_version"`
+	ToolVersion      string             `json:"tool_version"`
+	Title            string             `json:"title"`
+	SourceURL        string             `json:"source_url"`
+	RecordingID      string             `json:"recording_id"`
+	Hostname         string             `json:"hostname"`
+	Recording        *RecordingDetails  `json:"recording,omitempty"`
+	Lecturer         string             `json:"lecturer,omitempty"`
+	ParticipantCount int                `json:"participant_count,omitempty"`
+	MP4Path          string             `json:"mp4_path,omitempty"`
+	ZipPath          string             `json:"zip_path,omitempty"`
+	ExtractedDir     string             `json:"extracted_dir,omitempty"`
+	Files            []metadataFile     `json:"files,omitempty"`
+	Documents        []metadataDocument `json:"documents,omitempty"`
+	Streams          []MediaStream      `json:"streams,omitempty"`
+	Assets           []AssetStatus      `json:"assets,omitempty"`
+	Retention        Retention          `json:"retention,omitempty"`
+	DownloadedAt     time.Time          `json:"downloaded_at"`
+	ReprocessedAt    *time.Time         `json:"reprocessed_at,omitempty"`
+	Warnings         []string           `json:"warnings,omitempty"`
+}
+
+// metadataFile records the size of a file produced in the recording directory.
+type metadataFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// metadataDocument describes a shared document and where it was saved.
+type metadataDocument struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Size      int64  `json:"size,omitempty"`
+	LocalPath string `json:"local_path,omitempty"`
 }
 
 // log is a helper that safely logs debug messages when logger is available.
@@ -161,67 +194,47 @@ func sanitize(name string) string {
 	return name
 }
 
-// extractZip extracts a ZIP archive to the destination directory.
-func extractZip(zipPath, dest string) error {
-	r, err := zip.OpenReader(zipPath)
-	if err != nil {
-		return err
-	}
-	defer r.Close()
-
-	for _, f := range r.File {
-		target := filepath.Join(dest, f.Name)
-		if !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
-			return fmt.Errorf("unsafe path in zip: %s", f.Name)
-		}
-
-		if f.FileInfo().IsDir() {
-			if err := os.MkdirAll(target, f.Mode()); err != nil {
-				return err
-			}
-			continue
-		}
-
-		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
-			return err
-		}
-
-		rc, err := f.Open()
-		if err != nil {
-			return err
-		}
-
-		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-		if err != nil {
-			rc.Close()
-			return err
-		}
+// writeMetadata writes download metadata as JSON.
+func writeMetadata(root string, info recordingInfo, res Result) error {
+	return saveMetadata(root, buildMetadata(root, info, res))
+}
 
-		if _, err := io.Copy(out, rc); err != nil {
-			out.Close()
-			rc.Close()
-			return err
+// buildMetadata describes a download result and the files in its directory.
+func buildMetadata(root string, info recordingInfo, res Result) metadata {
+	m := metadata{
+		SchemaVersion:    metadataSchemaVersion,
+		ToolVersion:      version.Version,
+		Title:            res.Title,
+		SourceURL:        info.Source,
+		RecordingID:      info.ID,
+		Hostname:         info.Hostname,
+		Recording:        res.Details,
+		Lecturer:         res.Lecturer,
+		ParticipantCount: res.Participants,
+		MP4Path:          res.MP4Path,
+		ZipPath:          res.ZipPath,
+		ExtractedDir:     res.ExtractedDir,
+		Files:            listOutputFiles(root),
+		Streams:          res.Streams,
+		DownloadedAt:     time.Now().UTC(),
+		Assets:           res.Assets,
+		Retention:        res.Retention,
+		Warnings:         res.Warnings,
+	}
+	for _, doc := range res.Documents {
+		md := metadataDocument{Name: doc.Name, URL: doc.DownloadURL, Size: doc.Size}
+		local := filepath.Join(root, "documents", sanitize(doc.Name))
+		if st, err := os.Stat(local); err == nil {
+			md.LocalPath = filepath.ToSlash(filepath.Join("documents", sanitize(doc.Name)))
+			md.Size = st.Size()
 		}
-		out.Close()
-		rc.Close()
+		m.Documents = append(m.Documents, md)
 	}
-
-	return nil
+	return m
 }
 
-// writeMetadata writes download metadata as JSON.
-func writeMetadata(root string, info recordingInfo, res Result) error {
-	m := metadata{
-		Title:        res.Title,
-		SourceURL:    info.Source,
-		RecordingID:  info.ID,
-		Hostname:     info.Hostname,
-		MP4Path:      res.MP4Path,
-		ZipPath:      res.ZipPath,
-		ExtractedDir: res.ExtractedDir,
-		DownloadedAt: time.Now().UTC(),
-		Warnings:     res.Warnings,
-	}
+// saveMetadata writes metadata.json into root.
+func saveMetadata(root string, m metadata) error {
 	data, err := json.MarshalIndent(m, "", "  ")
 	if err != nil {
 		return err
@@ -229,6 +242,50 @@ func writeMetadata(root string, info recordingInfo, res Result) error {
 	return os.WriteFile(filepath.Join(root, "metadata.json"), data, 0o644)
 }
 
+// readMetadata reads the metadata.json of an earlier download.
+func readMetadata(root string) (metadata, error) {
+	var m metadata
+	data, err := os.ReadFile(filepath.Join(root, "metadata.json"))
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("parse metadata.json: %w", err)
+	}
+	return m, nil
+}
+
+// listOutputFiles returns the files in the recording directory with their sizes.
+// The extracted raw directory is skipped since it can hold hundreds of streams.
+func listOutputFiles(root string) []metadataFile {
+	var files []metadataFile
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil //nolint:nilerr // unreadable entries are simply not listed
+		}
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			return nil //nolint:nilerr // paths outside root are not listed
+		}
+		if d.IsDir() {
+			if rel == "raw" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rel == "metadata.json" {
+			return nil
+		}
+		info, infoErr := d.Info()
+		if infoErr != nil {
+			return nil //nolint:nilerr // files removed mid-walk are not listed
+		}
+		files = append(files, metadataFile{Path: filepath.ToSlash(rel), Size: info.Size()})
+		return nil
+	})
+	return files
+}
+
 // copyFile copies a file from src to dst.
 func copyFile(dst, src string) error {
 	s, err := os.Open(src)
@@ -0,0 +1,388 @@
+package downloader
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Note is the content of one Notes pod and how it changed during the session.
+type Note struct {
+	Pod       string         `json:"pod"`       // Stream the pod was recorded in, e.g. "ftnotes4"
+	Revisions []NoteRevision `json:"revisions"` // In time order; the last one is the final content
+}
+
+// NoteRevision is the content of a Notes pod from Offset onwards.
+type NoteRevision struct {
+	Offset   int64  `json:"offset_ms"` // Milliseconds from the start of the recording
+	Time     string `json:"time"`
+	Markdown string `json:"markdown"`
+}
+
+// Link is a URL shared during the session.
+type Link struct {
+	URL    string `json:"url"`
+	Title  string `json:"title,omitempty"`
+	Source string `json:"source"`           // Web Links pod stream, e.g. "ftweblinks2", or "chat"
+	Poster string `json:"poster,omitempty"` // Who posted it in the chat
+	Offset int64  `json:"offset_ms"`
+	Time   string `json:"time"`
+}
+
+// Raw streams of Notes and Web Links pods, and the fields holding their content.
+var (
+	noteStreams       = []string{"ftnote*.xml"}
+	webLinkStreams    = []string{"ftweblink*.xml", "ftlinks*.xml"}
+	noteContentFields = []string{"html", "htmlText", "noteText", "content", "text"}
+	linkURLFields     = []string{"url", "link", "href"}
+	linkTitleFields   = []string{"name", "title", "label"}
+)
+
+// chatURLRe matches http and https URLs in chat messages.
+var chatURLRe = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// isLinkURL reports whether a Web Links pod entry or a link in a note is safe
+// to list: an http, https or mailto URL. Anything else, such as javascript:,
+// would run when clicked in links.html or notes.md.
+func isLinkURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return u.Opaque != ""
+	}
+	return false
+}
+
+// linkTextEscaper and linkURLEscaper escape what would end Markdown link text
+// or a link destination early.
+var (
+	linkTextEscaper = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`)
+	linkURLEscaper  = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E")
+)
+
+// markdownLink renders a Markdown link to href with text.
+func markdownLink(text, href string) string {
+	return "[" + linkTextEscaper.Replace(text) + "](" + linkURLEscaper.Replace(href) + ")"
+}
+
+// extractNotes reads every Notes pod in rawDir with one revision per change
+// of its content. It returns fs.ErrNotExist when there is no Notes pod.
+func extractNotes(rawDir string) ([]Note, error) {
+	files := globStreams(rawDir, noteStreams)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no notes pod in the raw recording: %w", fs.ErrNotExist)
+	}
+	var notes []Note
+	var errs []error
+	for _, file := range files {
+		note := Note{Pod: strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))}
+		err := readStream(file, func(m StreamMessage) bool {
+			m.Walk(func(n *StreamNode) {
+				content := firstField(n, noteContentFields)
+				if content == "" {
+					return
+				}
+				md := htmlToMarkdown(content)
+				if last := len(note.Revisions) - 1; last >= 0 && note.Revisions[last].Markdown == md {
+					return
+				}
+				note.Revisions = append(note.Revisions, NoteRevision{
+					Offset:   m.Time,
+					Time:     formatMilliseconds(m.Time),
+					Markdown: md,
+				})
+			})
+			return true
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if len(note.Revisions) > 0 {
+			notes = append(notes, note)
+		}
+	}
+	if len(errs) > 0 && len(notes) == 0 {
+		return nil, errs[0]
+	}
+	return notes, nil
+}
+
+// extractLinks reads the entries of every Web Links pod and the URLs posted in
+// the chat messages, in the order they were first shared. Each URL is listed
+// once; only http, https and mailto URLs are kept.
+func extractLinks(rawDir string, messages []ChatMessage) []Link {
+	var links []Link
+	seen := make(map[string]bool)
+	add := func(l Link) {
+		if seen[l.URL] {
+			return
+		}
+		seen[l.URL] = true
+		l.Time = formatMilliseconds(l.Offset)
+		links = append(links, l)
+	}
+
+	for _, file := range globStreams(rawDir, webLinkStreams) {
+		_ = readStream(file, func(m StreamMessage) bool {
+			m.Walk(func(n *StreamNode) {
+				if link := strings.TrimSpace(firstField(n, linkURLFields)); isLinkURL(link) {
+					title := htmlToText(firstField(n, linkTitleFields))
+					add(Link{URL: link, Title: title, Source: m.Pod, Offset: m.Time})
+				}
+			})
+			return true
+		})
+	}
+	for _, msg := range messages {
+		for _, link := range chatURLRe.FindAllString(msg.Text, -1) {
+			add(Link{URL: trimURL(link), Source: "chat", Poster: msg.Sender, Offset: msg.Offset})
+		}
+	}
+	slices.SortStableFunc(links, func(a, b Link) int { return cmp.Compare(a.Offset, b.Offset) })
+	return links
+}
+
+// trimURL drops punctuation that ends the sentence around a URL rather than
+// the URL itself, keeping closing parentheses that have an opening one.
+func trimURL(url string) string {
+	for {
+		trimmed := strings.TrimRight(url, ".,;:!?'")
+		if strings.HasSuffix(trimmed, ")") && strings.Count(trimmed, "(") < strings.Count(trimmed, ")") {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+		if trimmed == url {
+			return url
+		}
+		url = trimmed
+	}
+}
+
+// htmlToMarkdown converts the rich text Connect stores for Notes pods to
+// Markdown. Paragraphs, line breaks, bold, italics, headings, lists and links
+// are kept; fonts and other styling are dropped.
+func htmlToMarkdown(s string) string {
+	if !strings.ContainsAny(s, "<&") {
+		return strings.TrimSpace(s)
+	}
+	var b strings.Builder
+	var lists []int // Item counter per open list; -1 for bulleted lists
+	var link *strings.Builder
+	var href string
+	out := func() *strings.Builder {
+		if link != nil {
+			return link
+		}
+		return &b
+	}
+
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		tok := z.Token()
+		switch tt {
+		case html.TextToken:
+			text := strings.Join(strings.Fields(tok.Data), " ")
+			w := out()
+			if strings.HasPrefix(tok.Data, " ") || strings.HasPrefix(tok.Data, "\n") {
+				if str := w.String(); str != "" && !strings.HasSuffix(str, "\n") && !strings.HasSuffix(str, " ") {
+					w.WriteByte(' ')
+				}
+			}
+			w.WriteString(text)
+			if text != "" && strings.ContainsAny(tok.Data[len(tok.Data)-1:], " \n\t") {
+				w.WriteByte(' ')
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch tok.Data {
+			case "br":
+				out().WriteString("\\\n")
+			case "p", "div":
+				b.WriteString("\n\n")
+			case "b", "strong":
+				out().WriteString("**")
+			case "i", "em":
+				out().WriteString("*")
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				level, _ := strconv.Atoi(tok.Data[1:])
+				b.WriteString("\n\n" + strings.Repeat("#", level) + " ")
+			case "ul", "ol":
+				if len(lists) == 0 {
+					b.WriteString("\n")
+				}
+				if tok.Data == "ol" {
+					lists = append(lists, 0)
+				} else {
+					lists = append(lists, -1)
+				}
+			case "li":
+				b.WriteString("\n" + strings.Repeat("  ", max(len(lists)-1, 0)))
+				if n := len(lists) - 1; n >= 0 && lists[n] >= 0 {
+					lists[n]++
+					fmt.Fprintf(&b, "%d. ", lists[n])
+				} else {
+					b.WriteString("- ")
+				}
+			case "a":
+				for _, attr := range tok.Attr {
+					if attr.Key == "href" {
+						href = attr.Val
+					}
+				}
+				link = &strings.Builder{}
+			}
+		case html.EndTagToken:
+			switch tok.Data {
+			case "p", "div", "h1", "h2", "h3", "h4", "h5", "h6":
+				b.WriteString("\n\n")
+			case "b", "strong":
+				out().WriteString("**")
+			case "i", "em":
+				out().WriteString("*")
+			case "ul", "ol":
+				if len(lists) > 0 {
+					lists = lists[:len(lists)-1]
+				}
+				if len(lists) == 0 {
+					b.WriteString("\n\n")
+				}
+			case "a":
+				if link != nil {
+					text := strings.TrimSpace(link.String())
+					link = nil
+					// Links that aren't safe to follow, such as javascript:, keep only their text
+					switch {
+					case !isLinkURL(href):
+						b.WriteString(text)
+					case text == "" || text == href:
+						fmt.Fprintf(&b, "<%s>", linkURLEscaper.Replace(href))
+					default:
+						b.WriteString(markdownLink(text, href))
+					}
+					href = ""
+				}
+			}
+		default:
+			// Comments and doctypes carry no note text
+		}
+	}
+	return tidyMarkdown(b.String())
+}
+
+// blankLinesRe matches runs of blank lines.
+var blankLinesRe = regexp.MustCompile(`\n{3,}`)
+
+// tidyMarkdown trims trailing spaces and collapses blank lines.
+func tidyMarkdown(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.TrimSpace(blankLinesRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// writeNotes writes notes.md from the Notes pods and the shared links, and
+// links.html with the links as a bookmarks file, recording both assets.
+func writeNotes(
+	rootDir, rawDir, title string,
+	messages []ChatMessage,
+	assets *assetTracker,
+	logger Logger,
+) {
+	start := time.Now()
+	notes, notesErr := extractNotes(rawDir)
+	links := extractLinks(rawDir, messages)
+
+	linksPath := filepath.Join(rootDir, "links.html")
+	if len(links) == 0 {
+		assets.missing(AssetLinks, "no web links pod entries or links in the chat")
+	} else if err := os.WriteFile(linksPath, []byte(linksHTML(title, links)), 0o644); err != nil {
+		log(logger, "links write failed", "error", err)
+		assets.fail(AssetLinks, time.Since(start), err)
+	} else {
+		log(logger, "links created", "path", linksPath, "links", len(links))
+		assets.ok(AssetLinks, linksPath, time.Since(start))
+	}
+
+	if notesErr != nil && len(links) == 0 {
+		log(logger, "notes extraction failed", "error", notesErr)
+		assets.fail(AssetNotes, time.Since(start), notesErr)
+		return
+	}
+	notesPath := filepath.Join(rootDir, "notes.md")
+	if err := os.WriteFile(notesPath, []byte(notesMarkdown(title, notes, links)), 0o644); err != nil {
+		log(logger, "notes write failed", "error", err)
+		assets.fail(AssetNotes, time.Since(start), err)
+		return
+	}
+	log(logger, "notes created", "path", notesPath, "pods", len(notes))
+	assets.ok(AssetNotes, notesPath, time.Since(start))
+}
+
+// notesMarkdown renders the final content of each Notes pod with its revision
+// history, followed by the shared links.
+func notesMarkdown(title string, notes []Note, links []Link) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Notes: %s\n", cmp.Or(title, "Recording"))
+	for _, note := range notes {
+		final := note.Revisions[len(note.Revisions)-1]
+		if len(notes) > 1 {
+			fmt.Fprintf(&b, "\n## %s\n", note.Pod)
+		}
+		fmt.Fprintf(&b, "\n%s\n", final.Markdown)
+		if len(note.Revisions) > 1 {
+			b.WriteString("\n### Revision history\n")
+			for _, rev := range note.Revisions {
+				fmt.Fprintf(&b, "\n#### %s\n\n%s\n", rev.Time, rev.Markdown)
+			}
+		}
+	}
+	if len(links) > 0 {
+		b.WriteString("\n## Links\n\n")
+		for _, l := range links {
+			source := "posted in " + l.Source
+			if l.Poster != "" {
+				source += " by " + l.Poster
+			}
+			fmt.Fprintf(&b, "- %s (%s at %s)\n", markdownLink(cmp.Or(l.Title, l.URL), l.URL), source, l.Time)
+		}
+	}
+	return b.String()
+}
+
+// linksHTML renders links in the Netscape bookmark file format that browsers import.
+func linksHTML(title string, links []Link) string {
+	var b strings.Builder
+	title = html.EscapeString(cmp.Or(title, "Recording"))
+	fmt.Fprintf(&b, `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>%s</TITLE>
+<H1>%s</H1>
+<DL><p>
+    <DT><H3>%s</H3>
+    <DL><p>
+`, title, title, title)
+	for _, l := range links {
+		fmt.Fprintf(&b, "        <DT><A HREF=\"%s\">%s</A>\n",
+			html.EscapeString(l.URL), html.EscapeString(cmp.Or(l.Title, l.URL)))
+	}
+	b.WriteString("    </DL><p>\n</DL><p>\n")
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain text", "plain text"},
+		{`<TEXTFORMAT><P ALIGN="LEFT"><FONT FACE="Arial">Read <B>chapter 2</B> &amp; <I>3</I></FONT></P></TEXTFORMAT>`,
+			"Read **chapter 2** & *3*"},
+		{"<p>First</p><p>Second<br>line</p>", "First\n\nSecond\\\nline"},
+		{"<h2>Homework</h2><ol><li>Read</li><li>Write</li></ol>", "## Homework\n\n1. Read\n2. Write"},
+		{"<ul><li>A<ul><li>B</li></ul></li></ul>", "- A\n  - B"},
+		{`See <a href="https://example.com/x">the site</a>.`, "See [the site](https://example.com/x)."},
+		{`<a href="https://example.com">https://example.com</a>`, "<https://example.com>"},
+		{`<a href="javascript:alert(1)">Click <b>here</b></a>`, "Click **here**"},
+		{`<a href="https://example.com/a_(b)">see [1]</a>`, `[see \[1\]](https://example.com/a_%28b%29)`},
+	}
+	for _, tt := range tests {
+		if got := htmlToMarkdown(tt.in); got != tt.want {
+			t.Errorf("htmlToMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNotesMarkdownEscapesLinks(t *testing.T) {
+	links := []Link{{URL: "https://example.com/x_(y)", Title: "Draft [v2]", Source: "ftweblinks2", Time: "00:00:05"}}
+	want := `- [Draft \[v2\]](https://example.com/x_%28y%29) (posted in ftweblinks2 at 00:00:05)`
+	if md := notesMarkdown("Lecture", nil, links); !strings.Contains(md, want) {
+		t.Errorf("notes.md missing %q:\n%s", want, md)
+	}
+}
+
+func TestExtractNotesAndLinks(t *testing.T) {
+	rawDir := lectureStreams(t, "lecture8")
+
+	got, err := extractNotes(rawDir)
+	if err != nil {
+		t.Fatalf("extractNotes error: %v", err)
+	}
+	wantNotes := []Note{{Pod: "ftnotes1", Revisions: []NoteRevision{
+		{Offset: 1000, Time: "00:00:01", Markdown: "Draft"},
+		{Offset: 65000, Time: "00:01:05", Markdown: "Final **notes**"},
+	}}}
+	if !reflect.DeepEqual(got, wantNotes) {
+		t.Errorf("notes = %+v, want %+v", got, wantNotes)
+	}
+
+	messages, _ := extractChatMessages(rawDir, map[string]string{"User2": "James Lewis"})
+	gotLinks := extractLinks(rawDir, messages)
+	wantLinks := []Link{
+		{URL: "https://go.dev/tour", Source: "chat", Poster: "James Lewis", Offset: 3000, Time: "00:00:03"},
+		{URL: "https://example.com/docs", Title: "Docs", Source: "ftweblinks2", Offset: 5000, Time: "00:00:05"},
+		{URL: "mailto:tutor@example.edu", Title: "Tutor", Source: "ftweblinks2", Offset: 5000, Time: "00:00:05"},
+	}
+	if !reflect.DeepEqual(gotLinks, wantLinks) {
+		t.Errorf("links = %+v, want %+v", gotLinks, wantLinks)
+	}
+
+	rootDir := t.TempDir()
+	assets := &assetTracker{root: rootDir}
+	writeNotes(rootDir, rawDir, "Lecture", nil, assets, nil)
+	md, err := os.ReadFile(filepath.Join(rootDir, "notes.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"Final **notes**\n\n### Revision history", "#### 00:00:01\n\nDraft", "[Docs](https://example.com/docs)",
+	} {
+		if !strings.Contains(string(md), s) {
+			t.Errorf("notes.md missing %q:\n%s", s, md)
+		}
+	}
+	bookmarks, err := os.ReadFile(filepath.Join(rootDir, "links.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(bookmarks), `<DT><A HREF="https://example.com/docs">Docs</A>`) {
+		t.Errorf("links.html missing bookmark:\n%s", bookmarks)
+	}
+}
+
+func TestWriteNotesWithoutPods(t *testing.T) {
+	assertWriterMissing(t, "", func(rootDir, rawDir string, assets *assetTracker) {
+		writeNotes(rootDir, rawDir, "", nil, assets, nil)
+	})
+}
@@ -0,0 +1,111 @@
+package downloader
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// Exported entry points for the raw recording parsers, so callers can rerun
+// them over an already extracted raw/ directory without a full download.
+
+// ParseUserMapping returns the anonymous ID to real name mapping from indexstream.xml.
+func ParseUserMapping(rawDir string) map[string]string {
+	return extractUserMapping(rawDir)
+}
+
+// ParseLecturerName returns the lecturer name found in the raw recording, or "".
+func ParseLecturerName(rawDir string) string {
+	return extractLecturerName(rawDir)
+}
+
+// ParseDocumentLinks returns the documents shared during the session.
+// Hostname is used to build absolute download URLs.
+func ParseDocumentLinks(rawDir, hostname string) []DocumentInfo {
+	return extractDocumentLinks(rawDir, "https://"+hostname)
+}
+
+// WriteChatLog writes the chat messages from transcriptstream.xml to outputPath.
+func WriteChatLog(rawDir, outputPath string) error {
+	return extractChatLog(rawDir, outputPath)
+}
+
+// ParseChatMessages returns the chat messages from transcriptstream.xml with
+// anonymous IDs replaced by real names.
+func ParseChatMessages(rawDir string) ([]ChatMessage, error) {
+	return extractChatMessages(rawDir, extractUserMapping(rawDir))
+}
+
+// ParseQA returns the questions and answers of the Q&A pods, in the order
+// they were asked.
+func ParseQA(rawDir string) ([]QAQuestion, error) {
+	return extractQA(rawDir, extractUserMapping(rawDir))
+}
+
+// ParsePolls returns the polls run in the poll pods with their final results.
+func ParsePolls(rawDir string) ([]Poll, error) {
+	return extractPolls(rawDir, extractUserMapping(rawDir))
+}
+
+// ParseNotes returns the content of the Notes pods with their revisions.
+func ParseNotes(rawDir string) ([]Note, error) {
+	return extractNotes(rawDir)
+}
+
+// ParseLinks returns the Web Links pod entries and the URLs posted in the chat.
+func ParseLinks(rawDir string) []Link {
+	messages, _ := extractChatMessages(rawDir, extractUserMapping(rawDir))
+	return extractLinks(rawDir, messages)
+}
+
+// ParseWhiteboards returns the drawings of the whiteboards and share pods.
+func ParseWhiteboards(rawDir string) ([]Whiteboard, error) {
+	return extractWhiteboards(rawDir)
+}
+
+// ParseAttendance returns who attended and for how long, with their chat and
+// Q&A activity. End is the recording length in milliseconds, or 0 to count
+// participants who never left until the last event.
+func ParseAttendance(rawDir string, end int64) (AttendanceReport, error) {
+	userMapping := extractUserMapping(rawDir)
+	messages, _ := extractChatMessages(rawDir, userMapping)
+	return extractAttendance(rawDir, userMapping, messages, end)
+}
+
+// ParseChapters returns the chapters that rules (nil = DefaultChapterRules)
+// cut the recording into. End is the recording length in milliseconds, or 0
+// to end the last chapter at the last event.
+func ParseChapters(rawDir string, rules []ChapterRule, end int64) ([]Chapter, error) {
+	if rules == nil {
+		rules = DefaultChapterRules
+	}
+	return extractChapters(rawDir, rules, end)
+}
+
+// ParseSlides returns which document page each share pod showed over time.
+// Hostname is used to build absolute document URLs. Paths point to documents
+// downloaded next to rawDir, as in a recording directory.
+func ParseSlides(rawDir, hostname string) ([]SlideChange, error) {
+	return extractSlides(filepath.Dir(rawDir), rawDir, extractDocumentLinks(rawDir, "https://"+hostname))
+}
+
+// ParseStreams returns the codecs, length, resolution, bitrate, start and
+// owner of every FLV media stream in rawDir.
+func ParseStreams(rawDir string) []MediaStream {
+	return inspectStreams(rawDir, extractUserMapping(rawDir))
+}
+
+// ParseSessionTimeline returns every message of the raw recording as one
+// time-ordered timeline. A non-zero start adds wall clock timestamps.
+func ParseSessionTimeline(rawDir string, start time.Time) SessionTimeline {
+	return extractSessionTimeline(rawDir, start, extractUserMapping(rawDir))
+}
+
+// CleanVTT rewrites srcPath to dstPath with speaker markers replaced by real names.
+func CleanVTT(srcPath, dstPath, lecturerName string, userMapping map[string]string) error {
+	return cleanVTTFile(srcPath, dstPath, lecturerName, userMapping)
+}
+
+// WriteTranscript converts a VTT file to a plain text transcript at outputPath.
+func WriteTranscript(vttPath, outputPath string) error {
+	return vttToTranscript(vttPath, outputPath)
+}
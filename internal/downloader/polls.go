@@ -0,0 +1,349 @@
+package downloader
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PollAnswerType is how a poll could be answered.
+type PollAnswerType string
+
+const (
+	PollSingle      PollAnswerType = "multiple_choice" // One option
+	PollMultiple    PollAnswerType = "multiple_answer" // Any number of options
+	PollShortAnswer PollAnswerType = "short_answer"    // Free text
+)
+
+// Poll is one question asked in a poll pod, with its final results.
+type Poll struct {
+	Pod        string         `json:"pod"` // Stream the poll was recorded in, e.g. "ftpoll3"
+	Question   string         `json:"question"`
+	AnswerType PollAnswerType `json:"answer_type"`
+	Options    []PollOption   `json:"options,omitempty"`
+	Opened     int64          `json:"opened_ms"` // Milliseconds from the start of the recording
+	OpenedTime string         `json:"opened_time"`
+	Closed     int64          `json:"closed_ms,omitempty"`
+	ClosedTime string         `json:"closed_time,omitempty"`
+	TotalVotes int            `json:"total_votes"`         // Option votes, or responses to short answer polls
+	Responses  []PollResponse `json:"responses,omitempty"` // Per-user answers, when Connect recorded them
+}
+
+// PollOption is one of a poll's answers and the votes it got.
+type PollOption struct {
+	Text  string `json:"text"`
+	Votes int    `json:"votes"`
+}
+
+// PollResponse is one participant's final answer to a poll.
+type PollResponse struct {
+	User    string   `json:"user"`
+	Answers []string `json:"answers"`
+}
+
+// pollStreams match the raw streams of poll pods.
+var pollStreams = []string{"ftpoll*.xml"}
+
+// Fields of poll pod objects. The pod sends the whole poll again whenever it
+// changes, so the last values seen are the final ones. Options are the
+// <String> values of the option list. Only the poll pod's own field names are
+// read: generic ones such as "status", "options" or "name" also appear in
+// objects that aren't polls or votes.
+const (
+	pollQuestionField = "question"
+	pollTypeField     = "questionType"
+	pollStatusField   = "pollStatus"
+	pollOptionList    = "answers"
+	pollCountList     = "answerCounts"
+	pollResponseList  = "userVotes"
+	pollUserField     = "userID"
+	pollAnswerField   = "answer"
+)
+
+// extractPolls reads every poll asked in the poll pods of rawDir, in the
+// order they were opened. It returns fs.ErrNotExist when the recording has no
+// poll pod.
+func extractPolls(rawDir string, userMapping map[string]string) ([]Poll, error) {
+	files := globStreams(rawDir, pollStreams)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no poll pod in the raw recording: %w", fs.ErrNotExist)
+	}
+
+	var polls []Poll
+	var errs []error
+	for _, file := range files {
+		var cur *pollState
+		var pod []*pollState
+		// Response lists are read whole by updatePoll; their answers would
+		// otherwise look like the poll's options
+		var visit func(n *StreamNode, msgTime int64, pollPod string)
+		visit = func(n *StreamNode, msgTime int64, pollPod string) {
+			if q := htmlToText(n.Field(pollQuestionField)); q != "" && (cur == nil || q != cur.Question) {
+				cur = &pollState{Poll: Poll{Pod: pollPod, Question: q, AnswerType: PollSingle, Opened: msgTime}}
+				pod = append(pod, cur)
+			}
+			if cur != nil {
+				updatePoll(cur, n, msgTime, userMapping)
+			}
+			for _, c := range n.Children {
+				if c.Name != pollResponseList {
+					visit(c, msgTime, pollPod)
+				}
+			}
+		}
+		err := readStream(file, func(m StreamMessage) bool {
+			for _, arg := range m.Args {
+				visit(arg, m.Time, m.Pod)
+			}
+			return true
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, p := range pod {
+			polls = append(polls, finishPoll(p.Poll))
+		}
+	}
+	if len(errs) > 0 && len(polls) == 0 {
+		return nil, errs[0]
+	}
+	return polls, nil
+}
+
+// pollState is a poll being read, with whether it has been opened yet.
+type pollState struct {
+	Poll
+	opened bool
+}
+
+// updatePoll applies the fields of one node to the current poll. Polls are
+// often prepared, closed, long before they are opened, so the open time is
+// the first time they are opened and the close time the last time they are
+// closed after that.
+func updatePoll(p *pollState, n *StreamNode, msgTime int64, userMapping map[string]string) {
+	if t := n.Field(pollTypeField); t != "" {
+		p.AnswerType = pollAnswerType(t)
+	}
+	voted := len(p.Responses) > 0 || slices.ContainsFunc(p.Options, func(o PollOption) bool { return o.Votes > 0 })
+	switch strings.ToLower(n.Field(pollStatusField)) {
+	case "open", "opened", "start", "started", "active":
+		if !p.opened && !voted {
+			p.Opened = msgTime
+		}
+		p.opened = true
+		p.Closed = 0
+	case "close", "closed", "end", "ended", "stop", "stopped":
+		if p.opened || voted {
+			p.Closed = msgTime
+		}
+	}
+	if list := n.Child(pollOptionList); list != nil {
+		var options []PollOption
+		for _, c := range list.Children {
+			if len(c.Children) > 0 {
+				continue
+			}
+			if text := htmlToText(c.Text); text != "" {
+				options = append(options, PollOption{Text: text})
+			}
+		}
+		if len(options) > 0 {
+			for i := range options {
+				if i < len(p.Options) && p.Options[i].Text == options[i].Text {
+					options[i].Votes = p.Options[i].Votes
+				}
+			}
+			p.Options = options
+		}
+	}
+	// Counts are matched to the options by position; those for options not
+	// seen yet are dropped
+	if list := n.Child(pollCountList); list != nil {
+		for i, c := range list.Children {
+			if count, err := strconv.Atoi(c.Text); err == nil && i < len(p.Options) {
+				p.Options[i].Votes = count
+			}
+		}
+	}
+	if list := n.Child(pollResponseList); list != nil {
+		for _, c := range list.Children {
+			if r, ok := pollResponse(p, c, userMapping); ok {
+				setResponse(p, r)
+			}
+		}
+	}
+}
+
+// pollResponse reads one participant's answer. Numeric answers are indexes
+// into the poll's options, counted from 0.
+func pollResponse(p *pollState, n *StreamNode, userMapping map[string]string) (PollResponse, bool) {
+	user := n.Field(pollUserField)
+	if user == "" {
+		return PollResponse{}, false
+	}
+	r := PollResponse{User: cmp.Or(userMapping[user], user)}
+	var visit func(n *StreamNode, inList bool)
+	visit = func(n *StreamNode, inList bool) {
+		for _, c := range n.Children {
+			if len(c.Children) > 0 {
+				visit(c, c.Name == pollOptionList)
+				continue
+			}
+			if !inList && c.Name != pollAnswerField {
+				continue
+			}
+			answer := c.Text
+			if i, err := strconv.Atoi(answer); err == nil && p.AnswerType != PollShortAnswer &&
+				i >= 0 && i < len(p.Options) {
+				answer = p.Options[i].Text
+			}
+			if answer = htmlToText(answer); answer != "" {
+				r.Answers = append(r.Answers, answer)
+			}
+		}
+	}
+	visit(n, false)
+	return r, len(r.Answers) > 0
+}
+
+// setResponse records r, replacing an earlier answer by the same user.
+func setResponse(p *pollState, r PollResponse) {
+	for i := range p.Responses {
+		if p.Responses[i].User == r.User {
+			p.Responses[i] = r
+			return
+		}
+	}
+	p.Responses = append(p.Responses, r)
+}
+
+// finishPoll fills in times and totals once the whole stream has been read.
+// Without recorded counts, votes are counted from the per-user answers.
+func finishPoll(p Poll) Poll {
+	for _, o := range p.Options {
+		p.TotalVotes += o.Votes
+	}
+	if p.TotalVotes == 0 {
+		for _, r := range p.Responses {
+			for _, a := range r.Answers {
+				if i := slices.IndexFunc(p.Options, func(o PollOption) bool { return o.Text == a }); i >= 0 {
+					p.Options[i].Votes++
+					p.TotalVotes++
+				}
+			}
+		}
+	}
+	if p.AnswerType == PollShortAnswer {
+		p.TotalVotes = len(p.Responses)
+	}
+	p.OpenedTime = formatMilliseconds(p.Opened)
+	if p.Closed > 0 {
+		p.ClosedTime = formatMilliseconds(p.Closed)
+	}
+	return p
+}
+
+// pollAnswerType normalizes Connect's question type names, such as
+// "multiple-choice", "Multiple Answer" or "short_answer".
+func pollAnswerType(t string) PollAnswerType {
+	t = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r
+		}
+		return -1
+	}, strings.ToLower(t))
+	switch {
+	case strings.Contains(t, "short"), strings.Contains(t, "text"), strings.Contains(t, "open"):
+		return PollShortAnswer
+	case strings.Contains(t, "multipleanswer"), strings.Contains(t, "multianswer"),
+		strings.Contains(t, "multipleresponse"), strings.Contains(t, "checkbox"):
+		return PollMultiple
+	}
+	return PollSingle
+}
+
+// firstChild returns the first direct child of n with one of names, or nil.
+func firstChild(n *StreamNode, names []string) *StreamNode {
+	for _, name := range names {
+		if c := n.Child(name); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// writePolls writes polls.json and polls.md from the poll pods in rawDir and
+// records the status of the polls asset.
+func writePolls(
+	rootDir, rawDir, title string,
+	userMapping map[string]string,
+	assets *assetTracker,
+	logger Logger,
+) {
+	start := time.Now()
+	polls, err := extractPolls(rawDir, userMapping)
+	if err != nil {
+		log(logger, "poll extraction failed", "error", err)
+		assets.fail(AssetPolls, time.Since(start), err)
+		return
+	}
+	if polls == nil {
+		polls = []Poll{}
+	}
+	jsonPath := filepath.Join(rootDir, "polls.json")
+	data, err := json.MarshalIndent(polls, "", "  ")
+	if err == nil {
+		err = os.WriteFile(jsonPath, data, 0o644)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(rootDir, "polls.md"), []byte(pollsMarkdown(title, polls)), 0o644)
+	}
+	if err != nil {
+		log(logger, "poll write failed", "error", err)
+		assets.fail(AssetPolls, time.Since(start), err)
+		return
+	}
+	log(logger, "polls created", "path", jsonPath, "polls", len(polls))
+	assets.ok(AssetPolls, jsonPath, time.Since(start))
+}
+
+// pollsMarkdown renders polls as a readable Markdown summary.
+func pollsMarkdown(title string, polls []Poll) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Polls: %s\n", cmp.Or(title, "Recording"))
+	if len(polls) == 0 {
+		b.WriteString("\nNo polls were run.\n")
+	}
+	for i, p := range polls {
+		fmt.Fprintf(&b, "\n## %d. %s\n\n", i+1, strings.ReplaceAll(p.Question, "\n", " "))
+		when := "opened " + p.OpenedTime
+		if p.ClosedTime != "" {
+			when += ", closed " + p.ClosedTime
+		}
+		fmt.Fprintf(&b, "%s, %s, %d votes\n", strings.ReplaceAll(string(p.AnswerType), "_", " "), when, p.TotalVotes)
+		if len(p.Options) > 0 {
+			b.WriteString("\n| Option | Votes | Share |\n|--------|------:|------:|\n")
+			for _, o := range p.Options {
+				share := 0
+				if p.TotalVotes > 0 {
+					share = o.Votes * 100 / p.TotalVotes
+				}
+				fmt.Fprintf(&b, "| %s | %d | %d%% |\n", strings.ReplaceAll(o.Text, "|", `\|`), o.Votes, share)
+			}
+		}
+		if len(p.Responses) > 0 {
+			b.WriteString("\nResponses:\n\n")
+			for _, r := range p.Responses {
+				fmt.Fprintf(&b, "- %s: %s\n", r.User, strings.Join(r.Answers, "; "))
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package downloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractPolls(t *testing.T) {
+	rawDir := lectureStreams(t, "lecture6")
+	mapping := map[string]string{"User1": "Jane Doe", "User2": "James Lewis"}
+
+	polls, err := extractPolls(rawDir, mapping)
+	if err != nil {
+		t.Fatalf("extractPolls error: %v", err)
+	}
+	want := []Poll{
+		{
+			Pod: "ftpoll1", Question: "Which topic next?", AnswerType: PollSingle,
+			Options: []PollOption{{"Graphs", 0}, {"Trees", 2}},
+			Opened:  60000, OpenedTime: "00:01:00", Closed: 80000, ClosedTime: "00:01:20", TotalVotes: 2,
+			Responses: []PollResponse{{"Jane Doe", []string{"Trees"}}, {"James Lewis", []string{"Trees"}}},
+		},
+		{
+			Pod: "ftpoll1", Question: "Any feedback?", AnswerType: PollShortAnswer,
+			Opened: 120000, OpenedTime: "00:02:00", TotalVotes: 1,
+			Responses: []PollResponse{{"James Lewis", []string{"More examples & less theory"}}},
+		},
+	}
+	if !reflect.DeepEqual(polls, want) {
+		t.Fatalf("polls = %+v\nwant %+v", polls, want)
+	}
+
+	rootDir := t.TempDir()
+	assets := &assetTracker{root: rootDir}
+	writePolls(rootDir, rawDir, "SE101", mapping, assets, nil)
+	if a := assets.list(); len(a) != 1 || a[0].State != StateOK || a[0].Path != "polls.json" {
+		t.Fatalf("unexpected status: %+v", a)
+	}
+	var decoded []Poll
+	data, _ := os.ReadFile(filepath.Join(rootDir, "polls.json"))
+	if err := json.Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(decoded, want) {
+		t.Errorf("polls.json = %s, err = %v", data, err)
+	}
+	md, _ := os.ReadFile(filepath.Join(rootDir, "polls.md"))
+	for _, s := range []string{
+		"multiple choice, opened 00:01:00, closed 00:01:20, 2 votes",
+		"| Trees | 2 | 100% |",
+		"- James Lewis: More examples & less theory",
+	} {
+		if !strings.Contains(string(md), s) {
+			t.Errorf("polls.md missing %q:\n%s", s, md)
+		}
+	}
+}
+
+func TestExtractPollsCountsAndTypes(t *testing.T) {
+	rawDir := lectureStreams(t, "lecture7")
+	polls, err := extractPolls(rawDir, nil)
+	if err != nil || len(polls) != 1 {
+		t.Fatalf("extractPolls = %+v, %v", polls, err)
+	}
+	// Counts sent before the options, or beyond them, match no option and are dropped
+	p := polls[0]
+	wantOptions := []PollOption{{"A", 3}, {"B", 4}}
+	if p.AnswerType != PollMultiple || p.TotalVotes != 7 || !reflect.DeepEqual(p.Options, wantOptions) {
+		t.Errorf("unexpected poll: %+v", p)
+	}
+}
+
+func TestWritePollsWithoutPod(t *testing.T) {
+	assertWriterMissing(t, "", func(rootDir, rawDir string, assets *assetTracker) {
+		writePolls(rootDir, rawDir, "", nil, assets, nil)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -52,10 +53,12 @@ type DownloadJob struct {
 	Cookies  []*http.Cookie
 	Referer  string
 	Kind     fileKind
+	Tee      io.Writer // Optional: also receives the downloaded bytes
 
 	// Extraction parameters (for extract jobs)
-	SourcePath string // ZIP file to extract
-	ExtractDir string // Directory to extract to
+	SourcePath string        // ZIP file to extract
+	ExtractDir string        // Directory to extract to
+	Limits     ExtractLimits // Zero value uses DefaultExtractLimits
 
 	// Callbacks
 	OnProgress ProgressCallback
@@ -224,7 +227,7 @@ func (p *DownloadPool) processJob(job DownloadJob) {
 		if p.logger != nil {
 			p.logger.Debug("starting extraction", "name", job.Name, "source", job.SourcePath)
 		}
-		err = extractZip(job.SourcePath, job.ExtractDir)
+		err = extractZip(job.SourcePath, job.ExtractDir, job.Limits)
 	} else {
 		// Log the start for download jobs
 		if p.logger != nil {
@@ -238,7 +241,9 @@ func (p *DownloadPool) processJob(job DownloadJob) {
 			Cookies:    job.Cookies,
 			Referer:    job.Referer,
 			Kind:       job.Kind,
+			Asset:      job.Type.String(),
 			OnProgress: job.OnProgress,
+			Tee:        job.Tee,
 		}, p.logger)
 	}
 
@@ -319,6 +324,19 @@ func (p *DownloadPool) SubmitZip(
 	destPath string,
 	referer string,
 	cookies []*http.Cookie,
+) <-chan DownloadResult {
+	return p.SubmitZipStream(ctx, url, destPath, referer, cookies, nil)
+}
+
+// SubmitZipStream is like SubmitZip but also writes the downloaded bytes to
+// tee, for example to extract the archive while it downloads.
+func (p *DownloadPool) SubmitZipStream(
+	ctx context.Context,
+	url string,
+	destPath string,
+	referer string,
+	cookies []*http.Cookie,
+	tee io.Writer,
 ) <-chan DownloadResult {
 	result := make(chan DownloadResult, 1)
 
@@ -330,6 +348,7 @@ func (p *DownloadPool) SubmitZip(
 		Cookies:  cookies,
 		Referer:  referer,
 		Kind:     fileKindZip,
+		Tee:      tee,
 		Ctx:      ctx,
 		OnComplete: func(err error) {
 			if err != nil {
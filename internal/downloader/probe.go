@@ -0,0 +1,233 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ProbeResult describes what a recording offers without downloading it.
+type ProbeResult struct {
+	URL         string         `json:"url"`
+	RecordingID string         `json:"recording_id"`
+	Hostname    string         `json:"hostname"`
+	Title       string         `json:"title"`
+	OutputDir   string         `json:"output_dir"`
+	DirExists   bool           `json:"output_dir_exists"`
+	Auth        ProbeAuth      `json:"auth"`
+	Assets      []AssetProbe   `json:"assets"`
+	Problems    []string       `json:"problems,omitempty"`
+	Session     *SessionStatus `json:"session,omitempty"`
+}
+
+// ProbeAuth summarises how the recording page responded to the session.
+type ProbeAuth struct {
+	SessionProvided bool   `json:"session_provided"`
+	PageAccessible  bool   `json:"page_accessible"`
+	Error           string `json:"error,omitempty"`
+}
+
+// AssetProbe describes a single remote asset discovered for a recording.
+type AssetProbe struct {
+	Name        string        `json:"name"`
+	URL         string        `json:"url"`
+	Available   bool          `json:"available"`
+	Size        int64         `json:"size"` // -1 when the server does not report a length
+	ContentType string        `json:"content_type,omitempty"`
+	Status      int           `json:"status,omitempty"`
+	Problem     string        `json:"problem,omitempty"`
+	Category    ErrorCategory `json:"category,omitempty"`  // Why the asset is unavailable, as for a failed download
+	Extractor   string        `json:"extractor,omitempty"` // Page extractor that discovered the URL
+}
+
+// Probe resolves a recording the same way Download does and checks the ZIP,
+// MP4 and VTT with HEAD (or single-byte ranged GET) requests. No files are written.
+func (d *Downloader) Probe(ctx context.Context, rawURL string, opts Options) (ProbeResult, error) {
+	logger := opts.Log
+
+	info, err := parseRecordingURL(rawURL)
+	if err != nil {
+		return ProbeResult{}, err
+	}
+	session := ResolveSession(rawURL, opts.Session)
+
+	baseOutputDir := opts.OutputDir
+	if baseOutputDir == "" {
+		baseOutputDir = "."
+	}
+
+	res := ProbeResult{
+		URL:         RedactURL(rawURL),
+		RecordingID: info.ID,
+		Hostname:    info.Hostname,
+		Title:       sanitize(info.ID),
+		Auth:        ProbeAuth{SessionProvided: session != ""},
+	}
+
+	if status, err := d.CheckSession(ctx, rawURL, session, logger); err == nil {
+		res.Session = &status
+		if session != "" && !status.LoggedIn {
+			res.Problems = append(res.Problems, "session token is expired or invalid")
+		}
+	} else {
+		log(logger, "probe session check skipped", "error", err)
+	}
+
+	page, pageErr := d.fetchPageInfo(ctx, rawURL, session, opts.Extractors, logger)
+	switch {
+	case pageErr == nil:
+		res.Auth.PageAccessible = true
+		if page.Title != "" {
+			res.Title = sanitize(page.Title)
+		}
+	case errors.Is(pageErr, ErrAuthRequired):
+		res.Auth.Error = pageErr.Error()
+		res.Problems = append(res.Problems, "recording page requires a valid session token")
+	default:
+		res.Auth.Error = pageErr.Error()
+		res.Problems = append(res.Problems, fmt.Sprintf("recording page could not be fetched: %v", pageErr))
+	}
+
+	res.OutputDir = filepath.Join(baseOutputDir, res.Title)
+	if entries, err := os.ReadDir(res.OutputDir); err == nil && len(entries) > 0 {
+		res.DirExists = true
+	}
+
+	cookies := mergeCookies(session, page.Cookies)
+
+	zipURL := fmt.Sprintf("%s/output/%s.zip?download=zip", info.BaseURL, info.ID)
+	res.Assets = append(res.Assets, d.probeAsset(ctx, "zip", zipURL, rawURL, fileKindZip, cookies, logger))
+
+	if page.VideoSrc != "" {
+		asset := d.probeAsset(ctx, "mp4", page.VideoSrc, rawURL, fileKindVideo, cookies, logger)
+		asset.Extractor = page.VideoExtractor
+		res.Assets = append(res.Assets, asset)
+	} else if pageErr == nil {
+		res.Assets = append(res.Assets, AssetProbe{Name: "mp4", Size: -1, Problem: "no video URL discovered in page"})
+	}
+
+	if page.VTTPath != "" {
+		vttURL := resolveVTTURL(info.BaseURL, page.VTTPath)
+		asset := d.probeAsset(ctx, "vtt", vttURL, rawURL, fileKindBinary, cookies, logger)
+		asset.Extractor = page.CaptionsExtractor
+		res.Assets = append(res.Assets, asset)
+	}
+
+	for _, a := range res.Assets {
+		if a.Problem != "" {
+			res.Problems = append(res.Problems, fmt.Sprintf("%s: %s", a.Name, a.Problem))
+		}
+	}
+
+	return res, nil
+}
+
+// probeAsset issues a HEAD request for an asset, falling back to a ranged GET
+// for servers that reject HEAD. Problems are classified like download
+// failures, so the category matches what a download would report.
+func (d *Downloader) probeAsset(
+	ctx context.Context,
+	name, assetURL, referer string,
+	kind fileKind,
+	cookies []*http.Cookie,
+	logger Logger,
+) AssetProbe {
+	probe := AssetProbe{Name: name, URL: RedactURL(assetURL), Size: -1}
+
+	resp, err := d.probeRequest(ctx, http.MethodHead, assetURL, referer, cookies, "")
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = d.probeRequest(ctx, http.MethodGet, assetURL, referer, cookies, "bytes=0-0")
+	}
+	if err != nil {
+		probe.setError(newDownloadError(CategoryNetwork, name, assetURL, 0, fmt.Errorf("request failed: %w", err)))
+		return probe
+	}
+	defer resp.Body.Close()
+
+	probe.Status = resp.StatusCode
+	probe.ContentType = resp.Header.Get("Content-Type")
+	log(logger, "probe response", "asset", name, "status", resp.StatusCode, "content-type", probe.ContentType)
+
+	if resp.StatusCode >= 300 {
+		probe.setError(statusError(name, assetURL, resp.StatusCode, kind))
+		return probe
+	}
+	if isHTMLResponse(resp.Header, nil) {
+		head := d.probeHead(ctx, resp, assetURL, referer, cookies)
+		probe.setError(htmlError(name, assetURL, resp.StatusCode, kind, head))
+		return probe
+	}
+
+	probe.Available = true
+	probe.Size = responseSize(resp)
+	return probe
+}
+
+// setError records why an asset is unavailable.
+func (a *AssetProbe) setError(err *DownloadError) {
+	a.Category = err.Category
+	a.Problem = err.summary()
+}
+
+// probeHead returns the start of an HTML response body, fetching it with a
+// GET when resp answered a HEAD request, so login pages can be recognised.
+func (d *Downloader) probeHead(
+	ctx context.Context,
+	resp *http.Response,
+	assetURL, referer string,
+	cookies []*http.Cookie,
+) []byte {
+	if resp.Request == nil || resp.Request.Method == http.MethodHead {
+		get, err := d.probeRequest(ctx, http.MethodGet, assetURL, referer, cookies, "bytes=0-4095")
+		if err != nil {
+			return nil
+		}
+		defer get.Body.Close()
+		resp = get
+	}
+	head, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return head
+}
+
+// probeRequest sends a HEAD or GET request, limited to byteRange when set.
+func (d *Downloader) probeRequest(
+	ctx context.Context,
+	method, assetURL, referer string,
+	cookies []*http.Cookie,
+	byteRange string,
+) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, assetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	applyRequestOptions(req, requestOptions{Cookies: cookies, Referer: referer})
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+	return d.client.Do(req)
+}
+
+// responseSize returns the full size of the resource, using Content-Range for
+// partial responses. Returns -1 when unknown.
+func responseSize(resp *http.Response) int64 {
+	if resp.StatusCode == http.StatusPartialContent {
+		cr := resp.Header.Get("Content-Range")
+		if i := strings.LastIndex(cr, "/"); i >= 0 {
+			if n, err := strconv.ParseInt(cr[i+1:], 10, 64); err == nil {
+				return n
+			}
+		}
+		return -1
+	}
+	if resp.ContentLength >= 0 {
+		return resp.ContentLength
+	}
+	return -1
+}
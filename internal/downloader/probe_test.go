@@ -0,0 +1,88 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProbeReportsAssetsWithoutWriting(t *testing.T) {
+	mp4Content := make([]byte, 4096)
+	zipContent := createZip(t, map[string]string{"a.txt": "hello"})
+	var gets int
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rec/":
+			fmt.Fprintf(w, `<html><head><title>Probe Me</title></head>
+<body><script>var casRecordingURL = '%s/rec/output/rec.mp4';
+var transcriptFilename = 'Probe\x20Me.vtt';</script></body></html>`, server.URL)
+		case "/rec/output/rec.mp4":
+			// Servers that reject HEAD must be probed with a ranged GET
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			gets++
+			if r.Header.Get("Range") != "bytes=0-0" {
+				t.Errorf("expected ranged GET, got Range=%q", r.Header.Get("Range"))
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-0/%d", len(mp4Content)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(mp4Content[:1])
+		case "/rec/output/rec.zip":
+			w.Header().Set("Content-Type", "application/zip")
+			w.Header().Set("Content-Length", fmt.Sprint(len(zipContent)))
+			if r.Method != http.MethodHead {
+				w.Write(zipContent)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tmp := t.TempDir()
+	res, err := New(server.Client()).Probe(context.Background(), server.URL+"/rec/", Options{OutputDir: tmp})
+	if err != nil {
+		t.Fatalf("probe error: %v", err)
+	}
+
+	if res.Title != "Probe Me" || res.OutputDir != filepath.Join(tmp, "Probe Me") {
+		t.Errorf("unexpected title/output dir: %q %q", res.Title, res.OutputDir)
+	}
+	if !res.Auth.PageAccessible {
+		t.Errorf("expected page to be accessible")
+	}
+
+	assets := map[string]AssetProbe{}
+	for _, a := range res.Assets {
+		assets[a.Name] = a
+	}
+	if a := assets["zip"]; !a.Available || a.Size != int64(len(zipContent)) {
+		t.Errorf("unexpected zip probe: %+v", a)
+	}
+	if a := assets["mp4"]; !a.Available || a.Size != int64(len(mp4Content)) || gets != 1 {
+		t.Errorf("unexpected mp4 probe: %+v (gets=%d)", a, gets)
+	}
+	if a := assets["vtt"]; a.Available || a.Category != CategoryNotGenerated ||
+		!strings.Contains(a.Problem, "not found") {
+		t.Errorf("expected missing vtt, got %+v", a)
+	}
+	if len(res.Problems) == 0 {
+		t.Errorf("expected missing vtt to be listed as a problem")
+	}
+
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatalf("read output dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("probe wrote files: %v", entries)
+	}
+}
@@ -0,0 +1,206 @@
+package downloader
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// QAQuestion is one question asked in a Q&A pod.
+type QAQuestion struct {
+	ID      string     `json:"id,omitempty"`
+	Pod     string     `json:"pod"` // Stream the question was recorded in, e.g. "ftqa1"
+	Text    string     `json:"question"`
+	Asker   string     `json:"asker,omitempty"`
+	Offset  int64      `json:"asked_ms"` // Milliseconds from the start of the recording
+	Time    string     `json:"asked_time"`
+	Answers []QAAnswer `json:"answers,omitempty"`
+}
+
+// QAAnswer is a host's answer to a QAQuestion.
+type QAAnswer struct {
+	Text     string `json:"answer"`
+	Answerer string `json:"answerer,omitempty"`
+	Offset   int64  `json:"answered_ms"`
+	Time     string `json:"answered_time"`
+}
+
+// qaStreams match the raw streams of Q&A pods.
+var qaStreams = []string{"ftqa*.xml", "ftqna*.xml"}
+
+// Fields of Q&A pod objects. Connect sends a question again with the same ID
+// whenever it is answered or edited, so questions are merged by ID. Only the
+// Q&A pod's own field names are read: generic ones such as "name" or "text"
+// also appear in objects that aren't questions.
+const (
+	questionField = "questionText"
+	askerField    = "fromName"
+	answerList    = "answers"
+	answerField   = "answerText"
+	answererField = "answeredBy"
+	qaIDField     = "questionID"
+)
+
+// extractQA reads the questions and answers of every Q&A pod in rawDir, in the
+// order they were asked. It returns fs.ErrNotExist when the recording has no
+// Q&A pod.
+func extractQA(rawDir string, userMapping map[string]string) ([]QAQuestion, error) {
+	files := globStreams(rawDir, qaStreams)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Q&A pod in the raw recording: %w", fs.ErrNotExist)
+	}
+	realName := func(name string) string {
+		return cmp.Or(userMapping[name], name)
+	}
+
+	var questions []*QAQuestion
+	byID := make(map[string]*QAQuestion)
+	var errs []error
+	for _, file := range files {
+		err := readStream(file, func(m StreamMessage) bool {
+			m.Walk(func(n *StreamNode) {
+				text := n.Field(questionField)
+				if text == "" {
+					return
+				}
+				id := n.Field(qaIDField)
+				q := byID[m.Pod+"/"+id]
+				if q == nil || id == "" {
+					offset := nodeTime(n, m.Time)
+					q = &QAQuestion{ID: id, Pod: m.Pod, Offset: offset, Time: formatMilliseconds(offset)}
+					questions = append(questions, q)
+					if id != "" {
+						byID[m.Pod+"/"+id] = q
+					}
+				}
+				q.Text = htmlToText(text)
+				if asker := n.Field(askerField); asker != "" {
+					q.Asker = realName(asker)
+				}
+				for _, a := range questionAnswers(n, m.Time) {
+					a.Answerer = realName(a.Answerer)
+					if !hasAnswer(q.Answers, a.Text) {
+						q.Answers = append(q.Answers, a)
+					}
+				}
+			})
+			return true
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	out := make([]QAQuestion, len(questions))
+	for i, q := range questions {
+		out[i] = *q
+	}
+	if len(errs) > 0 && len(out) == 0 {
+		return nil, errs[0]
+	}
+	return out, nil
+}
+
+// questionAnswers returns the answers recorded with a question: a list of
+// answer objects, or a single answer in the question's own fields.
+func questionAnswers(q *StreamNode, msgTime int64) []QAAnswer {
+	var answers []QAAnswer
+	add := func(n *StreamNode, text, answerer string) {
+		offset := cmp.Or(parseInt(n.Field("answerTime")), nodeTime(n, msgTime))
+		answers = append(answers, QAAnswer{
+			Text:     htmlToText(text),
+			Answerer: answerer,
+			Offset:   offset,
+			Time:     formatMilliseconds(offset),
+		})
+	}
+	if list := q.Child(answerList); list != nil {
+		list.Walk(func(n *StreamNode) {
+			if text := n.Field(answerField); text != "" && n != list {
+				add(n, text, n.Field(answererField))
+			}
+		})
+	}
+	if len(answers) == 0 {
+		if text := q.Field(answerField); text != "" {
+			add(q, text, q.Field(answererField))
+		}
+	}
+	return answers
+}
+
+func hasAnswer(answers []QAAnswer, text string) bool {
+	for _, a := range answers {
+		if a.Text == text {
+			return true
+		}
+	}
+	return false
+}
+
+// writeQA writes qa.json and qa.md from the Q&A pods in rawDir and records
+// the status of the qa asset.
+func writeQA(
+	rootDir, rawDir, title string,
+	userMapping map[string]string,
+	assets *assetTracker,
+	logger Logger,
+) {
+	start := time.Now()
+	questions, err := extractQA(rawDir, userMapping)
+	if err != nil {
+		log(logger, "q&a extraction failed", "error", err)
+		assets.fail(AssetQA, time.Since(start), err)
+		return
+	}
+	jsonPath := filepath.Join(rootDir, "qa.json")
+	if questions == nil {
+		questions = []QAQuestion{}
+	}
+	data, err := json.MarshalIndent(questions, "", "  ")
+	if err == nil {
+		err = os.WriteFile(jsonPath, data, 0o644)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(rootDir, "qa.md"), []byte(qaMarkdown(title, questions)), 0o644)
+	}
+	if err != nil {
+		log(logger, "q&a write failed", "error", err)
+		assets.fail(AssetQA, time.Since(start), err)
+		return
+	}
+	log(logger, "q&a created", "path", jsonPath, "questions", len(questions))
+	assets.ok(AssetQA, jsonPath, time.Since(start))
+}
+
+// qaMarkdown renders questions as a readable Markdown document.
+func qaMarkdown(title string, questions []QAQuestion) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Q&A: %s\n", cmp.Or(title, "Recording"))
+	if len(questions) == 0 {
+		b.WriteString("\nNo questions were asked.\n")
+	}
+	for i, q := range questions {
+		fmt.Fprintf(&b, "\n## %d. %s\n\n", i+1, strings.ReplaceAll(q.Text, "\n", " "))
+		if q.Asker != "" {
+			fmt.Fprintf(&b, "Asked by %s at %s\n", q.Asker, q.Time)
+		} else {
+			fmt.Fprintf(&b, "Asked at %s\n", q.Time)
+		}
+		if len(q.Answers) == 0 {
+			b.WriteString("\n*Unanswered*\n")
+		}
+		for _, a := range q.Answers {
+			fmt.Fprintf(&b, "\n**%s** at %s:\n\n", cmp.Or(a.Answerer, "Answer"), a.Time)
+			for _, line := range strings.Split(a.Text, "\n") {
+				fmt.Fprintf(&b, "> %s\n", line)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package downloader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractQA(t *testing.T) {
+	rawDir := lectureStreams(t, "lecture4")
+	mapping := map[string]string{"User1": "Jane Doe", "User2": "James Lewis"}
+
+	questions, err := extractQA(rawDir, mapping)
+	if err != nil {
+		t.Fatalf("extractQA error: %v", err)
+	}
+	want := []QAQuestion{
+		{
+			ID: "7", Pod: "ftqa1", Text: "Is the lab due Friday?", Asker: "James Lewis",
+			Offset: 1000, Time: "00:00:01",
+			Answers: []QAAnswer{{
+				Text: "No, Monday.\nLate submissions lose 10%.", Answerer: "Jane Doe",
+				Offset: 5000, Time: "00:00:05",
+			}},
+		},
+		{Pod: "ftqa1", Text: "Where are the slides?", Asker: "Ashley Cooper", Offset: 2000, Time: "00:00:02"},
+	}
+	if !reflect.DeepEqual(questions, want) {
+		t.Fatalf("questions = %+v\nwant %+v", questions, want)
+	}
+
+	rootDir := t.TempDir()
+	assets := &assetTracker{root: rootDir}
+	writeQA(rootDir, rawDir, "SE101", mapping, assets, nil)
+	if a := assets.list(); len(a) != 1 || a[0].State != StateOK || a[0].Path != "qa.json" {
+		t.Fatalf("unexpected status: %+v", a)
+	}
+	var decoded []QAQuestion
+	data, _ := os.ReadFile(filepath.Join(rootDir, "qa.json"))
+	if err := json.Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(decoded, want) {
+		t.Errorf("qa.json = %s, err = %v", data, err)
+	}
+	md, _ := os.ReadFile(filepath.Join(rootDir, "qa.md"))
+	for _, s := range []string{
+		"## 1. Is the lab due Friday?\n\nAsked by James Lewis at 00:00:01\n",
+		"**Jane Doe** at 00:00:05:\n\n> No, Monday.\n> Late submissions lose 10%.\n",
+		"## 2. Where are the slides?\n\nAsked by Ashley Cooper at 00:00:02\n\n*Unanswered*\n",
+	} {
+		if !strings.Contains(string(md), s) {
+			t.Errorf("qa.md missing %q:\n%s", s, md)
+		}
+	}
+}
+
+func TestWriteQAWithoutPod(t *testing.T) {
+	assertWriterMissing(t, "", func(rootDir, rawDir string, assets *assetTracker) {
+		writeQA(rootDir, rawDir, "", nil, assets, nil)
+	})
+}
@@ -0,0 +1,208 @@
+package downloader
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// rawEntryPatterns match the raw ZIP entries the downloader reads. Everything
+// else, mostly FLV media streams, is skipped by selective extraction unless
+// recording.mp4 has to be rebuilt.
+var rawEntryPatterns = []string{
+	"*.vtt",
+	"indexstream.xml",
+	"mainstream.xml", // Layout changes for the session timeline and chapters
+	"transcriptstream.xml",
+	"ft*.xml", // Pod streams: file shares, titles, chat, notes, polls and so on
+}
+
+// isNeededRawEntry reports whether a top-level raw ZIP entry is used by the downloader.
+func isNeededRawEntry(name string) bool {
+	for _, pattern := range rawEntryPatterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isMediaEntry reports whether a top-level raw ZIP entry is an FLV media
+// stream, which only a rebuild of recording.mp4 needs.
+func isMediaEntry(name string) bool {
+	ok, _ := path.Match("*.flv", name)
+	return ok
+}
+
+// errRangesUnsupported indicates the server did not answer a Range request with partial content.
+var errRangesUnsupported = errors.New("server does not support range requests")
+
+// rangeChunk is the minimum number of bytes fetched per range request. The
+// first request reads this much from the end of the file, which usually covers
+// the whole central directory.
+const rangeChunk = 256 << 10
+
+// rangeReader is an io.ReaderAt over an HTTP resource that fetches byte
+// ranges on demand, caching the most recent chunk.
+type rangeReader struct {
+	ctx      context.Context // ReadAt has no context parameter
+	client   HTTPClient
+	url      string
+	opts     requestOptions
+	size     int64
+	bufOff   int64
+	buf      []byte
+	requests int
+	fetched  int64
+}
+
+// openRangeReader reads the tail of the resource, learning its size from
+// Content-Range. It returns errRangesUnsupported when the server ignores ranges
+// or answers with an HTML page.
+func (d *Downloader) openRangeReader(ctx context.Context, rawURL string, opts requestOptions) (*rangeReader, error) {
+	r := &rangeReader{ctx: ctx, client: d.client, url: rawURL, opts: opts}
+	resp, err := r.get(fmt.Sprintf("bytes=-%d", rangeChunk))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent || isHTMLResponse(resp.Header, nil) {
+		return nil, fmt.Errorf("%w (HTTP %d)", errRangesUnsupported, resp.StatusCode)
+	}
+	// The suffix range must end the resource and be no longer than asked for,
+	// so a bad size can't make us allocate more than rangeChunk
+	start, end, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+	if !ok || end != size-1 || end-start+1 > rangeChunk {
+		return nil, fmt.Errorf("%w: bad Content-Range %q", errRangesUnsupported, resp.Header.Get("Content-Range"))
+	}
+	r.size = size
+	if err := r.fill(resp.Body, start, end-start+1); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ReadAt implements io.ReaderAt.
+func (r *rangeReader) ReadAt(p []byte, off int64) (int, error) {
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	end := min(off+int64(len(p)), r.size)
+	if off < r.bufOff || end > r.bufOff+int64(len(r.buf)) {
+		if err := r.fetch(off, min(max(end, off+rangeChunk), r.size)); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf[off-r.bufOff:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// fetch replaces the cache with bytes [start, end).
+func (r *rangeReader) fetch(start, end int64) error {
+	resp, err := r.get(fmt.Sprintf("bytes=%d-%d", start, end-1))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("%w (HTTP %d)", errRangesUnsupported, resp.StatusCode)
+	}
+	gotStart, gotEnd, gotSize, ok := parseContentRange(resp.Header.Get("Content-Range"))
+	if !ok || gotStart != start || gotEnd != end-1 || gotSize != r.size {
+		return fmt.Errorf("%w: got Content-Range %q for bytes %d-%d",
+			errRangesUnsupported, resp.Header.Get("Content-Range"), start, end-1)
+	}
+	return r.fill(resp.Body, start, end-start)
+}
+
+// fill reads exactly n bytes from body into the cache at offset start.
+func (r *rangeReader) fill(body io.Reader, start, n int64) error {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(body, buf); err != nil {
+		return fmt.Errorf("read range: %w", err)
+	}
+	r.bufOff, r.buf = start, buf
+	r.fetched += n
+	return nil
+}
+
+func (r *rangeReader) get(rangeHeader string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	applyRequestOptions(req, r.opts)
+	req.Header.Set("Range", rangeHeader)
+	r.requests++
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, newDownloadError(CategoryNetwork, AssetZip, r.url, 0, err)
+	}
+	return resp, nil
+}
+
+// parseContentRange parses "bytes start-end/size", where end is inclusive.
+func parseContentRange(header string) (start, end, size int64, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes ")
+	if !found {
+		return 0, 0, 0, false
+	}
+	rng, total, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, 0, false
+	}
+	first, last, found := strings.Cut(rng, "-")
+	if !found {
+		return 0, 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	end, err = strconv.ParseInt(last, 10, 64)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	size, err = strconv.ParseInt(total, 10, 64)
+	if err != nil || start < 0 || start > end || end >= size {
+		return 0, 0, 0, false
+	}
+	return start, end, size, true
+}
+
+// extractRemoteZip extracts only the raw ZIP entries accepted by keep into
+// dest, reading the central directory and those entries with range requests.
+func (d *Downloader) extractRemoteZip(
+	ctx context.Context,
+	zipURL, dest string,
+	keep func(name string) bool,
+	opts requestOptions,
+	limits ExtractLimits,
+	logger Logger,
+) error {
+	ra, err := d.openRangeReader(ctx, zipURL, opts)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(ra, ra.size)
+	if err != nil {
+		return newDownloadError(CategoryInvalidContent, AssetZip, zipURL, http.StatusPartialContent,
+			fmt.Errorf("%w: %w", ErrInvalidZip, err))
+	}
+	if err := extractFiles(zr, dest, keep, limits); err != nil {
+		return err
+	}
+	log(logger, "selective zip extraction complete",
+		"requests", ra.requests, "fetched", ra.fetched, "size", ra.size)
+	return nil
+}
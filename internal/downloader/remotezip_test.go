@@ -0,0 +1,234 @@
+package downloader
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// createRawZip builds a raw recording ZIP with a large stored FLV stream.
+func createRawZip(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	flv, err := zw.CreateHeader(&zip.FileHeader{Name: "cameraVoip_1_3.flv", Method: zip.Store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	noise := make([]byte, 4<<20)
+	for i := range noise {
+		noise[i] = byte(rand.IntN(256))
+	}
+	flv.Write(noise)
+	for name, content := range map[string]string{
+		"indexstream.xml":      "<root></root>",
+		"transcriptstream.xml": "<root></root>",
+		"fttitle0.xml":         "<root></root>",
+		"rec.vtt":              "WEBVTT\n\n00:00.000 --> 00:01.000\nhello\n",
+		"mainstream.xml":       "<root></root>",
+	} {
+		fw, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newRawZipServer serves a recording whose raw ZIP supports ranges unless
+// noRanges is set, with an MP4 rendition when withMP4 is set. It counts the
+// ZIP bytes sent.
+func newRawZipServer(t *testing.T, noRanges, withMP4 bool) (*httptest.Server, *atomic.Int64, int) {
+	t.Helper()
+	data := createRawZip(t)
+	var sent atomic.Int64
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rec/":
+			if withMP4 {
+				fmt.Fprintf(w, `<title>Selective</title>
+<script>var casRecordingURL = '%s/rec/output/rec.mp4';</script>`, server.URL)
+				return
+			}
+			w.Write([]byte("<title>Selective</title>"))
+		case "/rec/output/rec.mp4":
+			if !withMP4 {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(make([]byte, 2048))
+		case "/rec/output/rec.zip":
+			cw := &countingWriter{ResponseWriter: w, n: &sent}
+			if noRanges {
+				cw.Write(data)
+				return
+			}
+			http.ServeContent(cw, r, "", time.Time{}, bytes.NewReader(data))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &sent, len(data)
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n *atomic.Int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n.Add(int64(len(p)))
+	return c.ResponseWriter.Write(p)
+}
+
+func TestSelectiveZipFetchesOnlyNeededEntries(t *testing.T) {
+	server, sent, size := newRawZipServer(t, false, true)
+
+	res, err := New(server.Client()).Download(context.Background(), server.URL+"/rec/", Options{
+		OutputDir:    t.TempDir(),
+		SelectiveZip: true,
+	})
+	if err != nil {
+		t.Fatalf("download error: %v", err)
+	}
+	if res.ZipPath != "" {
+		t.Errorf("raw.zip should not be kept, got %s", res.ZipPath)
+	}
+	for _, name := range []string{"indexstream.xml", "mainstream.xml", "transcriptstream.xml", "fttitle0.xml", "rec.vtt"} {
+		if _, err := os.Stat(filepath.Join(res.ExtractedDir, name)); err != nil {
+			t.Errorf("expected %s to be extracted: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(res.ExtractedDir, "cameraVoip_1_3.flv")); err == nil {
+		t.Error("cameraVoip_1_3.flv should not be extracted")
+	}
+	if got := sent.Load(); got > int64(size)/4 {
+		t.Errorf("fetched %d of %d bytes", got, size)
+	}
+	if a, _ := res.Asset(AssetCaptions); a.State != StateOK {
+		t.Errorf("expected captions from the selective extraction, got %+v", a)
+	}
+	if a, _ := res.Asset(AssetZip); a.State != StateSkipped {
+		t.Errorf("expected zip to be skipped, got %+v", a)
+	}
+	if a, _ := res.Asset(AssetRemux); a.State != StateSkipped || a.Error != "MP4 rendition downloaded" {
+		t.Errorf("expected remux to be skipped with an MP4 rendition, got %+v", a)
+	}
+}
+
+func TestSelectiveZipFetchesMediaWithoutMP4(t *testing.T) {
+	server, sent, size := newRawZipServer(t, false, false)
+
+	res, err := New(server.Client()).Download(context.Background(), server.URL+"/rec/", Options{
+		OutputDir:    t.TempDir(),
+		SelectiveZip: true,
+	})
+	if err != nil {
+		t.Fatalf("download error: %v", err)
+	}
+	if res.ZipPath != "" {
+		t.Errorf("raw.zip should not be kept, got %s", res.ZipPath)
+	}
+	if _, err := os.Stat(filepath.Join(res.ExtractedDir, "cameraVoip_1_3.flv")); err != nil {
+		t.Errorf("expected the FLV stream to be fetched for the remux: %v", err)
+	}
+	if got := sent.Load(); got < int64(size)/2 {
+		t.Errorf("fetched %d of %d bytes, want the FLV stream", got, size)
+	}
+	// The stream is random bytes rather than FLV, but the remux was tried
+	if a, _ := res.Asset(AssetRemux); a.State == StateSkipped {
+		t.Errorf("expected a remux attempt without an MP4 rendition, got %+v", a)
+	}
+}
+
+func TestSelectiveZipFallsBackWithoutRanges(t *testing.T) {
+	server, _, _ := newRawZipServer(t, true, false)
+
+	res, err := New(server.Client()).Download(context.Background(), server.URL+"/rec/", Options{
+		OutputDir:    t.TempDir(),
+		SelectiveZip: true,
+	})
+	if err != nil {
+		t.Fatalf("download error: %v", err)
+	}
+	if res.ZipPath == "" {
+		t.Fatal("expected the full zip to be downloaded")
+	}
+	if _, err := os.Stat(filepath.Join(res.ExtractedDir, "cameraVoip_1_3.flv")); err != nil {
+		t.Errorf("expected full extraction: %v", err)
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header           string
+		start, end, size int64
+		ok               bool
+	}{
+		{"bytes 100-199/200", 100, 199, 200, true},
+		{"bytes 0-0/1", 0, 0, 1, true},
+		{"bytes */200", 0, 0, 0, false},
+		{"bytes 300-399/200", 0, 0, 0, false},
+		{"bytes 100-200/200", 0, 0, 0, false},
+		{"bytes 150-100/200", 0, 0, 0, false},
+		{"", 0, 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, end, size, ok := parseContentRange(tt.header)
+		if ok != tt.ok || start != tt.start || end != tt.end || size != tt.size {
+			t.Errorf("parseContentRange(%q) = %d, %d, %d, %v", tt.header, start, end, size, ok)
+		}
+	}
+}
+
+func TestRangeReaderRejectsUnexpectedRanges(t *testing.T) {
+	tests := []struct {
+		name  string
+		tail  string // Content-Range answering the suffix request
+		chunk string // Content-Range answering the next request
+	}{
+		{"suffix from the start of a huge file", "bytes 0-262143/1099511627776", ""},
+		{"suffix longer than requested", "bytes 100-600000/600001", ""},
+		{"suffix not ending the file", "bytes 0-1023/600000", ""},
+		{"other range than requested", "bytes 337856-599999/600000", "bytes 0-9/600000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") != fmt.Sprintf("bytes=-%d", rangeChunk) {
+					w.Header().Set("Content-Range", tt.chunk)
+					w.WriteHeader(http.StatusPartialContent)
+					return
+				}
+				w.Header().Set("Content-Range", tt.tail)
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write(make([]byte, rangeChunk))
+			}))
+			defer server.Close()
+
+			r, err := New(server.Client()).openRangeReader(context.Background(), server.URL, requestOptions{})
+			if err == nil {
+				_, err = r.ReadAt(make([]byte, 10), 0)
+			}
+			if !errors.Is(err, errRangesUnsupported) {
+				t.Errorf("err = %v, want errRangesUnsupported", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/keanucz/AdobeConnectDL/internal/flv"
+)
+
+// mediaStreams sorts the FLV files in rawDir into video and audio streams:
+// screen shares are the video, or the camera when nothing was shared, and
+// every stream other than a screen share may carry voice.
+func mediaStreams(rawDir string) (video, audio []flv.Stream) {
+	files, _ := filepath.Glob(filepath.Join(rawDir, "*.flv"))
+	index := streamIndex(rawDir, nil)
+	var cameras []flv.Stream
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		s := flv.Stream{Path: file, Offset: index[name].offset}
+		switch lower := strings.ToLower(name); {
+		case strings.HasPrefix(lower, "screenshare"):
+			video = append(video, s)
+		case strings.HasPrefix(lower, "camera"):
+			cameras = append(cameras, s)
+			audio = append(audio, s)
+		default:
+			audio = append(audio, s)
+		}
+	}
+	if len(video) == 0 {
+		video = cameras
+	}
+	return video, audio
+}
+
+// hasRemuxableStream reports whether any of streams carries a codec
+// remuxRecording copies into the MP4.
+func hasRemuxableStream(streams []MediaStream) bool {
+	return slices.ContainsFunc(streams, func(s MediaStream) bool {
+		return s.VideoCodec == "h264" || s.AudioCodec == "aac" || s.AudioCodec == "mp3"
+	})
+}
+
+// remuxRecording rebuilds the MP4 at mp4Path from the FLV streams in rawDir,
+// for recordings Connect offers no MP4 rendition of. Only H.264, AAC and MP3
+// are copied; other codecs would need re-encoding. It records the status of
+// the remux asset and returns mp4Path, or "" when no MP4 was written.
+func remuxRecording(ctx context.Context, mp4Path, rawDir string, assets *assetTracker, logger Logger) string {
+	video, audio := mediaStreams(rawDir)
+	if len(video) == 0 && len(audio) == 0 {
+		assets.missing(AssetRemux, "no FLV streams in the raw recording")
+		return ""
+	}
+	logInfo(logger, "rebuilding MP4 from raw streams", "video", len(video), "audio", len(audio))
+	start := time.Now()
+	summary, err := flv.Remux(ctx, mp4Path, video, audio)
+	switch {
+	case errors.Is(err, flv.ErrNoMedia):
+		logWarn(logger, "raw streams cannot be remuxed", "error", err)
+		assets.missing(AssetRemux, err.Error())
+		return ""
+	case errors.Is(err, flv.ErrUnsupportedCodec):
+		logWarn(logger, "raw streams need re-encoding to be remuxed", "error", err)
+		assets.skip(AssetRemux, err.Error())
+		return ""
+	case err != nil:
+		logWarn(logger, "remux failed", "error", err)
+		assets.fail(AssetRemux, time.Since(start), err)
+		return ""
+	}
+	if len(summary.Skipped) > 0 {
+		logWarn(logger, "some streams were left out of the MP4", "codecs", strings.Join(summary.Skipped, ", "))
+	}
+	logInfo(logger, "MP4 rebuilt from raw streams", "path", mp4Path,
+		"duration", formatMilliseconds(summary.Duration), "video_frames", summary.VideoFrames,
+		"audio_frames", summary.AudioFrames)
+	if dropped := droppedFrames(summary.Dropped); dropped != "" {
+		logWarn(logger, "overlapping streams were cut short in the MP4", "frames", dropped)
+		assets.incomplete(AssetRemux, mp4Path, time.Since(start), "overlapping streams cut short: "+dropped)
+		return mp4Path
+	}
+	assets.ok(AssetRemux, mp4Path, time.Since(start))
+	return mp4Path
+}
+
+// droppedFrames lists the frames flv.Remux left out of each stream, e.g.
+// "screenshare_2_5.flv (120 frames)", in name order.
+func droppedFrames(dropped map[string]int) string {
+	var parts []string
+	for _, path := range slices.Sorted(maps.Keys(dropped)) {
+		parts = append(parts, fmt.Sprintf("%s (%d frames)", filepath.Base(path), dropped[path]))
+	}
+	return strings.Join(parts, ", ")
+}
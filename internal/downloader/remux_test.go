@@ -0,0 +1,126 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/keanucz/AdobeConnectDL/internal/flv"
+)
+
+func TestMediaStreams(t *testing.T) {
+	rawDir := lectureStreams(t, "lecture13")
+	camera := flv.Stream{Path: filepath.Join(rawDir, "cameraVoip_1_3.flv"), Offset: 1200}
+	share := flv.Stream{Path: filepath.Join(rawDir, "screenshare_2_10.flv"), Offset: 64000}
+
+	video, audio := mediaStreams(rawDir)
+	if !reflect.DeepEqual(video, []flv.Stream{share}) || !reflect.DeepEqual(audio, []flv.Stream{camera}) {
+		t.Errorf("mediaStreams = %+v, %+v; want the screen share video and the camera audio", video, audio)
+	}
+
+	// Without a screen share the camera is the video too
+	os.Remove(share.Path)
+	video, audio = mediaStreams(rawDir)
+	if !reflect.DeepEqual(video, []flv.Stream{camera}) || !reflect.DeepEqual(audio, []flv.Stream{camera}) {
+		t.Errorf("mediaStreams = %+v, %+v; want the camera video and audio", video, audio)
+	}
+}
+
+func TestRemuxRecordingWithoutStreams(t *testing.T) {
+	rootDir := t.TempDir()
+	assets := &assetTracker{root: rootDir}
+	mp4Path := filepath.Join(rootDir, "recording.mp4")
+	if path := remuxRecording(context.Background(), mp4Path, t.TempDir(), assets, nil); path != "" {
+		t.Errorf("remuxRecording = %q, want no MP4", path)
+	}
+	if list := assets.list(); len(list) != 1 || list[0].Name != AssetRemux || list[0].State != StateMissing {
+		t.Errorf("assets = %+v, want remux missing", list)
+	}
+}
+
+// writeAudioFLV writes an FLV stream of n audio frames 23 ms apart in the
+// given sound format byte, AAC ones after an AudioSpecificConfig.
+func writeAudioFLV(t *testing.T, path string, format byte, n int) {
+	t.Helper()
+	b := []byte("FLV\x01\x04\x00\x00\x00\x09\x00\x00\x00\x00")
+	tag := func(ts int, data ...byte) {
+		size := len(data)
+		b = append(b, 8, 0, 0, byte(size), byte(ts>>16), byte(ts>>8), byte(ts), 0, 0, 0, 0)
+		b = append(b, data...)
+		b = append(b, 0, 0, 0, byte(11+size))
+	}
+	if format == 0xae {
+		tag(0, 0xae, 0x00, 0x12, 0x08)
+	}
+	for i := range n {
+		tag(i*23, format, 0x01, 0x21, byte(i))
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemuxRecordingReportsOverlap(t *testing.T) {
+	rawDir := t.TempDir()
+	writeAudioFLV(t, filepath.Join(rawDir, "cameraVoip_1_3.flv"), 0xae, 20)
+	writeAudioFLV(t, filepath.Join(rawDir, "cameraVoip_2_4.flv"), 0xae, 10)
+	rootDir := t.TempDir()
+	assets := &assetTracker{root: rootDir}
+	mp4Path := filepath.Join(rootDir, "recording.mp4")
+	if path := remuxRecording(context.Background(), mp4Path, rawDir, assets, nil); path != mp4Path {
+		t.Fatalf("remuxRecording = %q, want %q", path, mp4Path)
+	}
+	list := assets.list()
+	want := "overlapping streams cut short: cameraVoip_2_4.flv (10 frames)"
+	if len(list) != 1 || list[0].State != StateOK || list[0].Warning != want {
+		t.Errorf("assets = %+v, want remux ok with the dropped frames", list)
+	}
+}
+
+func TestRemuxRecordingUnsupportedCodec(t *testing.T) {
+	rawDir := t.TempDir()
+	writeAudioFLV(t, filepath.Join(rawDir, "cameraVoip_1_3.flv"), 0x62, 10) // Nellymoser
+	rootDir := t.TempDir()
+	assets := &assetTracker{root: rootDir}
+	mp4Path := filepath.Join(rootDir, "recording.mp4")
+	if path := remuxRecording(context.Background(), mp4Path, rawDir, assets, nil); path != "" {
+		t.Errorf("remuxRecording = %q, want no MP4", path)
+	}
+	list := assets.list()
+	if len(list) != 1 || list[0].State != StateSkipped || !strings.Contains(list[0].Error, "unsupported codec") {
+		t.Errorf("assets = %+v, want remux skipped for an unsupported codec", list)
+	}
+}
+
+func TestNeedsMediaStreams(t *testing.T) {
+	dir := t.TempDir()
+	rawDir := filepath.Join(dir, "raw")
+	os.MkdirAll(rawDir, 0o755)
+	meta := metadata{Streams: []MediaStream{{Name: "cameraVoip_1_3.flv", AudioCodec: "aac"}}}
+	if !needsMediaStreams(dir, rawDir, meta) {
+		t.Error("a deleted recording.mp4 without FLV streams should need them")
+	}
+	noMedia := metadata{
+		Streams: meta.Streams,
+		Assets:  []AssetStatus{{Name: AssetRemux, State: StateMissing, Error: "no audio or video to remux"}},
+	}
+	if needsMediaStreams(dir, rawDir, noMedia) {
+		t.Error("streams an earlier remux found no media in should not be fetched again")
+	}
+	nellymoser := metadata{Streams: []MediaStream{{Name: "cameraVoip_1_3.flv", AudioCodec: "nellymoser"}}}
+	if needsMediaStreams(dir, rawDir, nellymoser) {
+		t.Error("streams with no codec the remuxer copies should not be fetched again")
+	}
+	os.WriteFile(filepath.Join(rawDir, "cameraVoip_1_3.flv"), nil, 0o644)
+	if needsMediaStreams(dir, rawDir, meta) {
+		t.Error("FLV streams in raw/ should be enough")
+	}
+	os.Remove(filepath.Join(rawDir, "cameraVoip_1_3.flv"))
+	os.WriteFile(filepath.Join(dir, "recording.mp4"), nil, 0o644)
+	if needsMediaStreams(dir, rawDir, meta) {
+		t.Error("an existing recording.mp4 needs no FLV streams")
+	}
+}
@@ -0,0 +1,259 @@
+package downloader
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// reprocessedAssets are rebuilt by Reprocess; statuses of other assets are
+// carried over from metadata.json.
+var reprocessedAssets = append(
+	[]string{AssetExtraction, AssetCaptions, AssetTranscript, AssetSubtitles, AssetMarkers}, rawAssets...,
+)
+
+// Reprocess rebuilds the derived artifacts of an earlier download in dir
+// (captions, transcript, document list, chat, session timeline, attendance,
+// Q&A, polls, notes, links, whiteboards, slide timeline, chapters and chat
+// subtitles) from its raw data, then applies the retention policy again. A
+// missing recording.mp4 is rebuilt from the raw FLV streams, with captions,
+// chat and chapters embedded when opts.MP4Box is set; an existing one is left
+// as downloaded.
+// opts.Retention overrides the policy recorded in metadata.json.
+//
+// Raw data is re-fetched only when raw/ lacks the XML and caption streams and
+// there is no raw.zip to extract them from, or when recording.mp4 is to be
+// rebuilt and the keep-xml-only or delete policy removed the FLV streams: with
+// range requests when the server supports them, otherwise by downloading the
+// ZIP again.
+func (d *Downloader) Reprocess(ctx context.Context, dir string, opts Options) (Result, error) {
+	logger := opts.Log
+	meta, err := readMetadata(dir)
+	if err != nil {
+		return Result{}, fmt.Errorf("not a recording directory: %w", err)
+	}
+	info, err := parseRecordingURL(meta.SourceURL)
+	if err != nil {
+		return Result{}, fmt.Errorf("metadata.json source_url: %w", err)
+	}
+	retention, err := ParseRetention(string(cmp.Or(opts.Retention, meta.Retention)))
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Title: meta.Title, RootDir: dir, Details: meta.Recording}
+	warn := func(msg string) {
+		result.Warnings = append(result.Warnings, msg)
+		emit(opts.OnEvent, Event{Kind: EventWarning, URL: meta.SourceURL, Message: msg})
+	}
+	assets := &assetTracker{root: dir}
+	for _, a := range meta.Assets {
+		if !slices.Contains(reprocessedAssets, a.Name) {
+			assets.assets = append(assets.assets, a)
+		}
+	}
+	mp4Path := filepath.Join(dir, "recording.mp4")
+	if _, err := os.Stat(mp4Path); err == nil {
+		result.MP4Path = mp4Path
+	}
+	zipPath := filepath.Join(dir, "raw.zip")
+	rawDir := filepath.Join(dir, "raw")
+
+	start := time.Now()
+	if err := d.restoreRawStreams(ctx, info, meta, rawDir, zipPath, opts, logger); err != nil {
+		assets.fail(AssetExtraction, time.Since(start), err)
+		return result, fmt.Errorf("raw data unavailable: %w", err)
+	}
+	assets.ok(AssetExtraction, rawDir, time.Since(start))
+	result.ExtractedDir = rawDir
+	if _, err := os.Stat(zipPath); err == nil {
+		result.ZipPath = zipPath
+	}
+
+	lecturer := extractLecturerName(rawDir)
+	userMapping := extractUserMapping(rawDir)
+	result.Lecturer = lecturer
+	result.Participants = len(userMapping)
+
+	origin, _, _ := ParseTarget(info.BaseURL)
+	result.Documents = extractDocumentLinks(rawDir, origin)
+	if len(result.Documents) > 0 {
+		if err := writeDocumentList(filepath.Join(dir, "documents.txt"), result.Documents); err != nil {
+			warn(fmt.Sprintf("write document list: %v", err))
+		}
+	}
+
+	start = time.Now()
+	chatMessages, chatErr := extractChatMessages(rawDir, userMapping)
+	if chatLogPath, err := writeChatOutputs(dir, meta.Title, opts.ChatFormats, chatMessages, chatErr); err != nil {
+		log(logger, "chat log extraction failed", "error", err)
+		assets.fail(AssetChat, time.Since(start), err)
+	} else {
+		assets.ok(AssetChat, chatLogPath, time.Since(start))
+	}
+	chatVTTPath := writeChatSubtitles(dir, chatMessages, chatErr, opts.ChatCueDuration, assets, logger)
+
+	writeSession(dir, rawDir, meta.Recording, userMapping, assets, logger)
+	writeAttendance(dir, rawDir, meta.Recording, userMapping, chatMessages, opts.AttendanceAggregate, assets, logger)
+	writeQA(dir, rawDir, meta.Title, userMapping, assets, logger)
+	writePolls(dir, rawDir, meta.Title, userMapping, assets, logger)
+	writeNotes(dir, rawDir, meta.Title, chatMessages, assets, logger)
+	writeWhiteboards(dir, rawDir, opts.WhiteboardSnapshots, assets, logger)
+	writeSlides(dir, rawDir, result.Documents, assets, logger)
+	chaptersPath := writeChapters(dir, rawDir, meta.Recording, opts.ChapterRules, assets, logger)
+
+	// recording.mp4 is rebuilt only when it's missing, so an earlier remux
+	// keeps its status
+	existingMP4 := result.MP4Path != ""
+	if !existingMP4 {
+		result.MP4Path = remuxRecording(ctx, mp4Path, rawDir, assets, logger)
+	} else if i := slices.IndexFunc(meta.Assets, func(a AssetStatus) bool { return a.Name == AssetRemux }); i >= 0 {
+		assets.assets = append(assets.assets, meta.Assets[i])
+	} else {
+		assets.skip(AssetRemux, "recording.mp4 exists")
+	}
+	// Selective extraction and retention leave no FLV streams to inspect
+	result.Streams = inspectStreams(rawDir, userMapping)
+	if result.Streams == nil {
+		result.Streams = meta.Streams
+	}
+
+	// Captions are rebuilt from the raw stream when there is one, so cleaning
+	// starts again from Connect's original speaker markers
+	vttPath := filepath.Join(dir, "captions.vtt")
+	if vttFiles, _ := filepath.Glob(filepath.Join(rawDir, "*.vtt")); len(vttFiles) > 0 {
+		if err := copyFile(vttPath, vttFiles[0]); err != nil {
+			assets.fail(AssetCaptions, 0, err)
+			vttPath = ""
+		}
+	} else if _, err := os.Stat(vttPath); err != nil {
+		vttPath = ""
+	}
+	if vttPath != "" {
+		assets.ok(AssetCaptions, vttPath, 0)
+		d.processVTT(vttPath, dir, lecturer, userMapping, assets, logger)
+	} else {
+		assets.missing(AssetCaptions, "no captions in the raw recording")
+		assets.skip(AssetTranscript, "no captions")
+		assets.skip(AssetSubtitles, "no captions")
+	}
+	// MP4Box adds tracks next to the ones already there, so only an MP4
+	// rebuilt in this run is embedded into
+	if existingMP4 {
+		skipEmbedding("recording.mp4 exists; embedding again would duplicate its tracks",
+			vttPath, chatVTTPath, chaptersPath, assets)
+	} else {
+		embedTracks(ctx, result.MP4Path, vttPath, chatVTTPath, chaptersPath, opts.MP4Box, assets, logger)
+	}
+	result.Assets = assets.list()
+
+	if err := applyRetention(dir, retention, &result); err != nil {
+		warn(fmt.Sprintf("apply retention policy %s: %v", retention, err))
+	}
+
+	m := buildMetadata(dir, info, result)
+	m.DownloadedAt = meta.DownloadedAt
+	now := time.Now().UTC()
+	m.ReprocessedAt = &now
+	if err := saveMetadata(dir, m); err != nil {
+		warn(fmt.Sprintf("write metadata: %v", err))
+	}
+	emit(opts.OnEvent, Event{Kind: EventFinished, URL: meta.SourceURL, Path: dir, Message: result.Title})
+	return result, nil
+}
+
+// restoreRawStreams makes sure raw/ holds the streams the derived artifacts
+// are built from, extracting raw.zip or re-fetching only if it doesn't. When
+// recording.mp4 has to be rebuilt and the FLV streams were pruned, those are
+// fetched too.
+func (d *Downloader) restoreRawStreams(
+	ctx context.Context,
+	info recordingInfo,
+	meta metadata,
+	rawDir, zipPath string,
+	opts Options,
+	logger Logger,
+) error {
+	media := needsMediaStreams(filepath.Dir(rawDir), rawDir, meta)
+	if hasRawStreams(rawDir) {
+		log(logger, "using extracted raw data", "path", rawDir)
+		if !media {
+			return nil
+		}
+		// The XML streams are enough for everything but the MP4, whose asset
+		// records the missing streams
+		logInfo(logger, "media streams were deleted, fetching them again")
+		if err := d.fetchRawEntries(ctx, info, meta, rawDir, zipPath, isMediaEntry, opts, logger); err != nil {
+			logInfo(logger, "media streams unavailable", "error", err)
+		}
+		return nil
+	}
+	if _, err := os.Stat(zipPath); err == nil {
+		logInfo(logger, "extracting zip", "path", zipPath)
+		return extractZip(zipPath, rawDir, opts.ExtractLimits)
+	}
+
+	keep := isNeededRawEntry
+	if media {
+		keep = func(name string) bool { return isNeededRawEntry(name) || isMediaEntry(name) }
+	}
+	logInfo(logger, "raw data was deleted, fetching it again")
+	return d.fetchRawEntries(ctx, info, meta, rawDir, zipPath, keep, opts, logger)
+}
+
+// fetchRawEntries extracts the raw ZIP entries accepted by keep from the
+// server with range requests, falling back to downloading and extracting the
+// whole ZIP.
+func (d *Downloader) fetchRawEntries(
+	ctx context.Context,
+	info recordingInfo,
+	meta metadata,
+	rawDir, zipPath string,
+	keep func(name string) bool,
+	opts Options,
+	logger Logger,
+) error {
+	session := ResolveSession(meta.SourceURL, opts.Session)
+	cookies := mergeCookies(session, nil)
+	zipURL := fmt.Sprintf("%s/output/%s.zip?download=zip", info.BaseURL, info.ID)
+	log(logger, "fetching raw zip entries", "url", zipURL)
+	reqOpts := requestOptions{Cookies: cookies, Referer: info.Source}
+	err := d.extractRemoteZip(ctx, zipURL, rawDir, keep, reqOpts, opts.ExtractLimits, logger)
+	if err == nil {
+		return nil
+	}
+	logInfo(logger, "selective extraction unavailable, downloading the full zip", "error", err)
+	if err := d.downloadFile(ctx, zipURL, zipPath, downloadOptions{
+		Cookies: cookies,
+		Referer: info.Source,
+		Kind:    fileKindZip,
+	}, logger); err != nil {
+		os.Remove(zipPath)
+		return err
+	}
+	return extractZip(zipPath, rawDir, opts.ExtractLimits)
+}
+
+// needsMediaStreams reports whether recording.mp4 in dir is missing, so
+// Reprocess rebuilds it, but raw/ has no FLV streams to rebuild it from, as
+// after the keep-xml-only and delete retention policies. Whether the MP4 was
+// downloaded or rebuilt before doesn't matter, but streams an earlier remux
+// found unusable, or that carry no codec the remuxer copies, aren't fetched
+// again.
+func needsMediaStreams(dir, rawDir string, meta metadata) bool {
+	if _, err := os.Stat(filepath.Join(dir, "recording.mp4")); err == nil {
+		return false
+	}
+	if files, _ := filepath.Glob(filepath.Join(rawDir, "*.flv")); len(files) > 0 {
+		return false
+	}
+	if i := slices.IndexFunc(meta.Assets, func(a AssetStatus) bool { return a.Name == AssetRemux }); i >= 0 &&
+		meta.Assets[i].State == StateMissing {
+		return false
+	}
+	return hasRemuxableStream(meta.Streams)
+}
@@ -0,0 +1,105 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Retention decides which raw data is kept once the derived artifacts
+// (captions, transcript, chat log, documents) have been produced.
+type Retention string
+
+const (
+	RetainAll       Retention = "keep-all"       // Keep raw.zip and raw/
+	RetainZip       Retention = "keep-zip"       // Keep raw.zip, delete raw/
+	RetainExtracted Retention = "keep-extracted" // Keep raw/, delete raw.zip
+	RetainXMLOnly   Retention = "keep-xml-only"  // Keep the XML and caption streams in raw/, delete the rest
+	RetainNone      Retention = "delete"         // Delete raw.zip and raw/
+)
+
+// Retentions lists the valid retention policies.
+var Retentions = []Retention{RetainAll, RetainZip, RetainExtracted, RetainXMLOnly, RetainNone}
+
+// ParseRetention validates a retention policy name. The empty string means RetainAll.
+func ParseRetention(name string) (Retention, error) {
+	if name == "" {
+		return RetainAll, nil
+	}
+	for _, r := range Retentions {
+		if string(r) == name {
+			return r, nil
+		}
+	}
+	return "", fmt.Errorf("unknown retention policy %q (want one of %s)", name, joinRetentions())
+}
+
+func joinRetentions() string {
+	names := make([]string, len(Retentions))
+	for i, r := range Retentions {
+		names[i] = string(r)
+	}
+	return strings.Join(names, ", ")
+}
+
+// applyRetention removes the raw data policy doesn't keep from a recording
+// directory and clears the matching Result paths.
+func applyRetention(rootDir string, policy Retention, res *Result) error {
+	zipPath := filepath.Join(rootDir, "raw.zip")
+	rawDir := filepath.Join(rootDir, "raw")
+	res.Retention = policy
+
+	var errs []error
+	if policy == RetainExtracted || policy == RetainXMLOnly || policy == RetainNone {
+		if err := os.Remove(zipPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+		res.ZipPath = ""
+	}
+	switch policy {
+	case RetainZip, RetainNone:
+		if err := os.RemoveAll(rawDir); err != nil {
+			errs = append(errs, err)
+		}
+		res.ExtractedDir = ""
+	case RetainXMLOnly:
+		errs = append(errs, pruneRawDir(rawDir))
+	}
+	return errors.Join(errs...)
+}
+
+// pruneRawDir deletes everything in raw/ except the XML and caption streams,
+// mostly the FLV media.
+func pruneRawDir(rawDir string) error {
+	return filepath.WalkDir(rawDir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".xml", ".vtt":
+			return nil
+		}
+		return os.Remove(path)
+	})
+}
+
+// hasRawStreams reports whether raw/ holds the streams the derived artifacts
+// are built from.
+func hasRawStreams(rawDir string) bool {
+	entries, err := os.ReadDir(rawDir)
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if !e.IsDir() && isNeededRawEntry(e.Name()) {
+			return true
+		}
+	}
+	return false
+}
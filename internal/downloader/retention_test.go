@@ -0,0 +1,63 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseRetention(t *testing.T) {
+	if r, err := ParseRetention(""); err != nil || r != RetainAll {
+		t.Errorf("empty policy: %q, %v", r, err)
+	}
+	if r, err := ParseRetention("keep-xml-only"); err != nil || r != RetainXMLOnly {
+		t.Errorf("keep-xml-only: %q, %v", r, err)
+	}
+	if _, err := ParseRetention("keep-some"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	files := []string{"raw.zip", "raw/indexstream.xml", "raw/rec.vtt", "raw/cameraVoip_1_3.flv", "captions.vtt"}
+	tests := []struct {
+		policy Retention
+		kept   []string
+	}{
+		{RetainAll, files},
+		{RetainZip, []string{"raw.zip", "captions.vtt"}},
+		{RetainExtracted, []string{"raw/indexstream.xml", "raw/rec.vtt", "raw/cameraVoip_1_3.flv", "captions.vtt"}},
+		{RetainXMLOnly, []string{"raw/indexstream.xml", "raw/rec.vtt", "captions.vtt"}},
+		{RetainNone, []string{"captions.vtt"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			root := t.TempDir()
+			for _, name := range files {
+				path := filepath.Join(root, filepath.FromSlash(name))
+				os.MkdirAll(filepath.Dir(path), 0o755)
+				if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			res := Result{ZipPath: filepath.Join(root, "raw.zip"), ExtractedDir: filepath.Join(root, "raw")}
+			if err := applyRetention(root, tt.policy, &res); err != nil {
+				t.Fatalf("applyRetention error: %v", err)
+			}
+
+			kept := map[string]bool{}
+			for _, name := range tt.kept {
+				kept[name] = true
+			}
+			for _, name := range files {
+				_, err := os.Stat(filepath.Join(root, filepath.FromSlash(name)))
+				if exists := err == nil; exists != kept[name] {
+					t.Errorf("%s: exists = %v, want %v", name, exists, kept[name])
+				}
+			}
+			if (res.ZipPath != "") != kept["raw.zip"] || res.Retention != tt.policy {
+				t.Errorf("unexpected result: %+v", res)
+			}
+		})
+	}
+}
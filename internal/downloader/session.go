@@ -0,0 +1,265 @@
+package downloader
+
+import (
+	"cmp"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// sessionSchemaVersion is bumped whenever the session.json layout changes.
+const sessionSchemaVersion = 1
+
+// Session event types, normalized from the methods and fields Connect records.
+const (
+	SessionJoin      = "join"         // A participant joined the meeting or the stage
+	SessionLeave     = "leave"        // A participant left
+	SessionChat      = "chat"         // A chat message
+	SessionFileShare = "file_share"   // A document was shared in a file share pod
+	SessionSlide     = "slide_change" // A share pod moved to another page or slide
+	SessionPod       = "pod_change"   // A pod or layout was shown, hidden or reconfigured
+	SessionMetadata  = "metadata"     // Stream metadata such as the attendee list
+	SessionMessage   = "message"      // Anything else
+)
+
+// SessionTimeline is the time-ordered event log of a recording, written to
+// session.json.
+type SessionTimeline struct {
+	SchemaVersion int            `json:"schema_version"`
+	Start         time.Time      `json:"start,omitzero"` // Wall clock start of the recording, when known
+	Events        []SessionEvent `json:"events"`
+}
+
+// SessionEvent is one normalized message of the raw recording.
+type SessionEvent struct {
+	Offset    int64     `json:"offset_ms"`          // Milliseconds from the start of the recording
+	Timestamp time.Time `json:"timestamp,omitzero"` // Start plus Offset, when the start is known
+	Pod       string    `json:"pod"`                // Stream the message was recorded in, e.g. "ftfileshare1"
+	Type      string    `json:"type"`               // One of the Session* types
+	Method    string    `json:"method,omitempty"`   // Connect's method name, e.g. "setValue"
+	Actor     string    `json:"actor,omitempty"`    // Participant, with anonymous IDs mapped to real names
+	Payload   any       `json:"payload,omitempty"`  // The message arguments as JSON values
+}
+
+// sessionStreams match the raw stream files the timeline is built from.
+var sessionStreams = []string{"indexstream.xml", "mainstream.xml", "transcriptstream.xml", "ft*.xml"}
+
+// actorFields are the fields naming a participant, in order of preference.
+// Chat messages name their sender in a plain "name" field, which other pods
+// use for documents and pods.
+var actorFields = []string{"fullName", "userName", "anonymousName"}
+
+// extractSessionTimeline reads every stream in rawDir into one timeline,
+// ordered by offset and then by stream name. Streams that cannot be parsed
+// completely still contribute the messages before the error.
+func extractSessionTimeline(rawDir string, start time.Time, userMapping map[string]string) SessionTimeline {
+	timeline := SessionTimeline{SchemaVersion: sessionSchemaVersion, Start: start, Events: []SessionEvent{}}
+	seen := make(map[string]bool)
+	for _, pattern := range sessionStreams {
+		files, _ := filepath.Glob(filepath.Join(rawDir, pattern))
+		for _, file := range files {
+			if seen[file] {
+				continue
+			}
+			seen[file] = true
+			_ = readStream(file, func(m StreamMessage) bool {
+				timeline.Events = append(timeline.Events, newSessionEvent(m, start, userMapping))
+				return true
+			})
+		}
+	}
+	slices.SortStableFunc(timeline.Events, func(a, b SessionEvent) int {
+		return cmp.Or(cmp.Compare(a.Offset, b.Offset), cmp.Compare(a.Pod, b.Pod))
+	})
+	return timeline
+}
+
+// newSessionEvent normalizes one stream message.
+func newSessionEvent(m StreamMessage, start time.Time, userMapping map[string]string) SessionEvent {
+	ev := SessionEvent{Offset: m.Time, Pod: m.Pod, Type: sessionEventType(m), Method: m.Method}
+	ev.Actor = sessionActor(m, ev.Type == SessionChat, userMapping)
+	if !start.IsZero() {
+		ev.Timestamp = start.Add(time.Duration(m.Time) * time.Millisecond)
+	}
+	switch len(m.Args) {
+	case 0:
+	case 1:
+		ev.Payload = nodeValue(m.Args[0])
+	default:
+		args := make([]any, len(m.Args))
+		for i, arg := range m.Args {
+			args[i] = nodeValue(arg)
+		}
+		ev.Payload = args
+	}
+	return ev
+}
+
+// sessionEventType classifies a message by its stream, method and fields.
+func sessionEventType(m StreamMessage) string {
+	method := strings.ToLower(m.Method)
+	var chat, file, page, joined, left bool
+	m.Walk(func(n *StreamNode) {
+		switch {
+		case n.Name == "iconType" && n.Text == "chat":
+			chat = true
+		case n.Name == "newValue" && n.Field("name") != "" && strings.HasPrefix(m.Pod, "ftfileshare"):
+			file = true
+		case n.Name == "currentPage" || n.Name == "pageNumber" || n.Name == "slideIndex":
+			page = true
+		case strings.Contains(n.Text, "has joined"):
+			joined = true
+		case strings.Contains(n.Text, "has left"):
+			left = true
+		}
+	})
+
+	switch {
+	case chat:
+		return SessionChat
+	case file:
+		return SessionFileShare
+	case page || strings.Contains(method, "page") || strings.Contains(method, "slide"):
+		return SessionSlide
+	case joined || strings.Contains(method, "join") || method == "useradded":
+		return SessionJoin
+	case left || strings.Contains(method, "leave") || strings.Contains(method, "left") || method == "userremoved":
+		return SessionLeave
+	case method == "onmetadata":
+		return SessionMetadata
+	case strings.Contains(method, "layout") || strings.Contains(method, "pod"):
+		return SessionPod
+	case strings.HasPrefix(m.Pod, "ft") && method == "setvalue":
+		return SessionPod
+	}
+	return SessionMessage
+}
+
+// sessionActor returns the participant a message is about, if it names one.
+// Stage join notices name the participant as "[Tech - Name]". Messages that
+// list several participants, such as the attendee list, have no actor.
+func sessionActor(m StreamMessage, chat bool, userMapping map[string]string) string {
+	for _, arg := range m.Args {
+		if countUserNodes(arg) > 1 {
+			return ""
+		}
+	}
+	fields := actorFields
+	if chat {
+		fields = append([]string{"name"}, actorFields...)
+	}
+	var actor string
+	for _, field := range fields {
+		for _, arg := range m.Args {
+			if n := arg.Find(field); n != nil && n.Text != "" {
+				actor = n.Text
+				break
+			}
+		}
+		if actor != "" {
+			break
+		}
+	}
+	if actor == "" {
+		m.Walk(func(n *StreamNode) {
+			if match := techRe.FindStringSubmatch(n.Text); actor == "" && len(match) >= 2 {
+				actor = match[1]
+			}
+		})
+	}
+	if name, ok := userMapping[actor]; ok {
+		return name
+	}
+	return strings.TrimSpace(strings.TrimPrefix(actor, "Tech "))
+}
+
+// countUserNodes returns how many nodes in n's tree describe a participant,
+// i.e. have one of the actorFields as a direct child.
+func countUserNodes(n *StreamNode) int {
+	count := 0
+	n.Walk(func(c *StreamNode) {
+		for _, field := range actorFields {
+			if c.Child(field) != nil {
+				count++
+				return
+			}
+		}
+	})
+	return count
+}
+
+// nodeValue converts a node to a JSON value: leaves become strings, <Array>
+// and other nodes whose children share one name become lists, and everything
+// else an object keyed by child name. Repeated keys collect into a list.
+func nodeValue(n *StreamNode) any {
+	if len(n.Children) == 0 {
+		return n.Text
+	}
+	if n.Name == "Array" || (len(n.Children) > 1 && allNamed(n.Children, n.Children[0].Name)) {
+		list := make([]any, len(n.Children))
+		for i, c := range n.Children {
+			list[i] = nodeValue(c)
+		}
+		return list
+	}
+	counts := make(map[string]int, len(n.Children))
+	for _, c := range n.Children {
+		counts[c.Name]++
+	}
+	obj := make(map[string]any, len(counts))
+	for _, c := range n.Children {
+		if counts[c.Name] == 1 {
+			obj[c.Name] = nodeValue(c)
+			continue
+		}
+		list, _ := obj[c.Name].([]any)
+		obj[c.Name] = append(list, nodeValue(c))
+	}
+	return obj
+}
+
+func allNamed(nodes []*StreamNode, name string) bool {
+	for _, n := range nodes {
+		if n.Name != name {
+			return false
+		}
+	}
+	return true
+}
+
+// writeSessionTimeline writes the timeline as indented JSON to path.
+func writeSessionTimeline(path string, timeline SessionTimeline) error {
+	data, err := json.MarshalIndent(timeline, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// writeSession builds session.json from rawDir and records its status. The
+// recording start from details, when available, adds wall clock timestamps.
+func writeSession(
+	rootDir, rawDir string,
+	details *RecordingDetails,
+	userMapping map[string]string,
+	assets *assetTracker,
+	logger Logger,
+) {
+	var start time.Time
+	if details != nil {
+		start = details.DateBegin
+	}
+	sessionStart := time.Now()
+	timeline := extractSessionTimeline(rawDir, start, userMapping)
+	sessionPath := filepath.Join(rootDir, "session.json")
+	if err := writeSessionTimeline(sessionPath, timeline); err != nil {
+		log(logger, "session timeline write failed", "error", err)
+		assets.fail(AssetSession, time.Since(sessionStart), err)
+		return
+	}
+	log(logger, "session timeline created", "path", sessionPath, "events", len(timeline.Events))
+	assets.ok(AssetSession, sessionPath, time.Since(sessionStart))
+}
@@ -0,0 +1,48 @@
+package downloader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExtractSessionTimeline(t *testing.T) {
+	rawDir := lectureStreams(t, "lecture3")
+
+	start := time.Date(2025, 3, 5, 10, 0, 0, 0, time.UTC)
+	timeline := extractSessionTimeline(rawDir, start, extractUserMapping(rawDir))
+
+	want := []struct {
+		offset int64
+		pod    string
+		typ    string
+		actor  string
+	}{
+		// The attendee list names everyone, so no one is the actor
+		{0, "indexstream", SessionMetadata, ""},
+		{2000, "indexstream", SessionJoin, "Jane Doe"},
+		{3000, "ftfileshare1", SessionFileShare, ""},
+		{5000, "ftfileshare1", SessionSlide, ""},
+		{5000, "transcriptstream", SessionChat, "James Lewis"},
+	}
+	if len(timeline.Events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(timeline.Events), len(want), timeline.Events)
+	}
+	for i, w := range want {
+		ev := timeline.Events[i]
+		if ev.Offset != w.offset || ev.Pod != w.pod || ev.Type != w.typ || ev.Actor != w.actor {
+			t.Errorf("event %d = %+v, want %+v", i, ev, w)
+		}
+		if !ev.Timestamp.Equal(start.Add(time.Duration(w.offset) * time.Millisecond)) {
+			t.Errorf("event %d timestamp = %v", i, ev.Timestamp)
+		}
+	}
+
+	args, ok := timeline.Events[2].Payload.([]any)
+	if !ok || len(args) != 2 || args[0] != "share" {
+		t.Fatalf("unexpected file share payload: %#v", timeline.Events[2].Payload)
+	}
+	obj, _ := args[1].(map[string]any)
+	if nv, _ := obj["newValue"].(map[string]any); nv["name"] != "Slides.pdf" {
+		t.Errorf("unexpected file share payload: %#v", args[1])
+	}
+}
@@ -0,0 +1,182 @@
+package downloader
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SlideChange is a document page shown in a share pod, from Offset until End.
+type SlideChange struct {
+	Pod         string `json:"pod"`      // Stream the share pod was recorded in, e.g. "ftcontent5"
+	Offset      int64  `json:"start_ms"` // Milliseconds from the start of the recording
+	Time        string `json:"start_time"`
+	End         int64  `json:"end_ms,omitempty"` // 0 when shown until the end of the recording
+	EndTime     string `json:"end_time,omitempty"`
+	Document    string `json:"document,omitempty"` // Name as shown in the share pod
+	DocumentURL string `json:"document_url,omitempty"`
+	Path        string `json:"path,omitempty"` // Downloaded copy, relative to the recording directory
+	Page        int    `json:"page"`           // Page or slide number, from 1
+}
+
+// shareStreams match the raw streams of share pods.
+var shareStreams = []string{"ftcontent*.xml", "ftshare*.xml"}
+
+// Fields of share pod objects naming the shared document. Page numbers use
+// pageFields; slideIndex counts from 0 and the others from 1.
+var shareDocumentFields = []string{"documentName", "fileName", "contentName", "name", "title"}
+
+// extractSlides reads which document and page each share pod in rawDir showed
+// over time, matching documents to docs by name. Path is only set for
+// documents downloaded to rootDir/documents. A change lasts until the next
+// change in the same pod. It returns fs.ErrNotExist when no share pod showed a
+// document page.
+func extractSlides(rootDir, rawDir string, docs []DocumentInfo) ([]SlideChange, error) {
+	var slides []SlideChange
+	var errs []error
+	for _, file := range globStreams(rawDir, shareStreams) {
+		var cur SlideChange
+		var pod []SlideChange
+		err := readStream(file, func(m StreamMessage) bool {
+			next := cur
+			m.Walk(func(n *StreamNode) {
+				if name := firstField(n, shareDocumentFields); name != "" && isDocumentName(name) {
+					if name != next.Document {
+						next.Page = 1
+					}
+					next.Document = name
+				}
+				if page := n.Field("slideIndex"); page != "" {
+					next.Page = int(parseInt(page)) + 1
+				} else if page := firstField(n, pageFields); page != "" {
+					next.Page = int(parseInt(page))
+				}
+			})
+			if next.Page <= 0 || (next.Document == cur.Document && next.Page == cur.Page) {
+				return true
+			}
+			next.Pod, next.Offset = m.Pod, m.Time
+			if n := len(pod); n > 0 {
+				pod[n-1].End = m.Time
+			}
+			pod = append(pod, next)
+			cur = next
+			return true
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+		slides = append(slides, pod...)
+	}
+
+	for i := range slides {
+		s := &slides[i]
+		s.Time = formatMilliseconds(s.Offset)
+		if s.End > 0 {
+			s.EndTime = formatMilliseconds(s.End)
+		}
+		if doc := matchDocument(s.Document, docs); doc != nil {
+			s.DocumentURL = doc.DownloadURL
+			local := path.Join("documents", sanitize(doc.Name))
+			if _, err := os.Stat(filepath.Join(rootDir, filepath.FromSlash(local))); err == nil {
+				s.Path = local
+			}
+		}
+	}
+	slices.SortStableFunc(slides, func(a, b SlideChange) int {
+		return cmp.Or(cmp.Compare(a.Offset, b.Offset), cmp.Compare(a.Pod, b.Pod))
+	})
+	if len(slides) == 0 {
+		if len(errs) > 0 {
+			return nil, errs[0]
+		}
+		return nil, fmt.Errorf("no share pod slides in the raw recording: %w", fs.ErrNotExist)
+	}
+	return slides, nil
+}
+
+// isDocumentName reports whether a share pod name field names a document
+// rather than the pod itself: it has a file extension.
+func isDocumentName(name string) bool {
+	ext := path.Ext(name)
+	return len(ext) > 1 && len(ext) <= 6 && !strings.ContainsAny(ext, " /")
+}
+
+// matchDocument finds the shared document a share pod showed. Connect may
+// show a converted copy, so names also match without their extension.
+func matchDocument(name string, docs []DocumentInfo) *DocumentInfo {
+	if name == "" {
+		return nil
+	}
+	if unescaped, err := url.PathUnescape(path.Base(name)); err == nil {
+		name = unescaped
+	}
+	stem := func(s string) string { return strings.ToLower(strings.TrimSuffix(s, path.Ext(s))) }
+	for _, exact := range []bool{true, false} {
+		for i, doc := range docs {
+			if (exact && strings.EqualFold(doc.Name, name)) || (!exact && stem(doc.Name) == stem(name)) {
+				return &docs[i]
+			}
+		}
+	}
+	return nil
+}
+
+// slidesCSV renders one row per slide change.
+func slidesCSV(slides []SlideChange) ([]byte, error) {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	cw.Write([]string{"start_ms", "start_time", "end_ms", "end_time", "pod", "document", "page", "path"})
+	for _, s := range slides {
+		end := ""
+		if s.End > 0 {
+			end = strconv.FormatInt(s.End, 10)
+		}
+		cw.Write([]string{
+			strconv.FormatInt(s.Offset, 10), s.Time, end, s.EndTime, s.Pod, s.Document, strconv.Itoa(s.Page), s.Path,
+		})
+	}
+	cw.Flush()
+	return buf.Bytes(), cw.Error()
+}
+
+// writeSlides writes slides.json and slides.csv from the share pods in
+// rawDir and records the status of the slides asset.
+func writeSlides(rootDir, rawDir string, docs []DocumentInfo, assets *assetTracker, logger Logger) {
+	start := time.Now()
+	slides, err := extractSlides(rootDir, rawDir, docs)
+	if err != nil {
+		log(logger, "slide timeline extraction failed", "error", err)
+		assets.fail(AssetSlides, time.Since(start), err)
+		return
+	}
+	jsonPath := filepath.Join(rootDir, "slides.json")
+	data, err := json.MarshalIndent(slides, "", "  ")
+	if err == nil {
+		err = os.WriteFile(jsonPath, data, 0o644)
+	}
+	if err == nil {
+		data, err = slidesCSV(slides)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(rootDir, "slides.csv"), data, 0o644)
+	}
+	if err != nil {
+		log(logger, "slide timeline write failed", "error", err)
+		assets.fail(AssetSlides, time.Since(start), err)
+		return
+	}
+	log(logger, "slide timeline created", "path", jsonPath, "changes", len(slides))
+	assets.ok(AssetSlides, jsonPath, time.Since(start))
+}
@@ -0,0 +1,73 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractSlides(t *testing.T) {
+	rootDir := t.TempDir()
+	rawDir := lectureStreams(t, "lecture12")
+	if err := os.MkdirAll(filepath.Join(rootDir, "documents"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// Only the handout was downloaded, so only its slides link to a local copy
+	if err := os.WriteFile(filepath.Join(rootDir, "documents", "Handout.pdf"), []byte("%PDF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	docs := []DocumentInfo{
+		{Name: "Handout.pdf", DownloadURL: "https://connect.example.edu/p1/Handout.pdf?download=true"},
+		{Name: "Lecture 1.pdf", DownloadURL: "https://connect.example.edu/p1/Lecture%201.pdf?download=true"},
+	}
+
+	slides, err := extractSlides(rootDir, rawDir, docs)
+	if err != nil {
+		t.Fatalf("extractSlides error: %v", err)
+	}
+	lecture := func(offset, end int64, page int) SlideChange {
+		return SlideChange{
+			Pod: "ftcontent5", Offset: offset, Time: formatMilliseconds(offset), End: end,
+			EndTime: formatMilliseconds(end), Document: "Lecture%201.pptx", DocumentURL: docs[1].DownloadURL,
+			Page: page,
+		}
+	}
+	want := []SlideChange{
+		lecture(1000, 30000, 1),
+		lecture(30000, 90000, 3),
+		{
+			Pod: "ftcontent5", Offset: 90000, Time: "00:01:30", End: 95000, EndTime: "00:01:35",
+			Document: "Handout.pdf", DocumentURL: docs[0].DownloadURL, Path: "documents/Handout.pdf", Page: 1,
+		},
+		{
+			Pod: "ftcontent5", Offset: 95000, Time: "00:01:35", Document: "Handout.pdf",
+			DocumentURL: docs[0].DownloadURL, Path: "documents/Handout.pdf", Page: 4,
+		},
+	}
+	if !reflect.DeepEqual(slides, want) {
+		t.Errorf("slides =\n%+v\nwant\n%+v", slides, want)
+	}
+
+	assets := &assetTracker{root: rootDir}
+	writeSlides(rootDir, rawDir, docs, assets, nil)
+	csvData, _ := os.ReadFile(filepath.Join(rootDir, "slides.csv"))
+	for _, line := range []string{
+		"30000,00:00:30,90000,00:01:30,ftcontent5,Lecture%201.pptx,3,\n",
+		"95000,00:01:35,,,ftcontent5,Handout.pdf,4,documents/Handout.pdf\n",
+	} {
+		if !strings.Contains(string(csvData), line) {
+			t.Errorf("slides.csv missing %q:\n%s", line, csvData)
+		}
+	}
+	if got := assets.list(); len(got) != 1 || got[0].State != StateOK || got[0].Path != "slides.json" {
+		t.Errorf("assets = %+v", got)
+	}
+}
+
+func TestWriteSlidesWithoutSharePod(t *testing.T) {
+	assertWriterMissing(t, "", func(rootDir, rawDir string, assets *assetTracker) {
+		writeSlides(rootDir, rawDir, nil, assets, nil)
+	})
+}
@@ -0,0 +1,185 @@
+package downloader
+
+import (
+	"cmp"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// AssetState is the outcome for one asset of a recording.
+type AssetState string
+
+const (
+	StateOK      AssetState = "ok"      // Saved to Path
+	StateSkipped AssetState = "skipped" // Not attempted because a prerequisite is missing
+	StateMissing AssetState = "missing" // The server does not offer it for this recording
+	StateFailed  AssetState = "failed"  // Attempted but failed; see Error
+)
+
+// Asset names used in AssetStatus.Name, in the order they are reported.
+const (
+	AssetPage       = "page"
+	AssetZip        = "zip"
+	AssetExtraction = "extraction"
+	AssetMP4        = "mp4"
+	AssetRemux      = "remux" // recording.mp4 rebuilt from the raw FLV streams when there is no MP4 rendition
+	AssetCaptions   = "captions"
+	AssetTranscript = "transcript"
+	AssetChat       = "chat"
+	AssetChatVTT    = "chat_vtt"   // Public chat as subtitles in chat.vtt
+	AssetSession    = "session"    // Event timeline in session.json
+	AssetQA         = "qa"         // Q&A pod questions in qa.json and qa.md
+	AssetPolls      = "polls"      // Poll questions and results in polls.json and polls.md
+	AssetNotes      = "notes"      // Notes pod content and shared links in notes.md
+	AssetLinks      = "links"      // Web Links pod entries and chat URLs in links.html
+	AssetWhiteboard = "whiteboard" // Whiteboard drawings as SVGs in whiteboards/
+	AssetAttendance = "attendance" // Attendance report in attendance.json and attendance.csv
+	AssetSlides     = "slides"     // Share pod document pages over time in slides.json and slides.csv
+	AssetChapters   = "chapters"   // Chapter list in chapters.txt and chapters.vtt
+	AssetSubtitles  = "subtitles"  // Captions embedded into the MP4
+	AssetChatTrack  = "chat_track" // Chat from chat.vtt embedded into the MP4 as a second subtitle track
+	AssetMarkers    = "markers"    // Chapters embedded into the MP4
+	AssetDocument   = "document"   // One entry per shared document
+)
+
+var assetOrder = []string{
+	AssetPage, AssetZip, AssetExtraction, AssetMP4, AssetRemux, AssetCaptions,
+	AssetTranscript, AssetChat, AssetChatVTT, AssetSession, AssetQA, AssetPolls, AssetNotes, AssetLinks,
+	AssetWhiteboard, AssetAttendance, AssetSlides, AssetChapters, AssetSubtitles, AssetChatTrack,
+	AssetMarkers, AssetDocument,
+}
+
+// rawAssets are built from the extracted raw recording, so they are all
+// skipped when it isn't available.
+var rawAssets = []string{
+	AssetChat, AssetChatVTT, AssetSession, AssetQA, AssetPolls, AssetNotes, AssetLinks, AssetWhiteboard, AssetAttendance,
+	AssetSlides, AssetChapters, AssetChatTrack, AssetRemux,
+}
+
+// AssetStatus records what happened to one asset of a recording.
+type AssetStatus struct {
+	Name            string        `json:"name"`
+	Document        string        `json:"document,omitempty"` // Original name, for AssetDocument entries
+	State           AssetState    `json:"state"`
+	Path            string        `json:"path,omitempty"` // Relative to the recording directory
+	Size            int64         `json:"size,omitempty"`
+	Error           string        `json:"error,omitempty"`
+	Warning         string        `json:"warning,omitempty"`  // Why a saved asset is incomplete
+	Category        ErrorCategory `json:"category,omitempty"` // Why a failed asset failed
+	DurationSeconds float64       `json:"duration_seconds,omitempty"`
+}
+
+// Asset returns the status of the named asset. For documents it returns the
+// first entry; range over Assets to see all of them.
+func (r Result) Asset(name string) (AssetStatus, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return AssetStatus{}, false
+}
+
+// assetTracker collects asset statuses from the goroutines of one download.
+type assetTracker struct {
+	mu     sync.Mutex
+	root   string
+	assets []AssetStatus
+}
+
+// ok records a saved asset. path is absolute (or relative to the working
+// directory) and its size is read from disk.
+func (t *assetTracker) ok(name, path string, took time.Duration) {
+	t.add(AssetStatus{Name: name, State: StateOK}, path, took)
+}
+
+// incomplete records a saved asset that lacks some of its content.
+func (t *assetTracker) incomplete(name, path string, took time.Duration, warning string) {
+	t.add(AssetStatus{Name: name, State: StateOK, Warning: warning}, path, took)
+}
+
+// fail records a failed asset; errors meaning the server has no such asset
+// are recorded as missing.
+func (t *assetTracker) fail(name string, took time.Duration, err error) {
+	t.add(failedStatus(name, err), "", took)
+}
+
+// skip records an asset that was not attempted.
+func (t *assetTracker) skip(name, reason string) {
+	t.add(AssetStatus{Name: name, State: StateSkipped, Error: reason}, "", 0)
+}
+
+// skipRaw records every asset built from the raw recording as not attempted.
+func (t *assetTracker) skipRaw(reason string) {
+	for _, name := range rawAssets {
+		t.skip(name, reason)
+	}
+}
+
+// missing records an asset the server does not offer.
+func (t *assetTracker) missing(name, reason string) {
+	t.add(AssetStatus{Name: name, State: StateMissing, Error: reason}, "", 0)
+}
+
+// document records the outcome of one shared document download.
+func (t *assetTracker) document(doc DocumentInfo, path string, took time.Duration, err error) {
+	if err != nil {
+		status := failedStatus(AssetDocument, err)
+		status.Document = doc.Name
+		t.add(status, "", took)
+		return
+	}
+	t.add(AssetStatus{Name: AssetDocument, Document: doc.Name, State: StateOK}, path, took)
+}
+
+func (t *assetTracker) add(status AssetStatus, path string, took time.Duration) {
+	status.DurationSeconds = took.Seconds()
+	if path != "" {
+		status.Path = filepath.ToSlash(path)
+		if rel, err := filepath.Rel(t.root, path); err == nil {
+			status.Path = filepath.ToSlash(rel)
+		}
+		if st, err := os.Stat(path); err == nil && !st.IsDir() {
+			status.Size = st.Size()
+		}
+	}
+	t.mu.Lock()
+	t.assets = append(t.assets, status)
+	t.mu.Unlock()
+}
+
+// list returns the statuses in report order, documents last in name order.
+func (t *assetTracker) list() []AssetStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := slices.Clone(t.assets)
+	slices.SortStableFunc(out, func(a, b AssetStatus) int {
+		if d := slices.Index(assetOrder, a.Name) - slices.Index(assetOrder, b.Name); d != 0 {
+			return d
+		}
+		return cmp.Compare(a.Document, b.Document)
+	})
+	return out
+}
+
+// failedStatus builds the status for an asset that could not be fetched.
+func failedStatus(name string, err error) AssetStatus {
+	status := AssetStatus{Name: name, State: StateFailed, Category: CategoryOf(err)}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	switch {
+	case status.Category == CategoryNotGenerated:
+		status.State = StateMissing
+	case errors.Is(err, fs.ErrNotExist):
+		// A stream absent from the raw recording, not a failure
+		status.State = StateMissing
+		status.Category = ""
+	}
+	return status
+}
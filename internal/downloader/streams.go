@@ -0,0 +1,128 @@
+package downloader
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/keanucz/AdobeConnectDL/internal/flv"
+)
+
+// MediaStream describes one FLV stream of the raw recording, as listed in
+// metadata.json and by the inspect command.
+type MediaStream struct {
+	Name       string `json:"name"`            // File name in raw/
+	Type       string `json:"type"`            // Kind of stream, e.g. cameraVoip or screenshare
+	Owner      string `json:"owner,omitempty"` // Who published it, when the index stream says
+	Offset     int64  `json:"start_ms"`        // First media from the start of the recording, in milliseconds
+	Time       string `json:"start_time"`
+	Duration   int64  `json:"duration_ms"`
+	VideoCodec string `json:"video_codec,omitempty"`
+	AudioCodec string `json:"audio_codec,omitempty"`
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+	SampleRate int    `json:"sample_rate,omitempty"`
+	Channels   int    `json:"channels,omitempty"`
+	Bitrate    int64  `json:"bitrate,omitempty"` // Bits per second
+	Size       int64  `json:"size"`
+	Error      string `json:"error,omitempty"` // Why the file couldn't be read
+}
+
+// indexedStream is what the index stream says about a media stream.
+type indexedStream struct {
+	offset int64
+	typ    string
+	owner  string
+}
+
+// publisherFields name the user who published a stream in its index entry.
+var publisherFields = []string{"fullName", "userName", "streamPublisherID", "publisherID", "userID", "anonymousName"}
+
+// streamIndex returns what indexstream.xml says about each media stream,
+// keyed by file name without the extension. Streams are announced with a
+// streamName field such as "/cameraVoip_1_3"; the first message naming a
+// stream marks its start. Owners are mapped to real names through
+// userMapping and the user IDs of join messages.
+func streamIndex(rawDir string, userMapping map[string]string) map[string]indexedStream {
+	streams := make(map[string]indexedStream)
+	userIDs := make(map[string]string)
+	readStream(filepath.Join(rawDir, "indexstream.xml"), func(m StreamMessage) bool {
+		m.Walk(func(n *StreamNode) {
+			if id, name := n.Field("userID"), n.Field("fullName"); id != "" && name != "" {
+				userIDs[id] = name
+			}
+			name := strings.TrimPrefix(n.Field("streamName"), "/")
+			if _, seen := streams[name]; name == "" || seen {
+				return
+			}
+			streams[name] = indexedStream{
+				offset: m.Time,
+				typ:    n.Field("streamType"),
+				owner:  firstField(n, publisherFields),
+			}
+		})
+		return true
+	})
+	for name, s := range streams {
+		s.owner = cmp.Or(userIDs[s.owner], userMapping[s.owner], s.owner)
+		streams[name] = s
+	}
+	return streams
+}
+
+// inspectStreams probes every FLV file in rawDir. Files that can't be read
+// are listed with the error.
+func inspectStreams(rawDir string, userMapping map[string]string) []MediaStream {
+	files, _ := filepath.Glob(filepath.Join(rawDir, "*.flv"))
+	if len(files) == 0 {
+		return nil
+	}
+	index := streamIndex(rawDir, userMapping)
+	streams := make([]MediaStream, 0, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		entry := index[name]
+		typ, _, _ := strings.Cut(name, "_")
+		s := MediaStream{Name: filepath.Base(file), Type: cmp.Or(entry.typ, typ), Owner: entry.owner}
+		info, err := flv.Probe(file)
+		if err != nil {
+			s.Error = err.Error()
+		}
+		s.Offset = entry.offset + info.Start
+		s.Time = formatMilliseconds(s.Offset)
+		s.Duration, s.Size, s.Bitrate = info.Duration, info.Size, info.Bitrate
+		s.VideoCodec, s.Width, s.Height = info.VideoCodec, info.Width, info.Height
+		s.AudioCodec, s.SampleRate, s.Channels = info.AudioCodec, info.SampleRate, info.Channels
+		streams = append(streams, s)
+	}
+	return streams
+}
+
+// InspectRecording returns the media stream inventory of a recording
+// directory, or of its raw/ directory: probed from the FLV files while they
+// are there, otherwise as recorded in metadata.json before the retention
+// policy removed them.
+func InspectRecording(dir string) ([]MediaStream, error) {
+	rawDir := dir
+	if st, err := os.Stat(filepath.Join(dir, "raw")); err == nil && st.IsDir() {
+		rawDir = filepath.Join(dir, "raw")
+	}
+	if streams := inspectStreams(rawDir, extractUserMapping(rawDir)); len(streams) > 0 {
+		return streams, nil
+	}
+	meta, err := readMetadata(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no FLV streams or metadata.json in %s: %w", dir, fs.ErrNotExist)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(meta.Streams) == 0 {
+		return nil, fmt.Errorf("no media streams recorded in %s/metadata.json: %w", dir, fs.ErrNotExist)
+	}
+	return meta.Streams, nil
+}
@@ -0,0 +1,26 @@
+package downloader
+
+import (
+	"testing"
+)
+
+func TestInspectStreams(t *testing.T) {
+	rawDir := lectureStreams(t, "lecture14")
+	streams := inspectStreams(rawDir, nil)
+	if len(streams) != 2 {
+		t.Fatalf("inspectStreams = %+v, want 2 streams", streams)
+	}
+	camera, share := streams[0], streams[1]
+	if camera.Type != "cameraVoip" || camera.Error == "" || camera.Size != 12 {
+		t.Errorf("camera = %+v, want a cameraVoip stream that couldn't be read", camera)
+	}
+	want := MediaStream{Name: "screenshare_2_10.flv", Type: "screenshare", Owner: "Jane Doe", Offset: 64000,
+		Time: "00:01:04", Size: 13}
+	if share != want {
+		t.Errorf("screen share = %+v, want %+v", share, want)
+	}
+
+	if streams := inspectStreams(t.TempDir(), nil); streams != nil {
+		t.Errorf("inspectStreams without FLV files = %+v, want nil", streams)
+	}
+}
@@ -0,0 +1,255 @@
+package downloader
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// StreamMessage is one <Message> recorded in a Connect stream XML file such as
+// indexstream.xml, transcriptstream.xml or ftfileshare1.xml.
+type StreamMessage struct {
+	Pod    string        // Stream the message was recorded in, from the file name (e.g. "ftfileshare1")
+	Time   int64         // Milliseconds from the start of the recording
+	Type   string        // type attribute, e.g. "data" or "cycleEntry"
+	Method string        // e.g. "onMetaData", "cycleEntry" or "playEvent"
+	Args   []*StreamNode // Elements following <Method>, in document order
+}
+
+// StreamNode is an element inside a message: a named field, or an <Object>,
+// <Array>, <String>, <Number> or <Boolean> value. Text holds the trimmed
+// character data, CDATA included, of elements without children.
+type StreamNode struct {
+	Name     string
+	Text     string
+	Children []*StreamNode
+}
+
+// Child returns the first direct child with the given name, or nil.
+func (n *StreamNode) Child(name string) *StreamNode {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Field returns the text of the first direct child with the given name.
+func (n *StreamNode) Field(name string) string {
+	if c := n.Child(name); c != nil {
+		return c.Text
+	}
+	return ""
+}
+
+// Find returns the first descendant with the given name, depth first, or nil.
+func (n *StreamNode) Find(name string) *StreamNode {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+		if found := c.Find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Walk calls fn for n and each of its descendants in document order.
+func (n *StreamNode) Walk(fn func(*StreamNode)) {
+	fn(n)
+	for _, c := range n.Children {
+		c.Walk(fn)
+	}
+}
+
+// Walk calls fn for every node in the message's arguments in document order.
+func (m StreamMessage) Walk(fn func(*StreamNode)) {
+	for _, arg := range m.Args {
+		arg.Walk(fn)
+	}
+}
+
+// StreamDecoder reads the messages of a Connect stream XML file one at a
+// time, so large streams are never held in memory whole.
+type StreamDecoder struct {
+	dec *xml.Decoder
+	pod string
+}
+
+// NewStreamDecoder returns a decoder reading from r. Pod is reported in each
+// message; use the file name without extension.
+func NewStreamDecoder(r io.Reader, pod string) *StreamDecoder {
+	dec := xml.NewDecoder(r)
+	dec.Entity = xml.HTMLEntity
+	dec.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	return &StreamDecoder{dec: dec, pod: pod}
+}
+
+// Next returns the next message, or io.EOF after the last one.
+func (s *StreamDecoder) Next() (StreamMessage, error) {
+	for {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return StreamMessage{}, err
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "Message" {
+			return s.readMessage(start)
+		}
+	}
+}
+
+func (s *StreamDecoder) readMessage(start xml.StartElement) (StreamMessage, error) {
+	msg := StreamMessage{Pod: s.pod}
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "time":
+			if ms, err := strconv.ParseFloat(attr.Value, 64); err == nil {
+				msg.Time = int64(ms)
+			}
+		case "type":
+			msg.Type = attr.Value
+		}
+	}
+	body, err := readStreamNode(s.dec, start, 0)
+	if err != nil {
+		return msg, fmt.Errorf("%s: message at %dms: %w", s.pod, msg.Time, err)
+	}
+	for _, c := range body.Children {
+		if c.Name == "Method" && msg.Method == "" {
+			msg.Method = c.Text
+			continue
+		}
+		msg.Args = append(msg.Args, c)
+	}
+	return msg, nil
+}
+
+// maxStreamDepth limits how deeply elements may nest inside a message.
+// Connect nests a few Objects and Arrays; a limit keeps crafted files from
+// exhausting the stack.
+const maxStreamDepth = 64
+
+// errStreamDepth means a message nests elements deeper than maxStreamDepth.
+var errStreamDepth = errors.New("elements nested too deeply")
+
+// readStreamNode reads the element opened by start up to its end tag. Depth
+// counts the elements enclosing start within the message.
+func readStreamNode(dec *xml.Decoder, start xml.StartElement, depth int) (*StreamNode, error) {
+	if depth >= maxStreamDepth {
+		return nil, errStreamDepth
+	}
+	node := &StreamNode{Name: start.Name.Local}
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := readStreamNode(dec, t, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			node.Children = append(node.Children, child)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if len(node.Children) == 0 {
+				node.Text = strings.TrimSpace(text.String())
+			}
+			return node, nil
+		}
+	}
+}
+
+// readStream calls fn for each message in the stream file at path until fn
+// returns false. Messages decoded before a syntax error are still delivered.
+func readStream(path string, fn func(StreamMessage) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := NewStreamDecoder(f, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	for {
+		msg, err := dec.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !fn(msg) {
+			return nil
+		}
+	}
+}
+
+// readStreams calls fn for each message of every stream file matching pattern
+// in rawDir, in file name order, until fn returns false.
+func readStreams(rawDir, pattern string, fn func(StreamMessage) bool) error {
+	files, err := filepath.Glob(filepath.Join(rawDir, pattern))
+	if err != nil {
+		return err
+	}
+	stopped := false
+	var errs []error
+	for _, file := range files {
+		if err := readStream(file, func(m StreamMessage) bool {
+			stopped = !fn(m)
+			return !stopped
+		}); err != nil {
+			errs = append(errs, err)
+		}
+		if stopped {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// firstField returns the text of the first named field n has.
+func firstField(n *StreamNode, names []string) string {
+	for _, name := range names {
+		if v := n.Field(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// nodeTime returns the "time" field of n, or fallback.
+func nodeTime(n *StreamNode, fallback int64) int64 {
+	if t, err := strconv.ParseInt(n.Field("time"), 10, 64); err == nil {
+		return t
+	}
+	return fallback
+}
+
+// parseInt parses s as a base 10 integer, returning 0 if it isn't one.
+func parseInt(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+// globStreams returns the stream files in rawDir matching any of patterns.
+func globStreams(rawDir string, patterns []string) []string {
+	var files []string
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(filepath.Join(rawDir, pattern))
+		files = append(files, matches...)
+	}
+	return files
+}
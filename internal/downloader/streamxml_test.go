@@ -0,0 +1,151 @@
+package downloader
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const multilineStream = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<!--This is the opening comment-->
+<root>
+  <Message time="1500.5" type="cycleEntry">
+    <Method><![CDATA[cycleEntry]]></Method>
+    <String><![CDATA[chatPod]]></String>
+    <Object>
+      <iconType><![CDATA[chat]]></iconType>
+      <label><![CDATA[First line
+second line with ]] brackets &amp; <b>tags</b>]]></label>
+      <name><![CDATA[Ashley Cooper]]></name>
+    </Object>
+  </Message>
+  <Message time="3000" type="data">
+    <Method><![CDATA[onMetaData]]></Method>
+    <Object>
+      <attendees>
+        <Object>
+          <anonymousName><![CDATA[User1]]></anonymousName>
+          <fullName><![CDATA[Tech Jane Doe]]></fullName>
+        </Object>
+        <Object>
+          <anonymousName><![CDATA[User2]]></anonymousName>
+        </Object>
+        <Object>
+          <fullName><![CDATA[James Lewis]]></fullName>
+          <anonymousName><![CDATA[User3]]></anonymousName>
+        </Object>
+      </attendees>
+    </Object>
+  </Message>
+</root>
+`
+
+func TestStreamDecoder(t *testing.T) {
+	dec := NewStreamDecoder(strings.NewReader(multilineStream), "transcriptstream")
+
+	msg, err := dec.Next()
+	if err != nil {
+		t.Fatalf("Next error: %v", err)
+	}
+	if msg.Pod != "transcriptstream" || msg.Time != 1500 || msg.Type != "cycleEntry" || msg.Method != "cycleEntry" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+	if len(msg.Args) != 2 || msg.Args[0].Name != "String" || msg.Args[0].Text != "chatPod" {
+		t.Fatalf("unexpected args: %+v", msg.Args)
+	}
+	want := "First line\nsecond line with ]] brackets &amp; <b>tags</b>"
+	if got := msg.Args[1].Field("label"); got != want {
+		t.Errorf("label = %q, want %q", got, want)
+	}
+
+	msg, err = dec.Next()
+	if err != nil {
+		t.Fatalf("Next error: %v", err)
+	}
+	if n := msg.Args[0].Find("attendees"); n == nil || len(n.Children) != 3 {
+		t.Errorf("expected three attendees, got %+v", n)
+	}
+	if _, err := dec.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestStreamDecoderTruncated(t *testing.T) {
+	truncated := multilineStream[:strings.Index(multilineStream, "<attendees>")]
+	dec := NewStreamDecoder(strings.NewReader(truncated), "indexstream")
+	if _, err := dec.Next(); err != nil {
+		t.Fatalf("first message should decode: %v", err)
+	}
+	if _, err := dec.Next(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("expected a syntax error for the truncated message, got %v", err)
+	}
+}
+
+func TestStreamDecoderDepth(t *testing.T) {
+	deep := "<root><Message time=\"0\">" + strings.Repeat("<a>", 100000) + "</Message></root>"
+	dec := NewStreamDecoder(strings.NewReader(deep), "indexstream")
+	if _, err := dec.Next(); !errors.Is(err, errStreamDepth) {
+		t.Errorf("Next error = %v, want errStreamDepth", err)
+	}
+}
+
+func TestParsersUseTypedStream(t *testing.T) {
+	rawDir := t.TempDir()
+	for _, name := range []string{"indexstream.xml", "transcriptstream.xml"} {
+		if err := os.WriteFile(filepath.Join(rawDir, name), []byte(multilineStream), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// User2 has no full name; User3's must not shift onto it
+	mapping := extractUserMapping(rawDir)
+	if len(mapping) != 2 || mapping["User1"] != "Jane Doe" || mapping["User3"] != "James Lewis" {
+		t.Errorf("unexpected mapping: %v", mapping)
+	}
+
+	out := filepath.Join(rawDir, "chat_log.txt")
+	if err := extractChatLog(rawDir, out); err != nil {
+		t.Fatalf("extractChatLog error: %v", err)
+	}
+	data, _ := os.ReadFile(out)
+	if !strings.Contains(string(data), "[00:00:01] Ashley Cooper: First line\nsecond line") {
+		t.Errorf("unexpected chat log:\n%s", data)
+	}
+}
+
+// lectureStreams copies the raw streams of the test lecture in testdata/name
+// into a temporary directory, so a test can change them freely.
+func lectureStreams(t *testing.T, name string) string {
+	t.Helper()
+	rawDir := t.TempDir()
+	if err := os.CopyFS(rawDir, os.DirFS(filepath.Join("testdata", name))); err != nil {
+		t.Fatal(err)
+	}
+	return rawDir
+}
+
+// assertWriterMissing runs write over a recording holding the streams of the
+// test lecture name, or none when it's empty, and checks that every asset it
+// records is missing.
+func assertWriterMissing(t *testing.T, name string, write func(rootDir, rawDir string, assets *assetTracker)) {
+	t.Helper()
+	rawDir := t.TempDir()
+	if name != "" {
+		rawDir = lectureStreams(t, name)
+	}
+	rootDir := t.TempDir()
+	assets := &assetTracker{root: rootDir}
+	write(rootDir, rawDir, assets)
+	list := assets.list()
+	if len(list) == 0 {
+		t.Fatal("no asset recorded")
+	}
+	for _, a := range list {
+		if a.State != StateMissing {
+			t.Errorf("asset %s = %s, want missing", a.Name, a.State)
+		}
+	}
+}
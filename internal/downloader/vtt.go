@@ -9,6 +9,11 @@ import (
 	"strings"
 )
 
+// vttTimestamp formats milliseconds as a WebVTT timestamp, HH:MM:SS.mmm.
+func vttTimestamp(ms int64) string {
+	return fmt.Sprintf("%s.%03d", formatMilliseconds(ms), ms%1000)
+}
+
 // vttToTranscript converts a VTT file to a readable plain text transcript.
 // The output is formatted with timestamps and speaker text, suitable for AI tools.
 func vttToTranscript(vttPath, outputPath string) error {
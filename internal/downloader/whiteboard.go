@@ -0,0 +1,486 @@
+package downloader
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Whiteboard shape kinds reported in WhiteboardShape.Kind.
+const (
+	ShapeLine        = "line"
+	ShapeArrow       = "arrow"
+	ShapeRect        = "rect"
+	ShapeEllipse     = "ellipse"
+	ShapePath        = "path"        // Freehand pencil stroke
+	ShapeHighlighter = "highlighter" // Translucent freehand stroke
+	ShapeText        = "text"
+)
+
+// Whiteboard is the drawing on one page of a whiteboard pod, or the
+// annotations on one page of a document in a share pod.
+type Whiteboard struct {
+	Pod       string               `json:"pod"`                // Stream the drawing was recorded in, e.g. "ftwhiteboard6"
+	Document  string               `json:"document,omitempty"` // Annotated document, for share pods
+	Page      int                  `json:"page"`               // Page or slide number, from 1
+	Offset    int64                `json:"offset_ms"`          // Last change, in ms from the start of the recording
+	Time      string               `json:"time"`
+	Shapes    []WhiteboardShape    `json:"shapes"`              // Final state, in drawing order
+	Snapshots []WhiteboardSnapshot `json:"snapshots,omitempty"` // State each time the pod moved to another page
+}
+
+// WhiteboardSnapshot is the drawing on a page when the pod left it.
+type WhiteboardSnapshot struct {
+	Offset int64             `json:"offset_ms"`
+	Time   string            `json:"time"`
+	Shapes []WhiteboardShape `json:"shapes"`
+}
+
+// WhiteboardShape is one drawn shape. Lines and arrows run from X, Y to X2, Y2;
+// rectangles, ellipses and text start at X, Y; strokes follow Points.
+type WhiteboardShape struct {
+	ID          string       `json:"id,omitempty"`
+	Kind        string       `json:"kind"` // One of the Shape* kinds
+	X           float64      `json:"x"`
+	Y           float64      `json:"y"`
+	X2          float64      `json:"x2,omitempty"`
+	Y2          float64      `json:"y2,omitempty"`
+	Width       float64      `json:"width,omitempty"`
+	Height      float64      `json:"height,omitempty"`
+	Points      [][2]float64 `json:"points,omitempty"`
+	Stroke      string       `json:"stroke"`         // CSS color, e.g. "#ff0000"
+	Fill        string       `json:"fill,omitempty"` // CSS color, "" for none
+	StrokeWidth float64      `json:"stroke_width"`
+	Text        string       `json:"text,omitempty"`
+	FontSize    float64      `json:"font_size,omitempty"`
+	Offset      int64        `json:"offset_ms"` // When the shape was drawn or last changed
+}
+
+// whiteboardStreams match the raw streams of whiteboard pods. Share pods,
+// matched by shareStreams, carry annotations drawn over their documents.
+var whiteboardStreams = []string{"ftwhiteboard*.xml", "ftwb*.xml"}
+
+// shapeKinds maps Connect's shape and tool names, lower cased, to shape kinds.
+var shapeKinds = map[string]string{
+	"line": ShapeLine, "linetool": ShapeLine,
+	"arrow": ShapeArrow, "arrowtool": ShapeArrow,
+	"rect": ShapeRect, "rectangle": ShapeRect, "square": ShapeRect,
+	"recttool": ShapeRect, "rectangletool": ShapeRect,
+	"ellipse": ShapeEllipse, "ellipsetool": ShapeEllipse, "circle": ShapeEllipse, "oval": ShapeEllipse,
+	"highlighter": ShapeHighlighter, "highlightertool": ShapeHighlighter, "marker": ShapeHighlighter,
+	"pencil": ShapePath, "penciltool": ShapePath, "pen": ShapePath, "freehand": ShapePath, "brush": ShapePath,
+	"text": ShapeText, "texttool": ShapeText,
+}
+
+// Fields of whiteboard shape objects.
+var (
+	shapeKindFields   = []string{"shapeType", "tool"}
+	shapeIDFields     = []string{"shapeID", "shapeId", "id"}
+	shapePointLists   = []string{"points", "path", "pts"}
+	shapeStrokeFields = []string{"lineColor", "strokeColor", "color"}
+	shapeFillFields   = []string{"fillColor", "fill"}
+	shapeWidthFields  = []string{"lineThickness", "strokeWidth", "thickness", "lineWidth"}
+	shapeTextFields   = []string{"text", "htmlText", "label"}
+	shapeFontFields   = []string{"fontSize", "size"}
+	pageFields        = []string{"currentPage", "pageNumber", "slideIndex"}
+)
+
+// extractWhiteboards replays the shape events of every whiteboard pod in
+// rawDir into the final drawing of each page, with a snapshot each time the
+// pod moved away from a page, and does the same for the annotations on each
+// document page shown in a share pod. Pages that were never drawn on are
+// left out. It returns fs.ErrNotExist when nothing was drawn.
+func extractWhiteboards(rawDir string) ([]Whiteboard, error) {
+	var boards []*Whiteboard
+	var errs []error
+	for _, share := range []bool{false, true} {
+		patterns := whiteboardStreams
+		if share {
+			patterns = shareStreams
+		}
+		for _, file := range globStreams(rawDir, patterns) {
+			if err := replayDrawings(file, share, &boards); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	var out []Whiteboard
+	for _, b := range boards {
+		if len(b.Shapes) == 0 && len(b.Snapshots) == 0 {
+			continue
+		}
+		b.Time = formatMilliseconds(b.Offset)
+		out = append(out, *b)
+	}
+	slices.SortStableFunc(out, func(a, b Whiteboard) int {
+		return cmp.Or(cmp.Compare(a.Pod, b.Pod), cmp.Compare(a.Document, b.Document), cmp.Compare(a.Page, b.Page))
+	})
+	if len(out) == 0 {
+		if len(errs) > 0 {
+			return nil, errs[0]
+		}
+		return nil, fmt.Errorf("no whiteboard drawings or share pod annotations in the raw recording: %w", fs.ErrNotExist)
+	}
+	return out, nil
+}
+
+// replayDrawings replays the shape events of one pod stream, adding a board
+// to boards for each page drawn on. Shapes are only read from objects with a
+// shape or tool field. In a share pod the current page is that of the shown
+// document, read like extractSlides does, so annotations aren't mixed up with
+// the pod's own document and page objects.
+func replayDrawings(file string, share bool, boards *[]*Whiteboard) error {
+	type pageKey struct {
+		document string
+		page     int
+	}
+	pages := make(map[pageKey]*Whiteboard)
+	current := pageKey{page: 1}
+	page := func(pod string, key pageKey) *Whiteboard {
+		if pages[key] == nil {
+			pages[key] = &Whiteboard{Pod: pod, Document: key.document, Page: key.page}
+			*boards = append(*boards, pages[key])
+		}
+		return pages[key]
+	}
+	return readStream(file, func(m StreamMessage) bool {
+		method := strings.ToLower(m.Method)
+		switch {
+		case strings.Contains(method, "clear"):
+			b := page(m.Pod, current)
+			b.Shapes, b.Offset = nil, m.Time
+			return true
+		case strings.Contains(method, "remove") || strings.Contains(method, "delete") ||
+			strings.Contains(method, "erase"):
+			b := page(m.Pod, current)
+			for _, id := range removedShapes(m) {
+				b.Shapes = slices.DeleteFunc(b.Shapes, func(s WhiteboardShape) bool { return s.ID == id })
+			}
+			b.Offset = m.Time
+			return true
+		}
+		m.Walk(func(n *StreamNode) {
+			if s, ok := parseShape(n, m.Time); ok {
+				key := current
+				key.page = cmp.Or(int(parseInt(n.Field("page"))), current.page)
+				b := page(m.Pod, key)
+				if i := slices.IndexFunc(b.Shapes, func(o WhiteboardShape) bool {
+					return s.ID != "" && o.ID == s.ID
+				}); i >= 0 {
+					b.Shapes[i] = s
+				} else {
+					b.Shapes = append(b.Shapes, s)
+				}
+				b.Offset = m.Time
+				return
+			}
+			next := current
+			if share {
+				if name := firstField(n, shareDocumentFields); name != "" && isDocumentName(name) &&
+					name != next.document {
+					next = pageKey{document: name, page: 1}
+				}
+				if p := n.Field("slideIndex"); p != "" {
+					next.page = int(parseInt(p)) + 1
+				} else if p := firstField(n, pageFields); p != "" {
+					next.page = int(parseInt(p))
+				}
+			} else if p := int(parseInt(firstField(n, pageFields))); p > 0 {
+				next.page = p
+			}
+			if next.page <= 0 || next == current {
+				return
+			}
+			if b := pages[current]; b != nil && len(b.Shapes) > 0 {
+				b.Snapshots = append(b.Snapshots, WhiteboardSnapshot{
+					Offset: m.Time,
+					Time:   formatMilliseconds(m.Time),
+					Shapes: slices.Clone(b.Shapes),
+				})
+			}
+			current = next
+		})
+		return true
+	})
+}
+
+// removedShapes returns the IDs named by a remove message, either in ID
+// fields or as plain string arguments.
+func removedShapes(m StreamMessage) []string {
+	var ids []string
+	m.Walk(func(n *StreamNode) {
+		if id := firstField(n, shapeIDFields); id != "" {
+			ids = append(ids, id)
+		} else if len(n.Children) == 0 && n.Text != "" && !slices.Contains(shapeIDFields, n.Name) {
+			ids = append(ids, n.Text)
+		}
+	})
+	return ids
+}
+
+// parseShape reads a shape object, reporting false for nodes that aren't one.
+func parseShape(n *StreamNode, msgTime int64) (WhiteboardShape, bool) {
+	kind := shapeKind(firstField(n, shapeKindFields))
+	if kind == "" {
+		return WhiteboardShape{}, false
+	}
+	s := WhiteboardShape{
+		ID:          firstField(n, shapeIDFields),
+		Kind:        kind,
+		X:           parseFloat(cmp.Or(n.Field("x"), n.Field("left"))),
+		Y:           parseFloat(cmp.Or(n.Field("y"), n.Field("top"))),
+		Width:       parseFloat(cmp.Or(n.Field("width"), n.Field("w"))),
+		Height:      parseFloat(cmp.Or(n.Field("height"), n.Field("h"))),
+		Stroke:      cmp.Or(svgColor(firstField(n, shapeStrokeFields)), "#000000"),
+		Fill:        svgColor(firstField(n, shapeFillFields)),
+		StrokeWidth: cmp.Or(parseFloat(firstField(n, shapeWidthFields)), 2),
+		Offset:      msgTime,
+	}
+	if list := firstChild(n, shapePointLists); list != nil {
+		s.Points = parsePoints(list)
+	}
+	switch kind {
+	case ShapeLine, ShapeArrow:
+		switch {
+		case n.Field("x2") != "" || n.Field("endX") != "":
+			s.X2 = parseFloat(cmp.Or(n.Field("x2"), n.Field("endX")))
+			s.Y2 = parseFloat(cmp.Or(n.Field("y2"), n.Field("endY")))
+		case len(s.Points) >= 2:
+			s.X, s.Y = s.Points[0][0], s.Points[0][1]
+			s.X2, s.Y2 = s.Points[len(s.Points)-1][0], s.Points[len(s.Points)-1][1]
+		default:
+			// Width and height are the extent of the line and may be negative
+			s.X2, s.Y2 = s.X+s.Width, s.Y+s.Height
+		}
+		s.Width, s.Height, s.Points = 0, 0, nil
+	case ShapePath, ShapeHighlighter:
+		if len(s.Points) == 0 {
+			return WhiteboardShape{}, false
+		}
+	case ShapeText:
+		s.Text = htmlToText(firstField(n, shapeTextFields))
+		s.FontSize = cmp.Or(parseFloat(firstField(n, shapeFontFields)), 16)
+		if s.Text == "" {
+			return WhiteboardShape{}, false
+		}
+	case ShapeRect, ShapeEllipse:
+		// Normalize shapes dragged up or to the left
+		if s.Width < 0 {
+			s.X, s.Width = s.X+s.Width, -s.Width
+		}
+		if s.Height < 0 {
+			s.Y, s.Height = s.Y+s.Height, -s.Height
+		}
+	}
+	return s, true
+}
+
+// shapeKind normalizes Connect's shape and tool names, such as "Rectangle",
+// "pencil" or "highlighterTool", returning "" for anything else.
+func shapeKind(t string) string {
+	return shapeKinds[strings.ToLower(strings.TrimSpace(t))]
+}
+
+// parsePoints reads a point list: objects with x and y fields, or numbers as
+// text such as "10,20 30,40".
+func parsePoints(list *StreamNode) [][2]float64 {
+	var coords []float64
+	if len(list.Children) == 0 {
+		for _, f := range strings.FieldsFunc(list.Text, func(r rune) bool { return r == ',' || r == ' ' || r == ';' }) {
+			coords = append(coords, parseFloat(f))
+		}
+	}
+	for _, c := range list.Children {
+		if len(c.Children) > 0 {
+			coords = append(coords, parseFloat(c.Field("x")), parseFloat(c.Field("y")))
+		} else {
+			coords = append(coords, parseFloat(c.Text))
+		}
+	}
+	points := make([][2]float64, 0, len(coords)/2)
+	for i := 0; i+1 < len(coords); i += 2 {
+		points = append(points, [2]float64{coords[i], coords[i+1]})
+	}
+	return points
+}
+
+// svgColor converts a Flash color, a decimal RGB number such as "16711680",
+// or a hex color to a CSS hex color. It returns "" when s isn't a color.
+func svgColor(s string) string {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return ""
+	case strings.HasPrefix(s, "#"):
+		return s
+	case strings.HasPrefix(s, "0x"), strings.HasPrefix(s, "0X"):
+		if v, err := strconv.ParseUint(s[2:], 16, 32); err == nil {
+			return fmt.Sprintf("#%06x", v&0xffffff)
+		}
+	default:
+		if v, err := strconv.ParseUint(s, 10, 32); err == nil {
+			return fmt.Sprintf("#%06x", v&0xffffff)
+		}
+	}
+	return ""
+}
+
+// parseFloat parses s as a finite number, returning 0 for anything else,
+// NaN and infinities included, so they never reach an SVG.
+func parseFloat(s string) float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0
+	}
+	return f
+}
+
+// writeWhiteboards renders the final drawing on each whiteboard page and
+// annotated document page as an SVG in rootDir/whiteboards, plus one SVG per
+// page change when snapshots is set, and records the status of the
+// whiteboards asset.
+func writeWhiteboards(rootDir, rawDir string, snapshots bool, assets *assetTracker, logger Logger) {
+	start := time.Now()
+	boards, err := extractWhiteboards(rawDir)
+	if err != nil {
+		log(logger, "whiteboard extraction failed", "error", err)
+		assets.fail(AssetWhiteboard, time.Since(start), err)
+		return
+	}
+	dir := filepath.Join(rootDir, "whiteboards")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		assets.fail(AssetWhiteboard, time.Since(start), err)
+		return
+	}
+	files := 0
+	write := func(name string, b Whiteboard, offset int64, shapes []WhiteboardShape) error {
+		files++
+		return os.WriteFile(filepath.Join(dir, name), []byte(whiteboardSVG(b, offset, shapes)), 0o644)
+	}
+	for _, b := range boards {
+		base := fmt.Sprintf("%s-page%d", b.Pod, b.Page)
+		if b.Document != "" {
+			stem := strings.TrimSuffix(b.Document, path.Ext(b.Document))
+			base = fmt.Sprintf("%s-%s-page%d", b.Pod, sanitize(path.Base(stem)), b.Page)
+		}
+		if len(b.Shapes) > 0 {
+			err = write(base+".svg", b, b.Offset, b.Shapes)
+		}
+		for i, snap := range b.Snapshots {
+			if !snapshots || err != nil {
+				break
+			}
+			// Numbered, as the pod can leave a page more than once a second
+			name := fmt.Sprintf("%s-snapshot%d-%s.svg", base, i+1, strings.ReplaceAll(snap.Time, ":", "-"))
+			err = write(name, b, snap.Offset, snap.Shapes)
+		}
+		if err != nil {
+			log(logger, "whiteboard write failed", "error", err)
+			assets.fail(AssetWhiteboard, time.Since(start), err)
+			return
+		}
+	}
+	log(logger, "whiteboards created", "path", dir, "pages", len(boards), "files", files)
+	assets.ok(AssetWhiteboard, dir, time.Since(start))
+}
+
+// whiteboardSVG renders shapes as a standalone SVG sized to the drawing, with
+// the pod, document, page and offset in its title and the offset in its
+// bottom corner.
+func whiteboardSVG(b Whiteboard, offset int64, shapes []WhiteboardShape) string {
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	extend := func(x, y float64) {
+		minX, minY = min(minX, x), min(minY, y)
+		maxX, maxY = max(maxX, x), max(maxY, y)
+	}
+	for _, s := range shapes {
+		extend(s.X, s.Y)
+		switch s.Kind {
+		case ShapeLine, ShapeArrow:
+			extend(s.X2, s.Y2)
+		case ShapeText:
+			// Roughly the extent of the text, which SVG draws above its baseline
+			lines := strings.Split(s.Text, "\n")
+			longest := slices.MaxFunc(lines, func(a, b string) int { return cmp.Compare(len(a), len(b)) })
+			extend(s.X+float64(len(longest))*s.FontSize*0.6, s.Y+float64(len(lines))*s.FontSize*1.2)
+		default:
+			extend(s.X+s.Width, s.Y+s.Height)
+		}
+		for _, p := range s.Points {
+			extend(p[0], p[1])
+		}
+	}
+	const margin = 20
+	minX, minY = min(minX, 0)-margin, min(minY, 0)-margin
+	width, height := max(maxX+margin-minX, 200), max(maxY+2*margin-minY, 150)
+	stamp := formatMilliseconds(offset)
+
+	var w strings.Builder
+	box := fmt.Sprintf(`x="%s" y="%s" width="%s" height="%s"`, num(minX), num(minY), num(width), num(height))
+	fmt.Fprintf(&w, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="%s %s %s %s" width="%s" height="%s">
+<title>%s page %d at %s</title>
+<defs>
+<marker id="arrow" viewBox="0 0 10 10" refX="9" refY="5" markerWidth="6" markerHeight="6" orient="auto">
+<path d="M0,0 L10,5 L0,10 z" fill="context-stroke"/>
+</marker>
+</defs>
+<rect %s fill="#ffffff"/>
+`, num(minX), num(minY), num(width), num(height), num(width), num(height),
+		html.EscapeString(strings.TrimSpace(b.Pod+" "+b.Document)), b.Page, stamp, box)
+	for _, s := range shapes {
+		stroke := fmt.Sprintf(`stroke="%s" stroke-width="%s"`, html.EscapeString(s.Stroke), num(s.StrokeWidth))
+		fill := cmp.Or(html.EscapeString(s.Fill), "none")
+		switch s.Kind {
+		case ShapeLine, ShapeArrow:
+			marker := ""
+			if s.Kind == ShapeArrow {
+				marker = ` marker-end="url(#arrow)"`
+			}
+			fmt.Fprintf(&w, `<line x1="%s" y1="%s" x2="%s" y2="%s" %s%s/>`+"\n",
+				num(s.X), num(s.Y), num(s.X2), num(s.Y2), stroke, marker)
+		case ShapeRect:
+			fmt.Fprintf(&w, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s" %s/>`+"\n",
+				num(s.X), num(s.Y), num(s.Width), num(s.Height), fill, stroke)
+		case ShapeEllipse:
+			fmt.Fprintf(&w, `<ellipse cx="%s" cy="%s" rx="%s" ry="%s" fill="%s" %s/>`+"\n",
+				num(s.X+s.Width/2), num(s.Y+s.Height/2), num(s.Width/2), num(s.Height/2), fill, stroke)
+		case ShapePath, ShapeHighlighter:
+			points := make([]string, len(s.Points))
+			for i, p := range s.Points {
+				points[i] = num(p[0]) + "," + num(p[1])
+			}
+			opacity := ""
+			if s.Kind == ShapeHighlighter {
+				opacity = ` stroke-opacity="0.4"`
+			}
+			fmt.Fprintf(&w, `<polyline points="%s" fill="none" %s%s stroke-linecap="round"/>`+"\n",
+				strings.Join(points, " "), stroke, opacity)
+		case ShapeText:
+			for i, line := range strings.Split(s.Text, "\n") {
+				y := s.Y + float64(i+1)*s.FontSize*1.2
+				fmt.Fprintf(&w, `<text x="%s" y="%s" font-family="sans-serif" font-size="%s" fill="%s">%s</text>`+"\n",
+					num(s.X), num(y), num(s.FontSize), html.EscapeString(s.Stroke), html.EscapeString(line))
+			}
+		}
+	}
+	fmt.Fprintf(&w, `<text x="%s" y="%s" font-family="monospace" font-size="12" fill="#888888">%s</text>`+"\n",
+		num(minX+margin/2), num(minY+height-margin/2), stamp)
+	w.WriteString("</svg>\n")
+	return w.String()
+}
+
+// num formats a coordinate without trailing zeros.
+func num(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
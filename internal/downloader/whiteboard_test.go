@@ -0,0 +1,166 @@
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractWhiteboards(t *testing.T) {
+	rawDir := lectureStreams(t, "lecture9")
+
+	boards, err := extractWhiteboards(rawDir)
+	if err != nil {
+		t.Fatalf("extractWhiteboards error: %v", err)
+	}
+	rect := WhiteboardShape{
+		ID: "s1", Kind: ShapeRect, X: 10, Y: 60, Width: 40, Height: 20,
+		Stroke: "#ff0000", StrokeWidth: 3, Offset: 1000,
+	}
+	text := WhiteboardShape{
+		ID: "s3", Kind: ShapeText, X: 5, Y: 100, Stroke: "#000000", StrokeWidth: 2,
+		Text: "Q & A", FontSize: 16, Offset: 3000,
+	}
+	arrow := WhiteboardShape{
+		ID: "a1", Kind: ShapeArrow, X2: 90, Y2: 40, Stroke: "#000000", StrokeWidth: 2, Offset: 62000,
+	}
+	want := []Whiteboard{
+		{
+			Pod: "ftwhiteboard6", Page: 1, Offset: 130000, Time: "00:02:10",
+			Snapshots: []WhiteboardSnapshot{{Offset: 60000, Time: "00:01:00", Shapes: []WhiteboardShape{rect, text}}},
+		},
+		{
+			Pod: "ftwhiteboard6", Page: 2, Offset: 62000, Time: "00:01:02", Shapes: []WhiteboardShape{arrow},
+			Snapshots: []WhiteboardSnapshot{{Offset: 120000, Time: "00:02:00", Shapes: []WhiteboardShape{arrow}}},
+		},
+	}
+	if !reflect.DeepEqual(boards, want) {
+		t.Errorf("whiteboards =\n%+v\nwant\n%+v", boards, want)
+	}
+
+	rootDir := t.TempDir()
+	assets := &assetTracker{root: rootDir}
+	writeWhiteboards(rootDir, rawDir, true, assets, nil)
+	files, _ := filepath.Glob(filepath.Join(rootDir, "whiteboards", "*.svg"))
+	var names []string
+	for _, f := range files {
+		names = append(names, filepath.Base(f))
+	}
+	wantNames := []string{
+		"ftwhiteboard6-page1-snapshot1-00-01-00.svg", "ftwhiteboard6-page2-snapshot1-00-02-00.svg",
+		"ftwhiteboard6-page2.svg",
+	}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("files = %v, want %v", names, wantNames)
+	}
+	svg, _ := os.ReadFile(filepath.Join(rootDir, "whiteboards", "ftwhiteboard6-page1-snapshot1-00-01-00.svg"))
+	for _, s := range []string{
+		`<rect x="10" y="60" width="40" height="20" fill="none" stroke="#ff0000" stroke-width="3"/>`,
+		">Q &amp; A</text>", "ftwhiteboard6 page 1 at 00:01:00",
+	} {
+		if !strings.Contains(string(svg), s) {
+			t.Errorf("snapshot SVG missing %q:\n%s", s, svg)
+		}
+	}
+	if got := assets.list(); len(got) != 1 || got[0].State != StateOK || got[0].Path != "whiteboards" {
+		t.Errorf("assets = %+v", got)
+	}
+}
+
+func TestExtractShareAnnotations(t *testing.T) {
+	rawDir := lectureStreams(t, "lecture15")
+
+	boards, err := extractWhiteboards(rawDir)
+	if err != nil {
+		t.Fatalf("extractWhiteboards error: %v", err)
+	}
+	highlight := WhiteboardShape{
+		ID: "h1", Kind: ShapeHighlighter, Points: [][2]float64{{10, 20}, {90, 20}},
+		Stroke: "#ffff00", StrokeWidth: 12, Offset: 5000,
+	}
+	rect := WhiteboardShape{
+		ID: "r1", Kind: ShapeRect, X: 40, Y: 50, Width: 100, Height: 30,
+		Stroke: "#0000ff", StrokeWidth: 2, Offset: 32000,
+	}
+	text := WhiteboardShape{
+		ID: "t1", Kind: ShapeText, X: 20, Y: 30, Stroke: "#000000", StrokeWidth: 2,
+		Text: "Exam topic", FontSize: 16, Offset: 61000,
+	}
+	// The document's own position and size are not a shape
+	want := []Whiteboard{
+		{
+			Pod: "ftcontent3", Document: "Week 1 Slides.pdf", Page: 1, Offset: 5000, Time: "00:00:05",
+			Shapes:    []WhiteboardShape{highlight},
+			Snapshots: []WhiteboardSnapshot{{Offset: 30000, Time: "00:00:30", Shapes: []WhiteboardShape{highlight}}},
+		},
+		{
+			Pod: "ftcontent3", Document: "Week 1 Slides.pdf", Page: 2, Offset: 32000, Time: "00:00:32",
+			Shapes:    []WhiteboardShape{rect},
+			Snapshots: []WhiteboardSnapshot{{Offset: 60000, Time: "00:01:00", Shapes: []WhiteboardShape{rect}}},
+		},
+		{
+			Pod: "ftcontent3", Document: "Week 2.pptx", Page: 1, Offset: 61000, Time: "00:01:01",
+			Shapes: []WhiteboardShape{text},
+		},
+	}
+	if !reflect.DeepEqual(boards, want) {
+		t.Errorf("whiteboards =\n%+v\nwant\n%+v", boards, want)
+	}
+
+	rootDir := t.TempDir()
+	assets := &assetTracker{root: rootDir}
+	writeWhiteboards(rootDir, rawDir, false, assets, nil)
+	files, _ := filepath.Glob(filepath.Join(rootDir, "whiteboards", "*.svg"))
+	var names []string
+	for _, f := range files {
+		names = append(names, filepath.Base(f))
+	}
+	wantNames := []string{
+		"ftcontent3-Week 1 Slides-page1.svg", "ftcontent3-Week 1 Slides-page2.svg", "ftcontent3-Week 2-page1.svg",
+	}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("files = %v, want %v", names, wantNames)
+	}
+	svg, _ := os.ReadFile(filepath.Join(rootDir, "whiteboards", "ftcontent3-Week 2-page1.svg"))
+	if !strings.Contains(string(svg), "ftcontent3 Week 2.pptx page 1 at 00:01:01") {
+		t.Errorf("SVG title lacks the document:\n%s", svg)
+	}
+}
+
+func TestWriteWhiteboardsWithoutDrawings(t *testing.T) {
+	// A share pod that nothing was drawn on is not a whiteboard
+	assertWriterMissing(t, "lecture10", func(rootDir, rawDir string, assets *assetTracker) {
+		writeWhiteboards(rootDir, rawDir, true, assets, nil)
+	})
+}
+
+func TestShapeKind(t *testing.T) {
+	for in, want := range map[string]string{
+		"Rectangle": ShapeRect, "pencil": ShapePath, "highlighterTool": ShapeHighlighter, "arrow": ShapeArrow,
+		"outline": "", "timeline": "", "open": "", "text/plain": "", "document": "",
+	} {
+		if got := shapeKind(in); got != want {
+			t.Errorf("shapeKind(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseFloat(t *testing.T) {
+	for in, want := range map[string]float64{"12.5": 12.5, " -3 ": -3, "NaN": 0, "Inf": 0, "-Infinity": 0, "x": 0} {
+		if got := parseFloat(in); got != want {
+			t.Errorf("parseFloat(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestSVGColor(t *testing.T) {
+	for in, want := range map[string]string{
+		"16711680": "#ff0000", "0x00FF00": "#00ff00", "#123abc": "#123abc", "255": "#0000ff", "": "", "red": "",
+	} {
+		if got := svgColor(in); got != want {
+			t.Errorf("svgColor(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
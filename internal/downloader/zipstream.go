@@ -0,0 +1,324 @@
+package downloader
+
+import (
+	"archive/zip"
+	"bufio"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ZIP record signatures and local header flags used by streamExtract.
+const (
+	sigLocalFile      = 0x04034b50
+	sigDataDescriptor = 0x08074b50
+	sigCentralDir     = 0x02014b50
+	sigEndOfDir       = 0x06054b50
+	zip64ExtraID      = 0x0001
+	flagEncrypted     = 0x1
+	flagDataDesc      = 0x8
+)
+
+// errStreamStopped tells the download side that the stream extractor has
+// stopped reading; the remaining bytes only go to disk.
+var errStreamStopped = errors.New("stream extraction stopped")
+
+// streamExtract extracts ZIP entries from r as they arrive, front to back,
+// verifying each CRC and size. It returns the uncompressed size of every entry
+// written, keyed by name, and stops without error at the central directory or
+// at the first entry whose data can't be delimited without it (stored entries
+// with a data descriptor, encrypted or unsupported methods). Those are left to
+// completeStreamExtract once the whole archive is on disk.
+func streamExtract(r io.Reader, dest string, limits ExtractLimits) (map[string]int64, error) {
+	limits = limits.withDefaults()
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return nil, err
+	}
+	cr := &countingReader{r: bufio.NewReaderSize(r, 64<<10)}
+	written := make(map[string]int64)
+	var total int64
+
+	for {
+		var sig [4]byte
+		if _, err := io.ReadFull(cr, sig[:]); err != nil {
+			return written, fmt.Errorf("read entry header: %w", err)
+		}
+		switch binary.LittleEndian.Uint32(sig[:]) {
+		case sigLocalFile:
+		case sigCentralDir, sigEndOfDir:
+			return written, nil
+		default:
+			return written, fmt.Errorf("%w: unexpected record signature", ErrCorruptZip)
+		}
+
+		var hdr [26]byte
+		if _, err := io.ReadFull(cr, hdr[:]); err != nil {
+			return written, fmt.Errorf("read entry header: %w", err)
+		}
+		flags := binary.LittleEndian.Uint16(hdr[2:])
+		method := binary.LittleEndian.Uint16(hdr[4:])
+		wantCRC := binary.LittleEndian.Uint32(hdr[10:])
+		csize := uint64(binary.LittleEndian.Uint32(hdr[14:]))
+		usize := uint64(binary.LittleEndian.Uint32(hdr[18:]))
+		nameLen := binary.LittleEndian.Uint16(hdr[22:])
+		nameAndExtra := make([]byte, int(nameLen)+int(binary.LittleEndian.Uint16(hdr[24:])))
+		if _, err := io.ReadFull(cr, nameAndExtra); err != nil {
+			return written, fmt.Errorf("read entry header: %w", err)
+		}
+		name := string(nameAndExtra[:nameLen])
+		zip64 := parseZip64Extra(nameAndExtra[nameLen:], &usize, &csize)
+
+		descriptor := flags&flagDataDesc != 0
+		if flags&flagEncrypted != 0 || (method != zip.Store && method != zip.Deflate) ||
+			(method == zip.Store && descriptor) {
+			return written, nil
+		}
+		target, err := safeTarget(dest, name)
+		if err != nil {
+			return written, err
+		}
+		if !descriptor {
+			if err := limits.checkEntry(name, len(written), total, usize, csize); err != nil {
+				return written, err
+			}
+		} else if limits.MaxFiles > 0 && len(written) >= limits.MaxFiles {
+			return written, fmt.Errorf("%w: more than %d entries", ErrExtractLimit, limits.MaxFiles)
+		}
+
+		start := cr.n
+		data := io.NopCloser(io.LimitReader(cr, int64(csize)))
+		if method == zip.Deflate {
+			// flate reads byte by byte from an io.ByteReader, so it stops at the end of the entry
+			data = flate.NewReader(cr)
+		}
+		n, gotCRC, err := writeStreamEntry(name, target, data, limits, total)
+		data.Close()
+		if err != nil {
+			return written, err
+		}
+		compressed := uint64(cr.n - start)
+
+		if descriptor {
+			// Sizes in the descriptor are 8 bytes for Zip64 entries; writers that
+			// only learn an entry is large after writing it don't add the extra field
+			wide := zip64 || uint64(n) >= 0xFFFFFFFF || compressed >= 0xFFFFFFFF
+			if wantCRC, csize, usize, err = readDataDescriptor(cr, wide); err != nil {
+				os.Remove(target)
+				return written, err
+			}
+			if err := limits.checkEntry(name, len(written), total, usize, csize); err != nil {
+				os.Remove(target)
+				return written, err
+			}
+		}
+		if gotCRC != wantCRC || uint64(n) != usize || compressed != csize {
+			os.Remove(target)
+			return written, fmt.Errorf("%w: %s: checksum or size mismatch", ErrCorruptZip, name)
+		}
+		written[name] = n
+		total += n
+	}
+}
+
+// writeStreamEntry writes one entry (or creates a directory) from data,
+// returning its size and CRC-32. The total size limit is enforced while copying
+// because entries with a data descriptor declare no size up front.
+func writeStreamEntry(name, target string, data io.Reader, limits ExtractLimits, total int64) (int64, uint32, error) {
+	dir := strings.HasSuffix(name, "/")
+	var out io.Writer = io.Discard
+	if dir {
+		if err := os.MkdirAll(target, 0o755); err != nil {
+			return 0, 0, err
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return 0, 0, err
+		}
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+		if err != nil {
+			return 0, 0, err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if limits.MaxTotalSize > 0 {
+		data = io.LimitReader(data, limits.MaxTotalSize-total+1)
+	}
+	h := crc32.NewIEEE()
+	n, err := io.Copy(io.MultiWriter(out, h), data)
+	if err == nil && limits.MaxTotalSize > 0 && total+n > limits.MaxTotalSize {
+		err = fmt.Errorf("%w: more than %d bytes uncompressed", ErrExtractLimit, limits.MaxTotalSize)
+	}
+	if err != nil {
+		if !dir {
+			os.Remove(target)
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, new(flate.CorruptInputError)) {
+			err = fmt.Errorf("%w: %s: %w", ErrCorruptZip, name, err)
+		}
+		return 0, 0, err
+	}
+	return n, h.Sum32(), nil
+}
+
+// readDataDescriptor reads the CRC and sizes following an entry's data. The
+// descriptor signature is optional.
+func readDataDescriptor(r io.Reader, wide bool) (crc uint32, csize, usize uint64, err error) {
+	size := 12
+	if wide {
+		size = 20
+	}
+	buf := make([]byte, 4+size)
+	if _, err := io.ReadFull(r, buf[:4]); err != nil {
+		return 0, 0, 0, fmt.Errorf("read data descriptor: %w", err)
+	}
+	fields := buf[4:]
+	if binary.LittleEndian.Uint32(buf[:4]) == sigDataDescriptor {
+		if _, err := io.ReadFull(r, fields); err != nil {
+			return 0, 0, 0, fmt.Errorf("read data descriptor: %w", err)
+		}
+	} else {
+		// No signature: the four bytes already read are the CRC
+		fields = buf[:size]
+		if _, err := io.ReadFull(r, fields[4:]); err != nil {
+			return 0, 0, 0, fmt.Errorf("read data descriptor: %w", err)
+		}
+	}
+	crc = binary.LittleEndian.Uint32(fields)
+	if wide {
+		return crc, binary.LittleEndian.Uint64(fields[4:]), binary.LittleEndian.Uint64(fields[12:]), nil
+	}
+	return crc, uint64(binary.LittleEndian.Uint32(fields[4:])), uint64(binary.LittleEndian.Uint32(fields[8:])), nil
+}
+
+// parseZip64Extra replaces 0xFFFFFFFF sizes with the values from the Zip64
+// extra field and reports whether the field was present.
+func parseZip64Extra(extra []byte, usize, csize *uint64) bool {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		extra = extra[4:]
+		if size > len(extra) {
+			return false
+		}
+		if id == zip64ExtraID {
+			field := extra[:size]
+			for _, v := range []*uint64{usize, csize} {
+				if *v == 0xFFFFFFFF && len(field) >= 8 {
+					*v = binary.LittleEndian.Uint64(field)
+					field = field[8:]
+				}
+			}
+			return true
+		}
+		extra = extra[size:]
+	}
+	return false
+}
+
+// countingReader counts the bytes read through it. It implements
+// io.ByteReader so flate doesn't buffer past the end of an entry.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// zipStream feeds ZIP bytes, as they are downloaded, to streamExtract running in
+// the background. It is used as a download tee and never fails the download:
+// once the extractor stops, further bytes only go to disk.
+type zipStream struct {
+	pw      *io.PipeWriter
+	stopped bool
+	done    chan struct{}
+	entries map[string]int64
+	err     error
+}
+
+// startZipStream starts extracting into dest.
+func startZipStream(dest string, limits ExtractLimits) *zipStream {
+	pr, pw := io.Pipe()
+	s := &zipStream{pw: pw, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		s.entries, s.err = streamExtract(pr, dest, limits)
+		pr.CloseWithError(errStreamStopped)
+	}()
+	return s
+}
+
+// Write implements io.Writer.
+func (s *zipStream) Write(p []byte) (int, error) {
+	if !s.stopped {
+		if _, err := s.pw.Write(p); err != nil {
+			s.stopped = true
+		}
+	}
+	return len(p), nil
+}
+
+// finish ends the stream once the download returned downloadErr and waits for
+// the extractor, returning the entries it wrote.
+func (s *zipStream) finish(downloadErr error) (map[string]int64, error) {
+	s.pw.CloseWithError(downloadErr)
+	<-s.done
+	return s.entries, s.err
+}
+
+// completeStreamExtract finishes a streamed extraction from the downloaded
+// archive. The central directory is authoritative: streamed entries it marks
+// as symlinks or special files, or whose size disagrees, are removed, and
+// entries that weren't streamed are extracted now. The limits apply to the
+// whole archive, streamed entries included.
+func completeStreamExtract(zipPath, dest string, streamed map[string]int64, limits ExtractLimits) error {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	// The streamed entries count towards the limits too
+	if _, err := checkFiles(&r.Reader, nil, limits); err != nil {
+		return err
+	}
+
+	done := make(map[string]bool, len(streamed))
+	for _, f := range r.File {
+		size, ok := streamed[f.Name]
+		if !ok {
+			continue
+		}
+		mode := f.Mode()
+		if mode.IsDir() || (mode.IsRegular() && uint64(size) == f.UncompressedSize64) {
+			done[f.Name] = true
+		}
+	}
+	for name := range streamed {
+		if done[name] {
+			continue
+		}
+		if target, err := safeTarget(dest, name); err == nil && !strings.HasSuffix(name, "/") {
+			os.Remove(target)
+		}
+	}
+	return extractFiles(&r.Reader, dest, func(name string) bool { return !done[name] }, limits)
+}
@@ -0,0 +1,61 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultZipWait is how long Download waits for the server to generate the raw
+// recording ZIP when it answers with a "preparing your download" page.
+const DefaultZipWait = 5 * time.Minute
+
+// Poll interval bounds while the ZIP is being prepared. Variables so tests can shorten them.
+var (
+	zipPollInitial = 5 * time.Second
+	zipPollMax     = 30 * time.Second
+)
+
+// zipWait resolves the configured wait: zero means the default, negative disables waiting.
+func zipWait(configured time.Duration) time.Duration {
+	if configured == 0 {
+		return DefaultZipWait
+	}
+	return max(configured, 0)
+}
+
+// waitWhilePreparing calls fetch until it returns anything other than
+// ErrZipPreparing, backing off between attempts until maxWait has passed.
+// onWait is called before each pause with the time waited so far and the next delay.
+func waitWhilePreparing(
+	ctx context.Context,
+	maxWait time.Duration,
+	fetch func() error,
+	onWait func(waited, next time.Duration),
+) error {
+	start := time.Now()
+	delay := zipPollInitial
+	for {
+		err := fetch()
+		if !errors.Is(err, ErrZipPreparing) {
+			return err
+		}
+		waited := time.Since(start)
+		remaining := maxWait - waited
+		if remaining <= 0 {
+			if maxWait > 0 {
+				return fmt.Errorf("gave up after %s: %w", waited.Round(time.Second), err)
+			}
+			return err
+		}
+		next := min(delay, remaining)
+		onWait(waited, next)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(next):
+		}
+		delay = min(delay*2, zipPollMax)
+	}
+}
@@ -0,0 +1,106 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const preparingPageHTML = `<html><head><title>Adobe Connect</title></head>
+<body><p>Preparing your download. This may take a few minutes, please wait...</p></body></html>`
+
+// newPreparingServer serves the preparing page for the first `preparing` ZIP
+// requests (all of them when negative), then the real ZIP.
+func newPreparingServer(t *testing.T, preparing int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	zipContent := createZip(t, map[string]string{"a.txt": "hello"})
+	var zipHits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rec/":
+			fmt.Fprint(w, "<title>Preparing</title>")
+		case "/rec/output/rec.zip":
+			if n := int(zipHits.Add(1)); preparing < 0 || n <= preparing {
+				w.Header().Set("Content-Type", "text/html")
+				fmt.Fprint(w, preparingPageHTML)
+				return
+			}
+			w.Write(zipContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &zipHits
+}
+
+func shortZipPoll(t *testing.T) {
+	t.Helper()
+	initial, maxPoll := zipPollInitial, zipPollMax
+	zipPollInitial, zipPollMax = 5*time.Millisecond, 20*time.Millisecond
+	t.Cleanup(func() { zipPollInitial, zipPollMax = initial, maxPoll })
+}
+
+func TestWaitsForZipPreparation(t *testing.T) {
+	shortZipPoll(t)
+	server, hits := newPreparingServer(t, 2)
+
+	var mu sync.Mutex
+	var waiting int
+	res, err := New(server.Client()).Download(context.Background(), server.URL+"/rec/", Options{
+		OutputDir: t.TempDir(),
+		OnEvent: func(e Event) {
+			if e.Kind == EventWaiting && e.Asset == "zip" {
+				mu.Lock()
+				waiting++
+				mu.Unlock()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("download error: %v", err)
+	}
+	if res.ZipPath == "" || hits.Load() != 3 {
+		t.Fatalf("expected zip after 3 requests, got path %q after %d", res.ZipPath, hits.Load())
+	}
+	if waiting != 2 {
+		t.Errorf("expected 2 waiting events, got %d", waiting)
+	}
+}
+
+func TestZipPreparationGivesUp(t *testing.T) {
+	shortZipPoll(t)
+	server, hits := newPreparingServer(t, -1)
+
+	res, err := New(server.Client()).Download(context.Background(), server.URL+"/rec/", Options{
+		OutputDir: t.TempDir(),
+		ZipWait:   50 * time.Millisecond,
+	})
+	if err == nil || !strings.Contains(err.Error(), "no assets") {
+		t.Fatalf("expected no assets error, got %v", err)
+	}
+	if hits.Load() < 2 {
+		t.Errorf("expected the zip to be polled, got %d requests", hits.Load())
+	}
+	if zip, _ := res.Asset(AssetZip); zip.State != StateMissing || !strings.Contains(zip.Error, "preparing") {
+		t.Errorf("unexpected zip status: %+v", zip)
+	}
+}
+
+func TestZipWaitDisabled(t *testing.T) {
+	server, hits := newPreparingServer(t, -1)
+
+	_, _ = New(server.Client()).Download(context.Background(), server.URL+"/rec/", Options{
+		OutputDir: t.TempDir(),
+		ZipWait:   -1,
+	})
+	if hits.Load() != 1 {
+		t.Errorf("expected a single zip request, got %d", hits.Load())
+	}
+}
@@ -0,0 +1,208 @@
+package flv
+
+import (
+	"errors"
+)
+
+var (
+	// errShortConfig means a decoder configuration ended early.
+	errShortConfig = errors.New("truncated decoder configuration")
+	// errBadConfig means a decoder configuration holds values no encoder
+	// writes.
+	errBadConfig = errors.New("invalid decoder configuration")
+)
+
+// bitReader reads H.264 and AAC bit fields, most significant bit first.
+type bitReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (b *bitReader) bit() uint32 {
+	if b.pos >= len(b.data)*8 {
+		b.err = errShortConfig
+		return 0
+	}
+	v := b.data[b.pos/8] >> (7 - b.pos%8) & 1
+	b.pos++
+	return uint32(v)
+}
+
+func (b *bitReader) bits(n int) uint32 {
+	var v uint32
+	for range n {
+		v = v<<1 | b.bit()
+	}
+	return v
+}
+
+// ue reads an unsigned exponential Golomb code. Codes longer than 32 bits
+// don't fit and are an error.
+func (b *bitReader) ue() uint32 {
+	zeros := 0
+	for b.bit() == 0 && b.err == nil {
+		if zeros++; zeros > 31 {
+			b.err = errBadConfig
+			return 0
+		}
+	}
+	return 1<<zeros - 1 + b.bits(zeros)
+}
+
+// se reads a signed exponential Golomb code.
+func (b *bitReader) se() int32 {
+	v := b.ue()
+	if v&1 == 1 {
+		return int32(v+1) / 2
+	}
+	return -int32(v / 2)
+}
+
+// avcConfig is the AVCDecoderConfigurationRecord of an H.264 stream, which
+// FLV and MP4 (as the avcC box) store the same way.
+type avcConfig struct {
+	record        []byte
+	width, height int
+}
+
+// parseAVCConfig reads the picture size from the first SPS of an
+// AVCDecoderConfigurationRecord.
+func parseAVCConfig(record []byte) (avcConfig, error) {
+	cfg := avcConfig{record: append([]byte(nil), record...)}
+	if len(record) < 8 || record[5]&0x1f == 0 {
+		return cfg, errShortConfig
+	}
+	n := int(record[6])<<8 | int(record[7])
+	if len(record) < 8+n || n < 4 {
+		return cfg, errShortConfig
+	}
+	var err error
+	cfg.width, cfg.height, err = spsSize(record[8 : 8+n])
+	return cfg, err
+}
+
+// spsSize reads the cropped picture size from an H.264 sequence parameter set.
+func spsSize(nal []byte) (width, height int, err error) {
+	// Emulation prevention bytes (00 00 03) aren't part of the bit stream
+	rbsp := make([]byte, 0, len(nal))
+	for i := 1; i < len(nal); i++ {
+		if i >= 3 && nal[i] == 3 && nal[i-1] == 0 && nal[i-2] == 0 {
+			continue
+		}
+		rbsp = append(rbsp, nal[i])
+	}
+	b := &bitReader{data: rbsp}
+	profile := b.bits(8)
+	b.bits(16) // Constraint flags and level
+	b.ue()     // seq_parameter_set_id
+	chroma := uint32(1)
+	switch profile {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		if chroma = b.ue(); chroma == 3 {
+			b.bit() // separate_colour_plane_flag
+		}
+		b.ue()  // bit_depth_luma_minus8
+		b.ue()  // bit_depth_chroma_minus8
+		b.bit() // qpprime_y_zero_transform_bypass_flag
+		if b.bit() == 1 {
+			lists := 8
+			if chroma == 3 {
+				lists = 12
+			}
+			for i := range lists {
+				if b.bit() == 0 {
+					continue
+				}
+				size := 16
+				if i >= 6 {
+					size = 64
+				}
+				last, next := int32(8), int32(8)
+				for range size {
+					if next != 0 {
+						next = (last + b.se() + 256) % 256
+					}
+					if next != 0 {
+						last = next
+					}
+				}
+			}
+		}
+	}
+	b.ue() // log2_max_frame_num_minus4
+	switch b.ue() {
+	case 0:
+		b.ue() // log2_max_pic_order_cnt_lsb_minus4
+	case 1:
+		b.bit() // delta_pic_order_always_zero_flag
+		b.se()  // offset_for_non_ref_pic
+		b.se()  // offset_for_top_to_bottom_field
+		// num_ref_frames_in_pic_order_cnt_cycle is at most 255
+		cycle := b.ue()
+		if cycle > 255 {
+			return 0, 0, errBadConfig
+		}
+		for range cycle {
+			if b.se(); b.err != nil {
+				break
+			}
+		}
+	}
+	b.ue()  // max_num_ref_frames
+	b.bit() // gaps_in_frame_num_value_allowed_flag
+	mbWidth := int(b.ue()) + 1
+	mapHeight := int(b.ue()) + 1
+	frameMBsOnly := int(b.bit())
+	if frameMBsOnly == 0 {
+		b.bit() // mb_adaptive_frame_field_flag
+	}
+	b.bit() // direct_8x8_inference_flag
+	var left, right, top, bottom int
+	if b.bit() == 1 {
+		left, right, top, bottom = int(b.ue()), int(b.ue()), int(b.ue()), int(b.ue())
+	}
+	if b.err != nil {
+		return 0, 0, b.err
+	}
+	cropX, cropY := 1, 2-frameMBsOnly
+	switch chroma {
+	case 1:
+		cropX, cropY = 2, 2*(2-frameMBsOnly)
+	case 2:
+		cropX = 2
+	}
+	width = mbWidth*16 - cropX*(left+right)
+	height = (2-frameMBsOnly)*mapHeight*16 - cropY*(top+bottom)
+	return width, height, nil
+}
+
+// aacConfig is the AudioSpecificConfig of an AAC stream.
+type aacConfig struct {
+	record   []byte
+	rate     int
+	channels int
+}
+
+// aacRates are the sampling frequencies AudioSpecificConfig refers to by index.
+var aacRates = []int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+// parseAACConfig reads the sample rate and channel count from an
+// AudioSpecificConfig.
+func parseAACConfig(record []byte) (aacConfig, error) {
+	cfg := aacConfig{record: append([]byte(nil), record...)}
+	b := &bitReader{data: record}
+	if b.bits(5) == 31 {
+		b.bits(6) // Extended object type
+	}
+	if index := int(b.bits(4)); index == 15 {
+		cfg.rate = int(b.bits(24))
+	} else if index < len(aacRates) {
+		cfg.rate = aacRates[index]
+	}
+	cfg.channels = int(b.bits(4))
+	if b.err != nil || cfg.rate == 0 {
+		return cfg, errShortConfig
+	}
+	return cfg, nil
+}
@@ -0,0 +1,209 @@
+// Package flv reads the FLV media streams of Adobe Connect raw recordings and
+// remuxes their H.264, AAC and MP3 data into MP4 files without re-encoding.
+package flv
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Tag types.
+const (
+	TagAudio  = 8
+	TagVideo  = 9
+	TagScript = 18
+)
+
+// Audio formats from the first byte of an audio tag.
+const (
+	SoundMP3        = 2
+	SoundNellymoser = 6
+	SoundAAC        = 10
+	SoundSpeex      = 11
+	SoundMP38k      = 14
+)
+
+// Video codecs from the first byte of a video tag.
+const (
+	CodecH263        = 2
+	CodecScreenVideo = 3
+	CodecVP6         = 4
+	CodecVP6Alpha    = 5
+	CodecScreenV2    = 6
+	CodecAVC         = 7
+)
+
+// ErrNotFLV means a file doesn't start with an FLV header.
+var ErrNotFLV = errors.New("not an FLV file")
+
+// Tag is one audio, video or script data tag.
+type Tag struct {
+	Type      uint8
+	Timestamp int64  // Milliseconds from the start of the stream
+	Offset    int64  // Position of Data in the file
+	Data      []byte // Only valid until the next call to Next
+}
+
+// Reader reads the tags of an FLV file in order.
+type Reader struct {
+	r      *bufio.Reader
+	pos    int64
+	buf    []byte
+	header [11]byte
+}
+
+// NewReader reads the FLV header from r and returns a Reader positioned at
+// the first tag.
+func NewReader(r io.Reader) (*Reader, error) {
+	var h [9]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotFLV, err)
+	}
+	if string(h[:3]) != "FLV" {
+		return nil, ErrNotFLV
+	}
+	fr := &Reader{r: bufio.NewReaderSize(r, 64<<10), pos: 9}
+	// The header may be followed by data older readers don't know about
+	if skip := int64(binary.BigEndian.Uint32(h[5:])) - 9; skip > 0 {
+		if _, err := fr.r.Discard(int(skip)); err != nil {
+			return nil, err
+		}
+		fr.pos += skip
+	}
+	return fr, nil
+}
+
+// Next returns the next tag, or io.EOF after the last one. A tag cut short by
+// the end of the file also ends the stream, since Connect leaves the last tag
+// of an interrupted recording incomplete.
+func (fr *Reader) Next() (Tag, error) {
+	// Each tag follows the size of the previous one
+	if _, err := fr.r.Discard(4); err != nil {
+		return Tag{}, io.EOF
+	}
+	fr.pos += 4
+	if _, err := io.ReadFull(fr.r, fr.header[:]); err != nil {
+		return Tag{}, io.EOF
+	}
+	h := fr.header
+	size := int(h[1])<<16 | int(h[2])<<8 | int(h[3])
+	ts := int64(h[7])<<24 | int64(h[4])<<16 | int64(h[5])<<8 | int64(h[6])
+	if cap(fr.buf) < size {
+		fr.buf = make([]byte, size)
+	}
+	data := fr.buf[:size]
+	if _, err := io.ReadFull(fr.r, data); err != nil {
+		return Tag{}, io.EOF
+	}
+	tag := Tag{Type: h[0] & 0x1f, Timestamp: ts, Offset: fr.pos + 11, Data: data}
+	fr.pos += 11 + int64(size)
+	return tag, nil
+}
+
+// AudioFormat returns the sound format of an audio tag, one of the Sound*
+// constants.
+func (t Tag) AudioFormat() int {
+	if t.Type != TagAudio || len(t.Data) == 0 {
+		return -1
+	}
+	return int(t.Data[0] >> 4)
+}
+
+// VideoCodec returns the codec of a video tag, one of the Codec* constants.
+func (t Tag) VideoCodec() int {
+	if t.Type != TagVideo || len(t.Data) == 0 {
+		return -1
+	}
+	return int(t.Data[0] & 0x0f)
+}
+
+// Keyframe reports whether a video tag starts a keyframe.
+func (t Tag) Keyframe() bool {
+	return t.Type == TagVideo && len(t.Data) > 0 && t.Data[0]>>4 == 1
+}
+
+// IsSequenceHeader reports whether an AVC or AAC tag carries the decoder
+// configuration rather than media.
+func (t Tag) IsSequenceHeader() bool {
+	switch {
+	case t.VideoCodec() == CodecAVC:
+		return len(t.Data) > 1 && t.Data[1] == 0
+	case t.AudioFormat() == SoundAAC:
+		return len(t.Data) > 1 && t.Data[1] == 0
+	}
+	return false
+}
+
+// Payload returns where the codec data of a media tag starts within Data and
+// its composition time offset in milliseconds (AVC only).
+func (t Tag) Payload() (start int, cts int64) {
+	switch {
+	case t.VideoCodec() == CodecAVC:
+		if len(t.Data) < 5 {
+			return len(t.Data), 0
+		}
+		// Composition time is a signed 24 bit integer
+		cts = int64(int32(uint32(t.Data[2])<<24|uint32(t.Data[3])<<16|uint32(t.Data[4])<<8) >> 8)
+		return 5, cts
+	case t.AudioFormat() == SoundAAC:
+		return min(2, len(t.Data)), 0
+	}
+	return min(1, len(t.Data)), 0
+}
+
+// audioRate returns the sample rate of an audio tag from its header bits,
+// which is all FLV records for formats other than AAC.
+func (t Tag) audioRate() int {
+	if t.AudioFormat() == SoundMP38k {
+		return 8000
+	}
+	return [4]int{5512, 11025, 22050, 44100}[t.Data[0]>>2&3]
+}
+
+// audioChannels returns 1 for mono and 2 for stereo audio tags.
+func (t Tag) audioChannels() int {
+	return int(t.Data[0]&1) + 1
+}
+
+// CodecName returns a short name for an audio format or video codec.
+func CodecName(tagType uint8, codec int) string {
+	if tagType == TagAudio {
+		switch codec {
+		case 0, 3:
+			return "pcm"
+		case 1:
+			return "adpcm"
+		case SoundMP3, SoundMP38k:
+			return "mp3"
+		case 4, 5, SoundNellymoser:
+			return "nellymoser"
+		case 7:
+			return "g711a"
+		case 8:
+			return "g711u"
+		case SoundAAC:
+			return "aac"
+		case SoundSpeex:
+			return "speex"
+		}
+		return fmt.Sprintf("audio-%d", codec)
+	}
+	switch codec {
+	case CodecH263:
+		return "h263"
+	case CodecScreenVideo:
+		return "screen"
+	case CodecVP6:
+		return "vp6"
+	case CodecVP6Alpha:
+		return "vp6a"
+	case CodecScreenV2:
+		return "screen2"
+	case CodecAVC:
+		return "h264"
+	}
+	return fmt.Sprintf("video-%d", codec)
+}
@@ -0,0 +1,387 @@
+package flv
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// bitWriter builds test SPS bit streams.
+type bitWriter struct {
+	data []byte
+	n    int
+}
+
+func (w *bitWriter) bits(v uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		w.data[len(w.data)-1] |= byte(v>>i&1) << (7 - w.n%8)
+		w.n++
+	}
+}
+
+func (w *bitWriter) ue(v uint32) {
+	n := 0
+	for x := v + 1; x > 1; x >>= 1 {
+		n++
+	}
+	w.bits(0, n)
+	w.bits(v+1, n+1)
+}
+
+// testSPS returns a baseline profile SPS for a width by height picture,
+// cropping the bottom rows when height isn't a multiple of 16.
+func testSPS(width, height int) []byte {
+	w := &bitWriter{}
+	w.bits(0x67, 8) // NAL header
+	w.bits(66, 8)   // Baseline
+	w.bits(0, 8)
+	w.bits(30, 8) // Level 3.0
+	w.ue(0)       // seq_parameter_set_id
+	w.ue(0)       // log2_max_frame_num_minus4
+	w.ue(2)       // pic_order_cnt_type
+	w.ue(1)       // max_num_ref_frames
+	w.bits(0, 1)
+	w.ue(uint32(width/16 - 1))
+	mbHeight := (height + 15) / 16
+	w.ue(uint32(mbHeight - 1))
+	w.bits(1, 1) // frame_mbs_only_flag
+	w.bits(1, 1) // direct_8x8_inference_flag
+	if crop := mbHeight*16 - height; crop > 0 {
+		w.bits(1, 1)
+		w.ue(0)
+		w.ue(0)
+		w.ue(0)
+		w.ue(uint32(crop / 2))
+	} else {
+		w.bits(0, 1)
+	}
+	w.bits(0, 1) // vui_parameters_present_flag
+	w.bits(1, 1) // Stop bit
+	return w.data
+}
+
+func avcRecord(sps []byte) []byte {
+	b := []byte{1, sps[1], sps[2], sps[3], 0xff, 0xe1, byte(len(sps) >> 8), byte(len(sps))}
+	b = append(b, sps...)
+	return append(b, 1, 0, 4, 0x68, 0xce, 0x3c, 0x80)
+}
+
+type testTag struct {
+	typ  uint8
+	ts   int64
+	data []byte
+}
+
+func writeFLV(t *testing.T, name string, tags []testTag) string {
+	t.Helper()
+	b := []byte{'F', 'L', 'V', 1, 5, 0, 0, 0, 9}
+	prev := 0
+	for _, tag := range tags {
+		b = binary.BigEndian.AppendUint32(b, uint32(prev))
+		n := len(tag.data)
+		ts := uint32(tag.ts)
+		b = append(b, tag.typ, byte(n>>16), byte(n>>8), byte(n), byte(ts>>16), byte(ts>>8), byte(ts), byte(ts>>24))
+		b = append(b, 0, 0, 0)
+		b = append(b, tag.data...)
+		prev = 11 + n
+	}
+	b = binary.BigEndian.AppendUint32(b, uint32(prev))
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// screenShare has a 1280x720 H.264 frame every 100 ms for one second, with
+// a keyframe every five frames.
+func screenShare(t *testing.T) string {
+	tags := []testTag{{TagVideo, 0, append([]byte{0x17, 0, 0, 0, 0}, avcRecord(testSPS(1280, 720))...)}}
+	for i := range 10 {
+		frame := byte(0x27)
+		if i%5 == 0 {
+			frame = 0x17
+		}
+		tags = append(tags, testTag{TagVideo, int64(i * 100), []byte{frame, 1, 0, 0, 0, 0, 0, 0, 2, 0x65, byte(i)}})
+	}
+	return writeFLV(t, "screenshare_2_10.flv", tags)
+}
+
+// cameraVoip has mono 44.1 kHz AAC every 23 ms for half a second plus
+// Nellymoser audio that can't be remuxed.
+func cameraVoip(t *testing.T) string {
+	tags := []testTag{{TagAudio, 0, []byte{0xae, 0, 0x12, 0x08}}}
+	for i := range 20 {
+		tags = append(tags, testTag{TagAudio, int64(i * 23), []byte{0xae, 1, 0x21, byte(i)}})
+	}
+	tags = append(tags, testTag{TagAudio, 500, []byte{0x62, 1, 2, 3}})
+	return writeFLV(t, "cameraVoip_1_3.flv", tags)
+}
+
+func TestReader(t *testing.T) {
+	f, err := os.Open(screenShare(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fr, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("NewReader error: %v", err)
+	}
+	tag, err := fr.Next()
+	if err != nil {
+		t.Fatalf("Next error: %v", err)
+	}
+	if tag.VideoCodec() != CodecAVC || !tag.IsSequenceHeader() || !tag.Keyframe() || tag.Offset != 24 {
+		t.Errorf("first tag = %+v, want AVC sequence header at 24", tag)
+	}
+	cfg, err := parseAVCConfig(tag.Data[5:])
+	if err != nil || cfg.width != 1280 || cfg.height != 720 {
+		t.Errorf("parseAVCConfig = %dx%d, %v, want 1280x720", cfg.width, cfg.height, err)
+	}
+	tags := 1
+	for {
+		if _, err := fr.Next(); err != nil {
+			break
+		}
+		tags++
+	}
+	if tags != 11 {
+		t.Errorf("read %d tags, want 11", tags)
+	}
+
+	if _, err := NewReader(strings.NewReader("<xml/>")); !errors.Is(err, ErrNotFLV) {
+		t.Errorf("NewReader error = %v, want ErrNotFLV", err)
+	}
+}
+
+func TestSPSSize(t *testing.T) {
+	for _, size := range [][2]int{{320, 240}, {1920, 1080}, {640, 360}} {
+		w, h, err := spsSize(testSPS(size[0], size[1]))
+		if err != nil || w != size[0] || h != size[1] {
+			t.Errorf("spsSize = %dx%d, %v, want %dx%d", w, h, err, size[0], size[1])
+		}
+	}
+
+	// A pic_order_cnt_type 1 cycle longer than H.264 allows
+	w := &bitWriter{}
+	w.bits(0x67, 8)
+	w.bits(66, 8)
+	w.bits(0, 8)
+	w.bits(30, 8)
+	w.ue(0)
+	w.ue(0)
+	w.ue(1) // pic_order_cnt_type
+	w.bits(0, 1)
+	w.ue(0)
+	w.ue(0)
+	w.ue(1 << 30) // num_ref_frames_in_pic_order_cnt_cycle
+	if _, _, err := spsSize(w.data); !errors.Is(err, errBadConfig) {
+		t.Errorf("spsSize of a long cycle error = %v, want errBadConfig", err)
+	}
+	// An exponential Golomb code with more than 31 leading zeros
+	long := append([]byte{0x67, 66, 0, 30}, make([]byte, 12)...)
+	if _, _, err := spsSize(append(long, 0xff)); !errors.Is(err, errBadConfig) {
+		t.Errorf("spsSize of a long code error = %v, want errBadConfig", err)
+	}
+}
+
+// boxes indexes the boxes of an MP4 by path, e.g. "moov/trak/tkhd",
+// descending into containers.
+func boxes(data []byte, prefix string, index map[string][][]byte) {
+	for len(data) >= 8 {
+		size := int(binary.BigEndian.Uint32(data))
+		header := 8
+		if size == 1 {
+			size, header = int(binary.BigEndian.Uint64(data[8:])), 16
+		}
+		if size < header || size > len(data) {
+			return
+		}
+		name := prefix + string(data[4:8])
+		index[name] = append(index[name], data[header:size])
+		switch string(data[4:8]) {
+		case "moov", "trak", "mdia", "minf", "stbl", "edts":
+			boxes(data[header:size], name+"/", index)
+		}
+		data = data[size:]
+	}
+}
+
+func TestRemux(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "recording.mp4")
+	video, audio := []Stream{{Path: screenShare(t), Offset: 2000}}, []Stream{{Path: cameraVoip(t), Offset: 500}}
+	summary, err := Remux(context.Background(), dst, video, audio)
+	if err != nil {
+		t.Fatalf("Remux error: %v", err)
+	}
+	want := Summary{
+		Duration: 3000, Width: 1280, Height: 720, VideoFrames: 10, AudioFrames: 20, Skipped: []string{"nellymoser"},
+	}
+	if summary.Duration != want.Duration || summary.Width != want.Width || summary.Height != want.Height ||
+		summary.VideoFrames != want.VideoFrames || summary.AudioFrames != want.AudioFrames ||
+		strings.Join(summary.Skipped, ",") != "nellymoser" {
+		t.Errorf("summary = %+v, want %+v", summary, want)
+	}
+
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := make(map[string][][]byte)
+	boxes(data, "", index)
+	if len(index["ftyp"]) != 1 || len(index["moov"]) != 1 || len(index["moov/trak"]) != 2 {
+		t.Fatalf("top level boxes = %d ftyp, %d moov, %d trak",
+			len(index["ftyp"]), len(index["moov"]), len(index["moov/trak"]))
+	}
+	// Two video and two audio payload bytes per frame
+	if mdat := index["mdat"]; len(mdat) != 1 || len(mdat[0]) != 10*6+20*2 {
+		t.Errorf("mdat holds %d bytes, want %d", len(mdat[0]), 10*6+20*2)
+	}
+
+	// The movie starts at recording time 0; empty edits delay the video by
+	// 2 seconds and the audio by half a second
+	elst := index["moov/trak/edts/elst"]
+	if len(elst) != 2 || binary.BigEndian.Uint32(elst[0][4:]) != 2 || binary.BigEndian.Uint32(elst[0][8:]) != 2000 ||
+		binary.BigEndian.Uint32(elst[1][4:]) != 2 || binary.BigEndian.Uint32(elst[1][8:]) != 500 {
+		t.Errorf("edit lists = %x", elst)
+	}
+	stss := index["moov/trak/mdia/minf/stbl/stss"]
+	if len(stss) != 1 || !bytes.Equal(stss[0][4:], []byte{0, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0, 6}) {
+		t.Errorf("sync samples = %x, want samples 1 and 6", stss)
+	}
+	stts := index["moov/trak/mdia/minf/stbl/stts"]
+	if len(stts) != 2 || !bytes.Equal(stts[0][4:], []byte{0, 0, 0, 1, 0, 0, 0, 10, 0, 0, 0, 100}) {
+		t.Errorf("video sample times = %x, want 10 samples of 100 ms", stts)
+	}
+	mdhd := index["moov/trak/mdia/mdhd"]
+	if len(mdhd) != 2 || binary.BigEndian.Uint32(mdhd[1][12:]) != 44100 {
+		t.Errorf("audio timescale = %x, want 44100", mdhd)
+	}
+	if !bytes.Contains(index["moov/trak/mdia/minf/stbl/stsd"][1], []byte{5, 0x80, 0x80, 0x80, 2, 0x12, 0x08}) {
+		t.Error("audio sample entry lacks the AudioSpecificConfig")
+	}
+}
+
+func TestRemuxOverlap(t *testing.T) {
+	// The second share starts before the first ends, so its frames are only
+	// used from its first keyframe after the first share's last frame
+	first, second := screenShare(t), screenShare(t)
+	dst := filepath.Join(t.TempDir(), "recording.mp4")
+	video := []Stream{{Path: second, Offset: 600}, {Path: first, Offset: 0}}
+	summary, err := Remux(context.Background(), dst, video, nil)
+	if err != nil {
+		t.Fatalf("Remux error: %v", err)
+	}
+	if summary.VideoFrames != 15 || summary.Duration != 1600 {
+		t.Errorf("summary = %+v, want 15 frames over 1600 ms", summary)
+	}
+	if len(summary.Dropped) != 1 || summary.Dropped[second] != 5 {
+		t.Errorf("dropped = %v, want 5 frames of %s", summary.Dropped, second)
+	}
+}
+
+func TestRemuxCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dst := filepath.Join(t.TempDir(), "recording.mp4")
+	_, err := Remux(ctx, dst, []Stream{{Path: screenShare(t)}}, []Stream{{Path: cameraVoip(t)}})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Remux error = %v, want context.Canceled", err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("Remux left %s behind: %v", dst, err)
+	}
+}
+
+func TestRemuxNoMedia(t *testing.T) {
+	empty := writeFLV(t, "cameraVoip_1_3.flv", nil)
+	dst := filepath.Join(t.TempDir(), "recording.mp4")
+	if _, err := Remux(context.Background(), dst, nil, []Stream{{Path: empty}}); !errors.Is(err, ErrNoMedia) {
+		t.Errorf("Remux error = %v, want ErrNoMedia", err)
+	}
+}
+
+func TestRemuxUnsupportedCodec(t *testing.T) {
+	speech := writeFLV(t, "cameraVoip_1_3.flv", []testTag{{TagAudio, 0, []byte{0x62, 1, 2, 3}}})
+	dst := filepath.Join(t.TempDir(), "recording.mp4")
+	_, err := Remux(context.Background(), dst, nil, []Stream{{Path: speech}})
+	if !errors.Is(err, ErrUnsupportedCodec) || errors.Is(err, ErrNoMedia) || !strings.Contains(err.Error(), "nellymoser") {
+		t.Errorf("Remux error = %v, want ErrUnsupportedCodec naming nellymoser", err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("Remux left %s behind: %v", dst, err)
+	}
+}
+
+// amfName encodes an AMF0 string without its type marker.
+func amfName(s string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(s))), s...)
+}
+
+func amfNumberValue(v float64) []byte {
+	return binary.BigEndian.AppendUint64([]byte{amfNumber}, math.Float64bits(v))
+}
+
+func TestProbe(t *testing.T) {
+	info, err := Probe(screenShare(t))
+	if err != nil {
+		t.Fatalf("Probe error: %v", err)
+	}
+	// Ten frames of 11 bytes over 900 ms
+	want := Info{VideoCodec: "h264", Width: 1280, Height: 720, Duration: 900, VideoFrames: 10, Size: 317, Bitrate: 977}
+	if info != want {
+		t.Errorf("Probe = %+v, want %+v", info, want)
+	}
+
+	info, err = Probe(cameraVoip(t))
+	if err != nil {
+		t.Fatalf("Probe error: %v", err)
+	}
+	if info.AudioCodec != "aac" || info.SampleRate != 44100 || info.Channels != 1 || info.AudioFrames != 21 ||
+		info.Duration != 500 || info.VideoCodec != "" {
+		t.Errorf("Probe = %+v, want 21 AAC frames at 44.1 kHz mono over 500 ms", info)
+	}
+
+	// Screen Video stores the picture size in each frame; onMetaData is
+	// only used for codecs that don't
+	meta := append([]byte{amfString}, amfName("onMetaData")...)
+	meta = append(meta, amfECMAArray, 0, 0, 0, 2)
+	meta = append(append(meta, amfName("width")...), amfNumberValue(800)...)
+	meta = append(append(meta, amfName("framerate")...), amfNumberValue(5)...)
+	meta = append(meta, 0, 0, amfObjectEnd)
+	screen := writeFLV(t, "screenshare_1_4.flv", []testTag{
+		{TagScript, 0, meta},
+		{TagVideo, 40, []byte{0x13, 0x30, 0x40, 0x30, 0x30, 0xaa}},
+		{TagVideo, 240, []byte{0x23, 0x30, 0x40, 0x30, 0x30, 0xbb}},
+	})
+	info, err = Probe(screen)
+	if err != nil {
+		t.Fatalf("Probe error: %v", err)
+	}
+	if info.VideoCodec != "screen" || info.Width != 64 || info.Height != 48 ||
+		info.Start != 40 || info.Duration != 200 {
+		t.Errorf("Probe = %+v, want 64x48 Screen Video from 40 ms for 200 ms", info)
+	}
+	if got := scriptData(meta); got["width"] != 800 || got["framerate"] != 5 || len(got) != 2 {
+		t.Errorf("scriptData = %v, want width and framerate", got)
+	}
+
+	// Deeply nested arrays stop decoding instead of exhausting the stack
+	nested := append([]byte{amfString}, amfName("onMetaData")...)
+	for range 1 << 20 {
+		nested = append(nested, amfStrictArray, 0, 0, 0, 1)
+	}
+	if got := scriptData(nested); len(got) != 0 {
+		t.Errorf("scriptData of nested arrays = %v, want nothing", got)
+	}
+}
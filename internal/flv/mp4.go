@@ -0,0 +1,195 @@
+package flv
+
+import (
+	"encoding/binary"
+)
+
+// Big-endian field helpers for building MP4 boxes.
+func u8(b []byte, v uint8) []byte   { return append(b, v) }
+func u16(b []byte, v uint16) []byte { return binary.BigEndian.AppendUint16(b, v) }
+func u32(b []byte, v uint32) []byte { return binary.BigEndian.AppendUint32(b, v) }
+func u64(b []byte, v uint64) []byte { return binary.BigEndian.AppendUint64(b, v) }
+
+// box returns an MP4 box of type typ holding the concatenated parts.
+func box(typ string, parts ...[]byte) []byte {
+	n := 8
+	for _, p := range parts {
+		n += len(p)
+	}
+	b := make([]byte, 0, n)
+	b = u32(b, uint32(n))
+	b = append(b, typ...)
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+// fullBox returns a box that starts with a version and flags.
+func fullBox(typ string, version uint8, flags uint32, parts ...[]byte) []byte {
+	header := u32(nil, uint32(version)<<24|flags&0xffffff)
+	return box(typ, append([][]byte{header}, parts...)...)
+}
+
+// unityMatrix is the identity transformation of mvhd and tkhd.
+var unityMatrix = []uint32{0x10000, 0, 0, 0, 0x10000, 0, 0, 0, 0x40000000}
+
+func matrix(b []byte) []byte {
+	for _, v := range unityMatrix {
+		b = u32(b, v)
+	}
+	return b
+}
+
+// ftyp is the file type box written at the start of remuxed files.
+func ftyp() []byte {
+	return box("ftyp", []byte("isom"), u32(nil, 0x200), []byte("isomiso2avc1mp41"))
+}
+
+// mvhd is the movie header; times are in milliseconds.
+func mvhd(duration uint64, nextTrack uint32) []byte {
+	b := u32(nil, 0) // Creation time
+	b = u32(b, 0)    // Modification time
+	b = u32(b, 1000) // Timescale
+	b = u32(b, uint32(duration))
+	b = u32(b, 0x10000) // Rate 1.0
+	b = u16(b, 0x100)   // Volume 1.0
+	b = append(b, make([]byte, 10)...)
+	b = matrix(b)
+	b = append(b, make([]byte, 24)...)
+	b = u32(b, nextTrack)
+	return fullBox("mvhd", 0, 0, b)
+}
+
+// tkhd is a track header; duration is in milliseconds.
+func tkhd(id uint32, duration uint64, audio bool, width, height int) []byte {
+	b := u32(nil, 0) // Creation time
+	b = u32(b, 0)    // Modification time
+	b = u32(b, id)
+	b = u32(b, 0)
+	b = u32(b, uint32(duration))
+	b = append(b, make([]byte, 8)...)
+	b = u16(b, 0) // Layer
+	b = u16(b, 0) // Alternate group
+	if audio {
+		b = u16(b, 0x100)
+	} else {
+		b = u16(b, 0)
+	}
+	b = u16(b, 0)
+	b = matrix(b)
+	b = u32(b, uint32(width)<<16)
+	b = u32(b, uint32(height)<<16)
+	// Enabled and in the movie
+	return fullBox("tkhd", 0, 3, b)
+}
+
+// edts delays the start of a track by delay milliseconds, then plays
+// duration milliseconds of its media from mediaStart (in media timescale).
+func edts(delay, duration uint64, mediaStart uint32) []byte {
+	var entries [][2]uint64
+	if delay > 0 {
+		entries = append(entries, [2]uint64{delay, 0xffffffff})
+	}
+	entries = append(entries, [2]uint64{duration, uint64(mediaStart)})
+	b := u32(nil, uint32(len(entries)))
+	for _, e := range entries {
+		b = u32(b, uint32(e[0]))
+		b = u32(b, uint32(e[1]))
+		b = u32(b, 0x10000) // Media rate 1.0
+	}
+	return box("edts", fullBox("elst", 0, 0, b))
+}
+
+// mdhd is a media header in the track's own timescale.
+func mdhd(timescale uint32, duration uint64) []byte {
+	b := u32(nil, 0)
+	b = u32(b, 0)
+	b = u32(b, timescale)
+	b = u32(b, uint32(duration))
+	b = u16(b, 0x55c4) // Language "und"
+	b = u16(b, 0)
+	return fullBox("mdhd", 0, 0, b)
+}
+
+func hdlr(audio bool) []byte {
+	handler, name := "vide", "VideoHandler"
+	if audio {
+		handler, name = "soun", "SoundHandler"
+	}
+	b := u32(nil, 0)
+	b = append(b, handler...)
+	b = append(b, make([]byte, 12)...)
+	b = append(b, name...)
+	b = u8(b, 0)
+	return fullBox("hdlr", 0, 0, b)
+}
+
+// mediaHeader is the video or sound media header with the data reference
+// pointing at this file.
+func mediaHeader(audio bool) []byte {
+	var header []byte
+	if audio {
+		header = fullBox("smhd", 0, 0, u32(nil, 0))
+	} else {
+		header = fullBox("vmhd", 0, 1, make([]byte, 8))
+	}
+	dref := fullBox("dref", 0, 0, u32(nil, 1), fullBox("url ", 0, 1))
+	return append(header, box("dinf", dref)...)
+}
+
+// avc1 is the sample entry of an H.264 track.
+func avc1(cfg avcConfig) []byte {
+	b := make([]byte, 6)
+	b = u16(b, 1) // Data reference index
+	b = append(b, make([]byte, 16)...)
+	b = u16(b, uint16(cfg.width))
+	b = u16(b, uint16(cfg.height))
+	b = u32(b, 0x480000) // 72 dpi
+	b = u32(b, 0x480000)
+	b = u32(b, 0)
+	b = u16(b, 1) // Frame count
+	b = append(b, make([]byte, 32)...)
+	b = u16(b, 0x18) // Depth
+	b = u16(b, 0xffff)
+	return box("avc1", b, box("avcC", cfg.record))
+}
+
+// Object type indications of the audio formats in esds.
+const (
+	objectAAC = 0x40
+	objectMP3 = 0x6b
+)
+
+// mp4a is the sample entry of an AAC or MP3 track. config is the
+// AudioSpecificConfig for AAC and empty for MP3.
+func mp4a(object uint8, rate, channels int, config []byte) []byte {
+	b := make([]byte, 6)
+	b = u16(b, 1) // Data reference index
+	b = append(b, make([]byte, 8)...)
+	b = u16(b, uint16(channels))
+	b = u16(b, 16) // Sample size
+	b = u32(b, 0)
+	b = u32(b, uint32(rate)<<16)
+
+	decoder := u8(nil, object)
+	decoder = u8(decoder, 0x15)        // Audio stream
+	decoder = append(decoder, 0, 0, 0) // Buffer size
+	decoder = u32(decoder, 0)          // Max bitrate
+	decoder = u32(decoder, 0)          // Average bitrate
+	if len(config) > 0 {
+		decoder = append(decoder, descriptor(5, config)...)
+	}
+	es := u16(nil, 0) // ES ID
+	es = u8(es, 0)
+	es = append(es, descriptor(4, decoder)...)
+	es = append(es, descriptor(6, []byte{2})...)
+	return box("mp4a", b, fullBox("esds", 0, 0, descriptor(3, es)))
+}
+
+// descriptor returns an MPEG-4 descriptor with a four byte length.
+func descriptor(tag uint8, payload []byte) []byte {
+	n := len(payload)
+	b := []byte{tag, byte(n>>21&0x7f) | 0x80, byte(n>>14&0x7f) | 0x80, byte(n>>7&0x7f) | 0x80, byte(n & 0x7f)}
+	return append(b, payload...)
+}
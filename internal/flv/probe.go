@@ -0,0 +1,278 @@
+package flv
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+)
+
+// Info describes an FLV file, read from its tags.
+type Info struct {
+	VideoCodec  string // Empty without video
+	AudioCodec  string // Empty without audio
+	Width       int
+	Height      int
+	SampleRate  int
+	Channels    int
+	Start       int64 // Timestamp of the first media tag in milliseconds
+	Duration    int64 // Milliseconds from the first to the last media tag
+	VideoFrames int
+	AudioFrames int
+	Size        int64 // File size in bytes
+	Bitrate     int64 // Media bits per second over Duration
+}
+
+// Probe reads every tag of the FLV file at path. Resolution comes from the
+// codec headers where the codec stores it (H.264, Sorenson H.263 and Screen
+// Video), otherwise from the onMetaData script tag.
+func Probe(path string) (Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Info{}, err
+	}
+	defer f.Close()
+	var info Info
+	if st, err := f.Stat(); err == nil {
+		info.Size = st.Size()
+	}
+	fr, err := NewReader(f)
+	if err != nil {
+		return info, err
+	}
+	var media int64
+	var meta map[string]float64
+	first, last := int64(-1), int64(0)
+	for {
+		tag, err := fr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return info, err
+		}
+		switch {
+		case tag.Type == TagScript:
+			if meta == nil {
+				meta = scriptData(tag.Data)
+			}
+		case len(tag.Data) == 0 || (tag.Type != TagAudio && tag.Type != TagVideo):
+		case tag.IsSequenceHeader():
+			start, _ := tag.Payload()
+			if tag.Type == TagVideo {
+				if cfg, err := parseAVCConfig(tag.Data[start:]); err == nil && info.Width == 0 {
+					info.Width, info.Height = cfg.width, cfg.height
+				}
+			} else if cfg, err := parseAACConfig(tag.Data[start:]); err == nil {
+				info.SampleRate, info.Channels = cfg.rate, cfg.channels
+			}
+		case tag.Type == TagVideo && tag.Data[0]>>4 == 5:
+			// Video info and command frames carry no picture
+		default:
+			if first < 0 {
+				first = tag.Timestamp
+			}
+			last = max(last, tag.Timestamp)
+			media += int64(len(tag.Data))
+			if tag.Type == TagVideo {
+				info.VideoFrames++
+				if info.VideoCodec == "" {
+					info.VideoCodec = CodecName(TagVideo, tag.VideoCodec())
+				}
+				if info.Width == 0 {
+					info.Width, info.Height = pictureSize(tag.VideoCodec(), tag.Data[1:])
+				}
+				continue
+			}
+			info.AudioFrames++
+			if format := tag.AudioFormat(); info.AudioCodec == "" {
+				info.AudioCodec = CodecName(TagAudio, format)
+				if format != SoundAAC {
+					info.SampleRate, info.Channels = tag.audioRate(), tag.audioChannels()
+				}
+			}
+		}
+	}
+	if first >= 0 {
+		info.Start, info.Duration = first, last-first
+	}
+	if info.Width == 0 && info.VideoCodec != "" {
+		info.Width, info.Height = int(meta["width"]), int(meta["height"])
+	}
+	if info.Duration == 0 && meta["duration"] > 0 {
+		info.Duration = int64(meta["duration"] * 1000)
+	}
+	if info.Duration > 0 {
+		info.Bitrate = media * 8 * 1000 / info.Duration
+	}
+	return info, nil
+}
+
+// pictureSize reads the picture size from the header of a Sorenson H.263 or
+// Screen Video frame. It returns zeros for other codecs.
+func pictureSize(codec int, data []byte) (width, height int) {
+	b := &bitReader{data: data}
+	switch codec {
+	case CodecH263:
+		b.bits(17) // Picture start code
+		b.bits(5)  // Version
+		b.bits(8)  // Temporal reference
+		switch size := b.bits(3); size {
+		case 0:
+			width, height = int(b.bits(8)), int(b.bits(8))
+		case 1:
+			width, height = int(b.bits(16)), int(b.bits(16))
+		default:
+			sizes := [][2]int{2: {352, 288}, 3: {176, 144}, 4: {128, 96}, 5: {320, 240}, 6: {160, 120}}
+			if int(size) < len(sizes) {
+				width, height = sizes[size][0], sizes[size][1]
+			}
+		}
+	case CodecScreenVideo, CodecScreenV2:
+		b.bits(4) // Block width
+		width = int(b.bits(12))
+		b.bits(4) // Block height
+		height = int(b.bits(12))
+	}
+	if b.err != nil {
+		return 0, 0
+	}
+	return width, height
+}
+
+// AMF0 value types used in script data tags.
+const (
+	amfNumber      = 0
+	amfBoolean     = 1
+	amfString      = 2
+	amfObject      = 3
+	amfNull        = 5
+	amfUndefined   = 6
+	amfReference   = 7
+	amfECMAArray   = 8
+	amfObjectEnd   = 9
+	amfStrictArray = 10
+	amfDate        = 11
+	amfLongString  = 12
+)
+
+// scriptData returns the numeric properties of an onMetaData script tag,
+// such as width, height and duration, or nil for other script tags.
+func scriptData(data []byte) map[string]float64 {
+	d := &amfDecoder{data: data}
+	if name, ok := d.value().(string); !ok || name != "onMetaData" {
+		return nil
+	}
+	props, _ := d.value().(map[string]any)
+	meta := make(map[string]float64)
+	for k, v := range props {
+		if n, ok := v.(float64); ok && !math.IsNaN(n) {
+			meta[k] = n
+		}
+	}
+	return meta
+}
+
+// maxAMFDepth limits how deeply objects and arrays may nest, so a crafted
+// script tag can't exhaust the stack.
+const maxAMFDepth = 32
+
+// amfDecoder decodes AMF0 values, skipping what it can't represent.
+type amfDecoder struct {
+	data  []byte
+	err   bool
+	depth int // Objects and arrays being decoded
+}
+
+func (d *amfDecoder) take(n int) []byte {
+	if d.err || n > len(d.data) {
+		d.err = true
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *amfDecoder) u16() int {
+	if b := d.take(2); b != nil {
+		return int(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *amfDecoder) u32() int {
+	if b := d.take(4); b != nil {
+		return int(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+// properties reads name and value pairs up to the object end marker.
+func (d *amfDecoder) properties() map[string]any {
+	props := make(map[string]any)
+	for !d.err {
+		name := string(d.take(d.u16()))
+		if len(d.data) > 0 && d.data[0] == amfObjectEnd {
+			d.take(1)
+			break
+		}
+		props[name] = d.value()
+	}
+	return props
+}
+
+func (d *amfDecoder) value() any {
+	t := d.take(1)
+	if t == nil {
+		return nil
+	}
+	switch t[0] {
+	case amfObject, amfECMAArray, amfStrictArray:
+		if d.depth >= maxAMFDepth {
+			d.err = true
+			return nil
+		}
+		d.depth++
+		defer func() { d.depth-- }()
+	}
+	switch t[0] {
+	case amfNumber:
+		if b := d.take(8); b != nil {
+			return math.Float64frombits(binary.BigEndian.Uint64(b))
+		}
+	case amfBoolean:
+		if b := d.take(1); b != nil {
+			return b[0] != 0
+		}
+	case amfString:
+		return string(d.take(d.u16()))
+	case amfLongString:
+		return string(d.take(d.u32()))
+	case amfObject:
+		return d.properties()
+	case amfECMAArray:
+		d.u32() // Approximate count; the end marker is what counts
+		return d.properties()
+	case amfStrictArray:
+		n := d.u32()
+		values := make([]any, 0, min(n, len(d.data)))
+		for range n {
+			if d.err {
+				break
+			}
+			values = append(values, d.value())
+		}
+		return values
+	case amfDate:
+		d.take(10)
+	case amfReference:
+		d.take(2)
+	case amfNull, amfUndefined:
+	default:
+		// Types without a known length end decoding
+		d.err = true
+	}
+	return nil
+}
@@ -0,0 +1,457 @@
+package flv
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Stream is a raw FLV file placed on the recording timeline.
+type Stream struct {
+	Path   string
+	Offset int64 // Milliseconds from the start of the recording to the start of the stream
+}
+
+// ErrNoMedia means none of the streams holds media that can be remuxed
+// without re-encoding.
+var ErrNoMedia = errors.New("no H.264, AAC or MP3 media")
+
+// ErrUnsupportedCodec means the streams hold media, but only in codecs such
+// as Nellymoser or Speex that can't be remuxed without re-encoding.
+var ErrUnsupportedCodec = errors.New("unsupported codec")
+
+// Summary describes a remuxed MP4.
+type Summary struct {
+	Duration      int64 // Milliseconds
+	Width, Height int   // Zero without video
+	VideoFrames   int
+	AudioFrames   int
+	Skipped       []string       // Codecs that couldn't be remuxed, e.g. "nellymoser"
+	Dropped       map[string]int // Frames left out of each stream, by path, where it overlapped another
+}
+
+// sample is one frame of a track. Its data stays in the FLV file until the
+// MP4 is written.
+type sample struct {
+	file   int   // Index into the remuxer's files
+	offset int64 // Position of the codec data in the file
+	size   int
+	dts    int64 // Milliseconds on the recording timeline
+	cts    int64 // Composition offset in milliseconds
+	key    bool
+	entry  int // 1-based index of the sample entry
+}
+
+// track collects the samples of the video or the audio track.
+type track struct {
+	audio     bool
+	timescale uint32
+	entries   [][]byte // Rendered sample entries
+	configs   []string // Decoder configuration of each entry
+	entry     int      // Entry of the samples that follow
+	width     int
+	height    int
+	samples   []sample
+	file      int         // File the last sample came from
+	dropped   map[int]int // Frames left out of each file where it overlapped another
+}
+
+// setEntry makes the sample entry for config current, adding it when no
+// earlier stream used the same configuration.
+func (t *track) setEntry(config string, render func() []byte) {
+	if i := slices.Index(t.configs, config); i >= 0 {
+		t.entry = i + 1
+		return
+	}
+	t.configs = append(t.configs, config)
+	t.entries = append(t.entries, render())
+	t.entry = len(t.entries)
+}
+
+// add appends s unless it overlaps samples taken from another file, which it
+// counts as dropped. Without re-encoding, overlapping streams can't be mixed,
+// so the one that started first is kept until it ends. Video switches streams
+// only at a keyframe.
+func (t *track) add(s sample) {
+	if t.entry == 0 || s.size <= 0 {
+		return
+	}
+	s.entry = t.entry
+	if n := len(t.samples); n > 0 {
+		switch last := t.samples[n-1]; {
+		case s.file != last.file && (s.dts <= last.dts || (!t.audio && !s.key)):
+			if t.dropped == nil {
+				t.dropped = make(map[int]int)
+			}
+			t.dropped[s.file]++
+			return
+		case s.dts <= last.dts:
+			// Millisecond timestamps can repeat within a stream
+			s.dts = last.dts + 1
+		}
+	} else if !t.audio && !s.key {
+		return
+	}
+	t.samples = append(t.samples, s)
+}
+
+// Remux writes the H.264 video of the video streams and the AAC or MP3
+// audio of the audio streams to a new MP4 file at dst, each frame placed at
+// its stream's offset plus its FLV timestamp. The same file may appear in
+// both lists. Tracks without any usable frames are left out; when both are
+// empty Remux returns ErrNoMedia, or ErrUnsupportedCodec when the streams
+// only hold other codecs, and writes nothing. Cancelling ctx stops the
+// remux and removes dst.
+func Remux(ctx context.Context, dst string, video, audio []Stream) (Summary, error) {
+	var files []string
+	type source struct {
+		Stream
+		video, audio bool
+	}
+	var sources []*source
+	byPath := make(map[string]*source)
+	for i, list := range [][]Stream{video, audio} {
+		for _, s := range list {
+			src := byPath[s.Path]
+			if src == nil {
+				src = &source{Stream: s}
+				byPath[s.Path] = src
+				sources = append(sources, src)
+			}
+			src.video = src.video || i == 0
+			src.audio = src.audio || i == 1
+		}
+	}
+	slices.SortStableFunc(sources, func(a, b *source) int { return cmp.Compare(a.Offset, b.Offset) })
+
+	vt := &track{timescale: 1000}
+	at := &track{audio: true}
+	skipped := make(map[string]bool)
+	for _, src := range sources {
+		files = append(files, src.Path)
+		err := scan(ctx, src.Path, src.Offset, len(files)-1, src.video, src.audio, vt, at, skipped)
+		if err != nil {
+			return Summary{}, fmt.Errorf("%s: %w", src.Path, err)
+		}
+	}
+
+	summary := Summary{
+		Width: vt.width, Height: vt.height, VideoFrames: len(vt.samples), AudioFrames: len(at.samples),
+		Skipped: slices.Sorted(maps.Keys(skipped)),
+	}
+	for _, t := range []*track{vt, at} {
+		for file, n := range t.dropped {
+			if summary.Dropped == nil {
+				summary.Dropped = make(map[string]int)
+			}
+			summary.Dropped[files[file]] += n
+		}
+	}
+	var tracks []*track
+	for _, t := range []*track{vt, at} {
+		if len(t.samples) > 0 {
+			tracks = append(tracks, t)
+		}
+	}
+	if len(tracks) == 0 {
+		if len(summary.Skipped) > 0 {
+			return summary, fmt.Errorf("%w: %s", ErrUnsupportedCodec, strings.Join(summary.Skipped, ", "))
+		}
+		return summary, ErrNoMedia
+	}
+	duration, err := writeMP4(ctx, dst, files, tracks)
+	if err != nil {
+		os.Remove(dst)
+		return summary, err
+	}
+	summary.Duration = duration
+	return summary, nil
+}
+
+// scan reads the tags of one FLV file into the video and audio tracks.
+func scan(
+	ctx context.Context,
+	path string,
+	offset int64,
+	file int,
+	video, audio bool,
+	vt, at *track,
+	skipped map[string]bool,
+) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fr, err := NewReader(f)
+	if err != nil {
+		return err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		tag, err := fr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, cts := tag.Payload()
+		s := sample{
+			file: file, offset: tag.Offset + int64(start), size: len(tag.Data) - start,
+			dts: offset + tag.Timestamp, cts: max(cts, 0), key: tag.Keyframe(),
+		}
+		switch {
+		case tag.Type == TagVideo && video && len(tag.Data) > 0:
+			switch codec := tag.VideoCodec(); {
+			case codec == CodecAVC && tag.IsSequenceHeader():
+				cfg, err := parseAVCConfig(tag.Data[start:])
+				if err != nil {
+					continue
+				}
+				vt.setEntry(string(cfg.record), func() []byte { return avc1(cfg) })
+				if vt.width == 0 {
+					vt.width, vt.height = cfg.width, cfg.height
+				}
+			case codec == CodecAVC:
+				// Only NAL units carry pictures; the end of sequence marker doesn't
+				if len(tag.Data) > 1 && tag.Data[1] == 1 {
+					vt.add(s)
+				}
+			case tag.Data[0]>>4 == 5:
+				// Video info and command frames carry no picture
+			default:
+				skipped[CodecName(TagVideo, codec)] = true
+			}
+		case tag.Type == TagAudio && audio && len(tag.Data) > 0:
+			switch format := tag.AudioFormat(); format {
+			case SoundAAC:
+				if !tag.IsSequenceHeader() {
+					at.add(s)
+					continue
+				}
+				cfg, err := parseAACConfig(tag.Data[start:])
+				if err != nil {
+					continue
+				}
+				at.setEntry("aac"+string(cfg.record), func() []byte {
+					return mp4a(objectAAC, cfg.rate, cfg.channels, cfg.record)
+				})
+				at.timescale = cmp.Or(at.timescale, uint32(cfg.rate))
+			case SoundMP3, SoundMP38k:
+				rate, channels := tag.audioRate(), tag.audioChannels()
+				at.setEntry(fmt.Sprintf("mp3/%d/%d", rate, channels), func() []byte {
+					return mp4a(objectMP3, rate, channels, nil)
+				})
+				at.timescale = cmp.Or(at.timescale, uint32(rate))
+				at.add(s)
+			default:
+				skipped[CodecName(TagAudio, format)] = true
+			}
+		}
+	}
+}
+
+// chunk is a run of consecutive samples of one track stored together.
+type chunk struct {
+	offset int64
+	count  int
+	entry  int
+}
+
+// writeMP4 writes the tracks' samples, interleaved in one second windows,
+// and their index to dst. It returns the movie duration in milliseconds.
+func writeMP4(ctx context.Context, dst string, files []string, tracks []*track) (int64, error) {
+	out, err := os.Create(dst)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+	sources := make([]*os.File, len(files))
+	for i, path := range files {
+		if sources[i], err = os.Open(path); err != nil {
+			return 0, err
+		}
+		defer sources[i].Close()
+	}
+
+	w := bufio.NewWriterSize(out, 1<<20)
+	header := ftyp()
+	w.Write(header)
+	// A 64 bit mdat size, patched once the media is written
+	w.Write(u64(append(u32(nil, 1), "mdat"...), 0))
+	pos := int64(len(header)) + 16
+	chunks := make([][]chunk, len(tracks))
+	next := make([]int, len(tracks))
+	written := -1 // Track of the last sample written
+	var buf []byte
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		// Each window starts at the earliest remaining sample, skipping gaps
+		var window int64 = -1
+		for i, t := range tracks {
+			if next[i] < len(t.samples) && (window < 0 || t.samples[next[i]].dts < window) {
+				window = t.samples[next[i]].dts
+			}
+		}
+		if window < 0 {
+			break
+		}
+		window += 1000
+		for i, t := range tracks {
+			for ; next[i] < len(t.samples) && t.samples[next[i]].dts < window; next[i]++ {
+				s := t.samples[next[i]]
+				if n := len(chunks[i]); n == 0 || written != i || chunks[i][n-1].entry != s.entry {
+					chunks[i] = append(chunks[i], chunk{offset: pos, entry: s.entry})
+				}
+				chunks[i][len(chunks[i])-1].count++
+				written = i
+				if cap(buf) < s.size {
+					buf = make([]byte, s.size)
+				}
+				if _, err := sources[s.file].ReadAt(buf[:s.size], s.offset); err != nil {
+					return 0, fmt.Errorf("read %s: %w", files[s.file], err)
+				}
+				if _, err := w.Write(buf[:s.size]); err != nil {
+					return 0, err
+				}
+				pos += int64(s.size)
+			}
+		}
+	}
+	mdatSize := pos - int64(len(header))
+
+	// Movie time 0 is recording time 0, so captions, chat and chapters,
+	// which use recording offsets, line up with the media
+	var duration int64
+	moov := [][]byte{nil}
+	for i, t := range tracks {
+		trak, end := trakBox(uint32(i+1), t, chunks[i])
+		moov = append(moov, trak)
+		duration = max(duration, end)
+	}
+	moov[0] = mvhd(uint64(duration), uint32(len(tracks)+1))
+	if _, err := w.Write(box("moov", moov...)); err != nil {
+		return 0, err
+	}
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	if _, err := out.WriteAt(u64(nil, uint64(mdatSize)), int64(len(header))+8); err != nil {
+		return 0, err
+	}
+	return duration, out.Close()
+}
+
+// runs run-length encodes values as count and value pairs, the layout of
+// the stts and ctts tables.
+func runs(values []uint32) (table []byte, n uint32) {
+	for i := 0; i < len(values); {
+		j := i + 1
+		for j < len(values) && values[j] == values[i] {
+			j++
+		}
+		table = u32(u32(table, uint32(j-i)), values[i])
+		n++
+		i = j
+	}
+	return table, n
+}
+
+// trakBox builds the track box of t for a movie that starts at the start of
+// the recording. An empty edit delays the track until its first sample. It
+// returns the box and the end of the track in movie time.
+func trakBox(id uint32, t *track, chunks []chunk) ([]byte, int64) {
+	ticks := func(ms int64) int64 { return ms * int64(t.timescale) / 1000 }
+	samples := t.samples
+	first := samples[0].dts
+
+	// A sample lasts until the next one starts; the last one as long as the
+	// one before it
+	durations := make([]uint32, len(samples))
+	offsets := make([]uint32, len(samples))
+	var mediaDuration uint64
+	var stss, stsz []byte
+	var keys uint32
+	var reordered bool
+	for i, s := range samples {
+		switch {
+		case i+1 < len(samples):
+			durations[i] = uint32(ticks(samples[i+1].dts-first) - ticks(s.dts-first))
+		case i > 0:
+			durations[i] = durations[i-1]
+		case t.audio:
+			durations[i] = 1024
+		default:
+			durations[i] = uint32(ticks(40))
+		}
+		mediaDuration += uint64(durations[i])
+		offsets[i] = uint32(ticks(s.cts))
+		reordered = reordered || offsets[i] != 0
+		if s.key {
+			stss = u32(stss, uint32(i+1))
+			keys++
+		}
+		stsz = u32(stsz, uint32(s.size))
+	}
+
+	stsd := u32(nil, uint32(len(t.entries)))
+	for _, e := range t.entries {
+		stsd = append(stsd, e...)
+	}
+	stts, sttsN := runs(durations)
+	tables := [][]byte{fullBox("stsd", 0, 0, stsd), fullBox("stts", 0, 0, u32(nil, sttsN), stts)}
+	if reordered {
+		ctts, cttsN := runs(offsets)
+		tables = append(tables, fullBox("ctts", 0, 0, u32(nil, cttsN), ctts))
+	}
+	if !t.audio && int(keys) < len(samples) {
+		tables = append(tables, fullBox("stss", 0, 0, u32(nil, keys), stss))
+	}
+
+	var stsc, stco []byte
+	var stscN uint32
+	large := chunks[len(chunks)-1].offset > 0xffffffff
+	for i, c := range chunks {
+		if i == 0 || c.count != chunks[i-1].count || c.entry != chunks[i-1].entry {
+			stsc = u32(u32(u32(stsc, uint32(i+1)), uint32(c.count)), uint32(c.entry))
+			stscN++
+		}
+		if large {
+			stco = u64(stco, uint64(c.offset))
+		} else {
+			stco = u32(stco, uint32(c.offset))
+		}
+	}
+	tables = append(tables,
+		fullBox("stsc", 0, 0, u32(nil, stscN), stsc),
+		fullBox("stsz", 0, 0, u32(nil, 0), u32(nil, uint32(len(samples))), stsz),
+	)
+	if large {
+		tables = append(tables, fullBox("co64", 0, 0, u32(nil, uint32(len(chunks))), stco))
+	} else {
+		tables = append(tables, fullBox("stco", 0, 0, u32(nil, uint32(len(chunks))), stco))
+	}
+
+	delay := first
+	playback := int64(mediaDuration) * 1000 / int64(t.timescale)
+	minf := box("minf", mediaHeader(t.audio), box("stbl", tables...))
+	mdia := box("mdia", mdhd(t.timescale, mediaDuration), hdlr(t.audio), minf)
+	trak := box("trak",
+		tkhd(id, uint64(delay+playback), t.audio, t.width, t.height),
+		edts(uint64(delay), uint64(playback), offsets[0]),
+		mdia,
+	)
+	return trak, delay + playback
+}
@@ -1,17 +1,18 @@
-// Package mp4box provides an interface to run MP4Box for embedding subtitles into MP4 files.
+// Package mp4box provides an interface to run MP4Box for embedding subtitles and chapters into MP4 files.
 // MP4Box is much smaller than FFmpeg and sufficient for subtitle embedding tasks.
 package mp4box
 
 import (
 	"bytes"
+	"cmp"
 	"context"
-	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 )
 
 // Runner wraps execution of the MP4Box binary.
@@ -43,8 +44,9 @@ func Locate(explicit string) (string, error) {
 	if p, err := extractEmbedded(runtime.GOOS, runtime.GOARCH); err == nil {
 		return p, nil
 	}
-	return "", errors.New(
-		"MP4Box not found; set ADOBECONNECTDL_MP4BOX, provide --mp4box, install MP4Box, or use a supported platform for the embedded binary",
+	return "", fmt.Errorf(
+		"MP4Box %w; set ADOBECONNECTDL_MP4BOX, provide --mp4box, install MP4Box, or use a supported platform for the embedded binary",
+		exec.ErrNotFound,
 	)
 }
 
@@ -77,34 +79,81 @@ func (r *Runner) Path() string {
 	return r.path
 }
 
-// EmbedSubtitles adds a VTT subtitle track to an MP4 file.
-// The command uses the vtt2tx3g filter to convert WebVTT to TX3G format,
-// which is properly recognized as a subtitle track by ffmpeg and video players.
-// Command: MP4Box -add "subtitles.vtt:lang=en:@vtt2tx3g" -tmp <dir> input.mp4
+// Subtitle is a WebVTT file to add to an MP4 file as a subtitle track.
+type Subtitle struct {
+	Path string
+	Lang string // ISO 639 language code; empty means "en"
+	Name string // Track name shown by players; empty leaves the track unnamed
+}
+
+// EmbedSubtitles adds an unnamed VTT subtitle track to an MP4 file.
+// Command: MP4Box -add "captions.vtt:lang=en:@vtt2tx3g" -tmp <dir> input.mp4
 // Note: MP4Box modifies the input file in place.
 func (r *Runner) EmbedSubtitles(ctx context.Context, mp4Path, vttPath, lang string, stdout, stderr io.Writer) error {
-	if lang == "" {
-		lang = "en"
-	}
+	return r.Embed(ctx, mp4Path, []Subtitle{{Path: vttPath, Lang: lang}}, "", stdout, stderr)
+}
 
+// Embed adds the subtitle tracks and, when chaptersPath is non-empty, the
+// chapters listed in it to an MP4 file in a single MP4Box run, so the file is
+// rewritten once. Each line of the chapters file is "HH:MM:SS.mmm Title".
+// The vtt2tx3g filter converts WebVTT to TX3G format, which is properly
+// recognized as a subtitle track by ffmpeg and video players.
+// Command: MP4Box -add "chat.vtt:lang=und:name=Chat:@vtt2tx3g" -chap chapters.txt -tmp <dir> input.mp4
+// Note: MP4Box modifies the input file in place.
+func (r *Runner) Embed(
+	ctx context.Context,
+	mp4Path string,
+	subtitles []Subtitle,
+	chaptersPath string,
+	stdout, stderr io.Writer,
+) error {
 	// Convert to absolute paths to avoid issues with working directory
 	absMP4, err := filepath.Abs(mp4Path)
 	if err != nil {
 		return fmt.Errorf("failed to resolve MP4 path: %w", err)
 	}
-	absVTT, err := filepath.Abs(vttPath)
-	if err != nil {
-		return fmt.Errorf("failed to resolve VTT path: %w", err)
-	}
-
 	// Verify files exist before calling MP4Box
 	if _, err := os.Stat(absMP4); err != nil {
 		return fmt.Errorf("MP4 file not found: %w", err)
 	}
-	if _, err := os.Stat(absVTT); err != nil {
-		return fmt.Errorf("VTT file not found: %w", err)
+
+	var args []string
+	for _, sub := range subtitles {
+		absVTT, err := filepath.Abs(sub.Path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve VTT path: %w", err)
+		}
+		if _, err := os.Stat(absVTT); err != nil {
+			return fmt.Errorf("VTT file not found: %w", err)
+		}
+		// The @vtt2tx3g filter converts WebVTT to TX3G (mov_text) format
+		// which is the proper MP4 subtitle format recognized by all players
+		opts := "lang=" + cmp.Or(sub.Lang, "en")
+		if sub.Name != "" {
+			// A colon would end the option in MP4Box's -add syntax
+			opts += ":name=" + strings.ReplaceAll(sub.Name, ":", " ")
+		}
+		args = append(args, "-add", fmt.Sprintf("%s:%s:@vtt2tx3g", absVTT, opts))
+	}
+	if chaptersPath != "" {
+		absChapters, err := filepath.Abs(chaptersPath)
+		if err != nil {
+			return fmt.Errorf("failed to resolve chapters path: %w", err)
+		}
+		if _, err := os.Stat(absChapters); err != nil {
+			return fmt.Errorf("chapters file not found: %w", err)
+		}
+		args = append(args, "-chap", absChapters)
 	}
+	if len(args) == 0 {
+		return nil
+	}
+	return r.editInPlace(ctx, absMP4, args, stdout, stderr)
+}
 
+// editInPlace runs MP4Box with args on the MP4 at absMP4, which it modifies
+// in place.
+func (r *Runner) editInPlace(ctx context.Context, absMP4 string, args []string, stdout, stderr io.Writer) error {
 	// Create a unique temp directory for this MP4Box invocation to avoid collisions
 	// when multiple MP4Box instances run concurrently on files in the same directory.
 	// Each MP4Box process creates a temp file like "out_<filename>.mp4" in the temp dir,
@@ -115,15 +164,8 @@ func (r *Runner) EmbedSubtitles(ctx context.Context, mp4Path, vttPath, lang stri
 	}
 	defer os.RemoveAll(tmpDir) // Clean up temp dir after we're done
 
-	// MP4Box -add "subtitles.vtt:lang=en:@vtt2tx3g" -tmp <dir> input.mp4
-	// The @vtt2tx3g filter converts WebVTT to TX3G (mov_text) format
-	// which is the proper MP4 subtitle format recognized by all players
 	// -tmp sets the temp directory for intermediate files
-	args := []string{
-		"-add", fmt.Sprintf("%s:lang=%s:@vtt2tx3g", absVTT, lang),
-		"-tmp", tmpDir,
-		absMP4,
-	}
+	args = append(args, "-tmp", tmpDir, absMP4)
 
 	// Capture stderr for better error messages if not provided
 	var errBuf bytes.Buffer